@@ -2,21 +2,36 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/seldomhappy/vibe_architecture/config"
 	httpdelivery "github.com/seldomhappy/vibe_architecture/internal/delivery/http"
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
 	"github.com/seldomhappy/vibe_architecture/internal/infrastructure/kafka"
+	"github.com/seldomhappy/vibe_architecture/internal/infrastructure/outbox"
 	"github.com/seldomhappy/vibe_architecture/internal/infrastructure/postgres"
+	webhookdelivery "github.com/seldomhappy/vibe_architecture/internal/infrastructure/webhook"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/auth"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/buildinfo"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/eventbus"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/health"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/httpclient"
 	"github.com/seldomhappy/vibe_architecture/internal/pkg/lifecycle"
 	"github.com/seldomhappy/vibe_architecture/internal/pkg/metrics"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/pubsub"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/scheduler"
 	"github.com/seldomhappy/vibe_architecture/internal/pkg/tracing"
 	"github.com/seldomhappy/vibe_architecture/internal/repository"
+	"github.com/seldomhappy/vibe_architecture/internal/repository/memory"
 	"github.com/seldomhappy/vibe_architecture/internal/usecase/task"
+	"github.com/seldomhappy/vibe_architecture/internal/usecase/webhook"
 	"github.com/seldomhappy/vibe_architecture/logger"
 )
 
@@ -30,16 +45,35 @@ func main() {
 
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
-		fmt.Printf("Invalid configuration: %v\n", err)
+		fmt.Printf("Invalid configuration:\n%v\n", err)
 		os.Exit(1)
 	}
 
-	// Create logger
-	log := logger.New(cfg.App.Name)
-	log.Info("Starting %s v%s in %s mode", cfg.App.Name, cfg.App.Version, cfg.App.Environment)
+	// Create logger (the level and format were checked by Validate)
+	level, _ := logger.ParseLevel(cfg.Logger.Level)
+	format, _ := logger.ParseFormat(cfg.Logger.Format)
+	logOutput, err := logger.OpenOutput(cfg.Logger.Output, logger.RotateConfig{
+		MaxSizeMB:  cfg.Logger.MaxSizeMB,
+		MaxAge:     cfg.Logger.MaxAge,
+		MaxBackups: cfg.Logger.MaxBackups,
+	})
+	if err != nil {
+		fmt.Printf("Failed to open log output: %v\n", err)
+		os.Exit(1)
+	}
+	log := logger.New(cfg.App.Name, logger.WithLevel(level), logger.WithFormat(format), logger.WithOutput(logOutput))
+	if cfg.Logger.DedupWindow > 0 {
+		log = logger.NewDeduplicating(log, cfg.Logger.DedupWindow)
+	}
+	build := buildinfo.Get(cfg.App.Name, cfg.App.Version)
+	log.Info("Starting %s v%s (commit %s) in %s mode", build.Name, build.Version, build.Commit, cfg.App.Environment)
 
 	// Run migrations if requested
 	if os.Getenv("RUN_MIGRATIONS") == "true" {
+		if cfg.DB.Driver == config.DBDriverMemory {
+			log.Info("The in-memory database needs no migrations")
+			return
+		}
 		log.Info("Running database migrations...")
 		if err := postgres.RunMigrations(cfg.DB.DSN(), log); err != nil {
 			log.Fatal("Failed to run migrations: %v", err)
@@ -48,8 +82,25 @@ func main() {
 		return
 	}
 
+	// Reset consumer offsets if requested. This is a dry run unless
+	// RESET_OFFSETS_APPLY=true; the consumers must be stopped to apply it.
+	if to := os.Getenv("RESET_OFFSETS_TO"); to != "" {
+		if err := resetOffsets(cfg, to, os.Getenv("RESET_OFFSETS_APPLY") == "true", log); err != nil {
+			log.Fatal("Failed to reset consumer offsets: %v", err)
+		}
+		return
+	}
+
+	// Print a bearer token for local development if requested
+	if user := os.Getenv("ISSUE_TOKEN_USER"); user != "" {
+		if err := issueToken(cfg, user, os.Getenv("ISSUE_TOKEN_TENANT"), os.Getenv("ISSUE_TOKEN_ROLE")); err != nil {
+			log.Fatal("Failed to issue token: %v", err)
+		}
+		return
+	}
+
 	// Initialize application
-	app, err := initApp(cfg, log)
+	app, err := initApp(cfg, build, log)
 	if err != nil {
 		log.Fatal("Failed to initialize application: %v", err)
 	}
@@ -61,7 +112,7 @@ func main() {
 	}
 
 	// Print startup information
-	printStartupInfo(cfg, log)
+	printStartupInfo(cfg, build, log)
 
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
@@ -74,11 +125,17 @@ func main() {
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
-	if err := app.lifecycle.ShutdownAll(shutdownCtx); err != nil {
+	// One line per service that failed, so none is lost in a long message
+	for _, err := range lifecycle.ShutdownErrors(app.lifecycle.ShutdownAll(shutdownCtx)) {
 		log.Error("Error during shutdown: %v", err)
 	}
 
 	log.Info("Server stopped")
+
+	// Flush file output once nothing else will log
+	if err := logOutput.Close(); err != nil {
+		fmt.Printf("Failed to close log output: %v\n", err)
+	}
 }
 
 type application struct {
@@ -114,99 +171,361 @@ func loadConfig() (*config.Config, error) {
 	return &cfg, nil
 }
 
-func initApp(cfg *config.Config, log logger.ILogger) (*application, error) {
-	lm := lifecycle.New()
+// resetOffsets moves the consumer group's offsets on the task event and retry topics to target
+func resetOffsets(cfg *config.Config, target string, apply bool, log logger.ILogger) error {
+	if !cfg.Kafka.Enabled {
+		return errors.New("kafka is disabled (kafka.enabled is false)")
+	}
+	resetTarget, err := kafka.ParseResetTarget(target)
+	if err != nil {
+		return err
+	}
+
+	topics := []string{cfg.Kafka.Topics.TaskEvents}
+	for _, tier := range cfg.Kafka.Retry.Tiers {
+		topics = append(topics, tier.Topic)
+	}
+
+	log.Info("Resetting offsets of group %s to %s (apply=%t)", cfg.Kafka.ConsumerGroupID, resetTarget, apply)
+	_, err = kafka.ResetOffsets(kafka.OffsetResetConfig{
+		Brokers:  cfg.Kafka.Brokers,
+		GroupID:  cfg.Kafka.ConsumerGroupID,
+		Topics:   topics,
+		Version:  cfg.Kafka.Version,
+		Security: kafkaSecurity(cfg.Kafka),
+	}, resetTarget, apply, log)
+	return err
+}
+
+// issueToken prints a token signed with auth.jwt_secret that is valid for a day
+func issueToken(cfg *config.Config, user, tenant, role string) error {
+	userID, err := strconv.ParseInt(user, 10, 64)
+	if err != nil || userID <= 0 {
+		return fmt.Errorf("ISSUE_TOKEN_USER must be a positive user ID, got %q", user)
+	}
+	if err := domain.ValidateTenantID(tenant); err != nil {
+		return fmt.Errorf("ISSUE_TOKEN_TENANT %q: %w", tenant, err)
+	}
+	verifier, err := auth.NewVerifier(cfg.Auth.JWTSecret, nil)
+	if err != nil {
+		return err
+	}
+	fmt.Println(verifier.Sign(auth.Claims{
+		UserID:    userID,
+		TenantID:  tenant,
+		Role:      role,
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+	}))
+	return nil
+}
+
+func initApp(cfg *config.Config, build buildinfo.Info, log logger.ILogger) (*application, error) {
+	lm := lifecycle.New(log)
+	lm.SetPhaseTimeout(cfg.Server.ShutdownPhaseTimeout)
+	lm.SetServiceTimeouts(cfg.Server.ShutdownServiceTimeouts)
 
 	// 1. Initialize Metrics
 	log.Info("Initializing metrics...")
-	m := metrics.New(cfg.App.Name, cfg.App.Version, cfg.Metrics.Port, cfg.Metrics.Enabled)
-	lm.Register("metrics", m)
+	m := metrics.New(build, cfg.Metrics.Port, cfg.Metrics.Enabled)
+	lm.Register("metrics", m, lifecycle.WithShutdownPhase(lifecycle.PhaseTelemetry))
 
 	// 2. Initialize Tracing
 	log.Info("Initializing tracing...")
 	tracer, err := tracing.New(
 		cfg.Tracing.ServiceName,
 		cfg.Tracing.JaegerEndpoint,
-		cfg.Tracing.SamplingRate,
+		tracing.SamplingConfig{
+			Sampler:        cfg.Tracing.Sampler,
+			Rate:           cfg.Tracing.SamplingRate,
+			KeepErrors:     cfg.Tracing.KeepErrors,
+			KeepSlowerThan: cfg.Tracing.KeepSlowerThan,
+		},
 		cfg.Tracing.Enabled,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize tracing: %w", err)
 	}
-	lm.Register("tracing", tracer)
+	lm.Register("tracing", tracer, lifecycle.WithShutdownPhase(lifecycle.PhaseTelemetry))
 
-	// 3. Initialize Database
-	log.Info("Initializing database...")
-	dbConfig := postgres.Config{
-		DSN:             cfg.DB.DSN(),
-		MaxOpenConns:    int32(cfg.DB.MaxOpenConns),
-		MaxIdleConns:    int32(cfg.DB.MaxIdleConns),
-		ConnMaxLifetime: cfg.DB.ConnMaxLifetime,
-		ConnMaxIdleTime: cfg.DB.ConnMaxIdleTime,
-	}
-	
-	dbTracer := tracing.GetTracer("postgres")
-	db, err := postgres.New(dbConfig, log, m, dbTracer)
+	// Apply safe config changes on SIGHUP
+	reloader := config.NewReloader(cfg, loadConfig, log)
+	reloader.OnReload(func(c *config.Config) {
+		level, _ := logger.ParseLevel(c.Logger.Level)
+		log.SetLevel(level)
+	})
+	reloader.OnReload(func(c *config.Config) {
+		if err := tracer.SetSamplingRate(c.Tracing.SamplingRate); err != nil {
+			log.Error("Failed to apply tracing sampling rate: %v", err)
+		}
+	})
+	lm.Register("config-reloader", reloader)
+
+	// 3. Initialize Database and Repositories
+	readiness := health.New(cfg.Server.ReadinessTimeout)
+	readiness.Observe(func(name string, result health.Result, changed bool) {
+		m.SetDependencyUp(name, result.Status == health.StatusUp)
+		if !changed {
+			return
+		}
+		if result.Status == health.StatusUp {
+			log.Info("Dependency %s is up", name)
+		} else {
+			log.Warn("Dependency %s is %s: %s", name, result.Status, result.Error)
+		}
+	})
+	repos, err := initRepositories(cfg, lm, m, readiness, log)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize database: %w", err)
+		return nil, err
 	}
-	lm.Register("database", db)
 
 	// 4. Initialize Kafka Producer
-	log.Info("Initializing Kafka producer...")
-	producerConfig := kafka.ProducerConfig{
-		Brokers:      cfg.Kafka.Brokers,
-		Topic:        cfg.Kafka.Topics.TaskEvents,
-		Compression:  cfg.Kafka.Producer.Compression,
-		RetryMax:     cfg.Kafka.Producer.RetryMax,
-		RetryBackoff: cfg.Kafka.Producer.RetryBackoff,
-		Idempotent:   cfg.Kafka.Producer.Idempotent,
-		Timeout:      cfg.Kafka.Producer.Timeout,
-	}
-	producer, err := kafka.NewProducer(producerConfig, log)
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize kafka producer: %w", err)
-	}
-	lm.Register("kafka-producer", producer)
+	var kafkaSink eventbus.Sink
+	var dlqReplayer httpdelivery.DeadLetterReplayer
+	var producer *kafka.Producer
+	var dlq *kafka.DeadLetterQueue
+	if cfg.Kafka.ProducerEnabled() {
+		log.Info("Initializing Kafka producer...")
+		producerConfig := kafka.ProducerConfig{
+			Brokers:      cfg.Kafka.Brokers,
+			Topic:        cfg.Kafka.Topics.TaskEvents,
+			Compression:  cfg.Kafka.Producer.Compression,
+			RetryMax:     cfg.Kafka.Producer.RetryMax,
+			RetryBackoff: cfg.Kafka.Producer.RetryBackoff,
+			Idempotent:   cfg.Kafka.Producer.Idempotent,
+			Timeout:      cfg.Kafka.Producer.Timeout,
+			Breaker: kafka.BreakerConfig{
+				FailureThreshold: cfg.Kafka.Producer.Breaker.FailureThreshold,
+				OpenTimeout:      cfg.Kafka.Producer.Breaker.OpenTimeout,
+			},
+			KeyStrategy: cfg.Kafka.Producer.KeyStrategy,
+			Version:     cfg.Kafka.Version,
+			Security:    kafkaSecurity(cfg.Kafka),
+		}
+		producer, err = kafka.NewProducer(producerConfig, m, log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize kafka producer: %w", err)
+		}
 
-	// 5. Initialize Repositories
-	log.Info("Initializing repositories...")
-	taskRepo := repository.NewTaskRepository(db, log)
-	txManager := repository.NewTxManager(db, log)
-	_ = txManager // For future use with transactions
+		// Starts alongside the database; neither needs the other
+		lm.Register("kafka-producer", producer,
+			lifecycle.WithDependsOn("metrics", "tracing"),
+			lifecycle.WithShutdownPhase(lifecycle.PhaseClients))
+		readiness.Register("kafka", producer.Ping)
+		readiness.Register("kafka_circuit", producer.CheckCircuit)
+
+		dlqConfig := kafka.DLQConfig{
+			Brokers:  cfg.Kafka.Brokers,
+			Topic:    cfg.Kafka.Topics.DeadLetter,
+			GroupID:  cfg.Kafka.ConsumerGroupID,
+			Version:  cfg.Kafka.Version,
+			Security: kafkaSecurity(cfg.Kafka),
+		}
+		dlq, err = kafka.NewDeadLetterQueue(dlqConfig, producer, m, log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize kafka dead letter queue: %w", err)
+		}
+		lm.Register("kafka-dlq", dlq,
+			lifecycle.WithDependsOn("kafka-producer"),
+			lifecycle.WithShutdownPhase(lifecycle.PhaseClients))
+
+		kafkaSink = producer
+		dlqReplayer = dlq
+	} else {
+		log.Warn("Kafka producer is disabled: task events are not published")
+		kafkaSink = kafka.NewNopPublisher(log)
+		readiness.Disable("kafka")
+	}
 
-	// 6. Initialize Use Cases
+	// 5. Initialize Use Cases
 	log.Info("Initializing use cases...")
-	taskUC := task.New(taskRepo, producer, log, m)
-
-	// 7. Initialize Kafka Consumer
-	log.Info("Initializing Kafka consumer...")
-	eventHandler := kafka.NewTaskEventHandler(log)
-	consumerConfig := kafka.ConsumerConfig{
-		Brokers:          cfg.Kafka.Brokers,
-		GroupID:          cfg.Kafka.ConsumerGroupID,
-		Topics:           []string{cfg.Kafka.Topics.TaskEvents},
-		Workers:          cfg.Kafka.Consumer.Workers,
-		SessionTimeout:   cfg.Kafka.Consumer.SessionTimeout.String(),
-		RebalanceTimeout: cfg.Kafka.Consumer.RebalanceTimeout.String(),
-	}
-	consumer, err := kafka.NewConsumer(consumerConfig, eventHandler, log)
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize kafka consumer: %w", err)
+	broker := pubsub.New(cfg.Events.BufferSize, log)
+
+	// Use cases publish their events to the bus, which hands them to Kafka, live
+	// subscribers and webhooks. It shuts down with the ingress, so its queue
+	// drains while the sinks are still up.
+	bus := eventbus.New(eventbus.Config{
+		Mode:       cfg.Events.BusMode,
+		Timeout:    cfg.Events.BusTimeout,
+		BufferSize: cfg.Events.BusBufferSize,
+	}, log)
+	var (
+		taskOutbox task.Outbox
+		durable    task.EventSink
+	)
+	switch cfg.Events.PublishPolicy {
+	case task.PublishOutbox:
+		// The use cases store Kafka events in the outbox along with their
+		// change, so Kafka gets them from the relay rather than the bus; the
+		// relay stops before the producer does
+		ob := outbox.New(repos.outbox, repos.tx, kafkaSink, cfg.Events.OutboxBatchSize, log)
+		taskOutbox = ob
+		lm.Register("outbox-relay", scheduler.New("outbox-relay", cfg.Events.OutboxInterval, ob.Relay, log))
+	case task.PublishStrict:
+		// The use cases hand Kafka its events inside the transaction of their
+		// change; the bus only gets them once the change has committed
+		durable = kafkaSink
+	default:
+		bus.Subscribe("kafka", kafkaSink)
+	}
+	bus.Subscribe("live", broker)
+	lm.Register("event-bus", bus, lifecycle.WithShutdownPhase(lifecycle.PhaseIngress))
+
+	taskLimits := domain.Limits{MaxDescriptionLength: cfg.Tasks.MaxDescriptionLength}
+	taskConfig := task.Config{
+		StatsCacheTTL:            cfg.Tasks.StatsCacheTTL,
+		RequireSubtasksCompleted: cfg.Tasks.RequireSubtasksCompleted,
+		BulkMaxIDs:               cfg.Tasks.BulkMaxIDs,
+		DefaultPriority:          domain.Priority(cfg.Tasks.DefaultPriority),
+		InitialStatus:            domain.TaskStatus(cfg.Tasks.InitialStatus),
+		Sanitize:                 cfg.Tasks.Sanitize,
+		ListDefaultLimit:         cfg.Tasks.ListDefaultLimit,
+		ListMaxLimit:             cfg.Tasks.ListMaxLimit,
+		ListExcludeTerminal:      cfg.Tasks.ListExcludeTerminal,
+		IncludeTaskInEvents:      cfg.Events.IncludeTask,
+		PublishPolicy:            cfg.Events.PublishPolicy,
+		Outbox:                   taskOutbox,
+		Durable:                  durable,
+		DueSoonWindow:            cfg.Tasks.DueSoonWindow,
+		Limits:                   taskLimits,
+	}
+	taskUC := task.New(taskConfig, repos.tasks, repos.tx, bus, log, m)
+	webhookUC := webhook.New(repos.webhooks, log)
+
+	// Generate the next occurrence of completed recurring tasks
+	recurrenceJob := scheduler.New("recurrence", cfg.Tasks.RecurrenceInterval, func(ctx context.Context) error {
+		_, err := taskUC.GenerateRecurringTasks(ctx)
+		return err
+	}, log)
+	lm.Register("recurrence-scheduler", recurrenceJob)
+
+	// Remind of open tasks coming up on their due date
+	dueSoonJob := scheduler.New("due-soon", cfg.Tasks.DueSoonInterval, func(ctx context.Context) error {
+		_, err := taskUC.NotifyDueSoon(ctx)
+		return err
+	}, log)
+	lm.Register("due-soon-scheduler", dueSoonJob)
+
+	// Keep the tasks_by_status gauge in line with the database
+	reconcileJob := scheduler.New("metrics-reconcile", cfg.Tasks.MetricsReconcileInterval, func(ctx context.Context) error {
+		_, err := taskUC.ReconcileMetrics(ctx)
+		return err
+	}, log)
+	lm.Register("metrics-reconciler", reconcileJob)
+
+	// Check the dependencies in the background so dependency_up stays current
+	// when nothing calls /readyz
+	dependencyJob := scheduler.New("dependency-check", cfg.Server.DependencyCheckInterval, func(ctx context.Context) error {
+		readiness.Check(ctx)
+		return nil
+	}, log)
+	lm.Register("dependency-checker", dependencyJob)
+
+	// POST task events to webhook subscribers; drains before the database closes
+	dispatcherConfig := webhookdelivery.Config{
+		Workers:        cfg.Webhooks.Workers,
+		QueueSize:      cfg.Webhooks.QueueSize,
+		Timeout:        cfg.Webhooks.Timeout,
+		MaxAttempts:    cfg.Webhooks.MaxAttempts,
+		InitialBackoff: cfg.Webhooks.InitialBackoff,
+		MaxBackoff:     cfg.Webhooks.MaxBackoff,
+	}
+	webhookClient := httpclient.New("webhooks", httpClientConfig(cfg.HTTPClient), m)
+	dispatcher := webhookdelivery.NewDispatcher(dispatcherConfig, repos.webhooks, webhookClient, m, log)
+	bus.Subscribe("webhooks", dispatcher)
+	lm.Register("webhook-dispatcher", dispatcher)
+
+	// 6. Initialize Kafka Consumer (validation guarantees the producer is enabled too)
+	if cfg.Kafka.ConsumerEnabled() {
+		log.Info("Initializing Kafka consumer...")
+		retryTiers := make([]kafka.RetryTier, 0, len(cfg.Kafka.Retry.Tiers))
+		for _, tier := range cfg.Kafka.Retry.Tiers {
+			retryTiers = append(retryTiers, kafka.RetryTier{Topic: tier.Topic, Delay: tier.Delay})
+		}
+		retrier := kafka.NewRetrier(retryTiers, producer, dlq, log)
+
+		// Without its topics the consumer would silently receive nothing
+		topicsConfig := kafka.TopicsConfig{
+			Brokers:           cfg.Kafka.Brokers,
+			Topics:            append([]string{cfg.Kafka.Topics.TaskEvents, cfg.Kafka.Topics.DeadLetter}, retrier.Topics()...),
+			AutoCreate:        cfg.Kafka.Topics.AutoCreate,
+			Partitions:        cfg.Kafka.Topics.Partitions,
+			ReplicationFactor: cfg.Kafka.Topics.ReplicationFactor,
+			Version:           cfg.Kafka.Version,
+			Security:          kafkaSecurity(cfg.Kafka),
+		}
+		if err := kafka.EnsureTopics(topicsConfig, log); err != nil {
+			return nil, fmt.Errorf("failed to verify kafka topics: %w", err)
+		}
+
+		eventHandler := kafka.NewTaskEventHandler(retrier, repos.processedEvents, log)
+		consumerConfig := kafka.ConsumerConfig{
+			Brokers:          cfg.Kafka.Brokers,
+			GroupID:          cfg.Kafka.ConsumerGroupID,
+			Topics:           append([]string{cfg.Kafka.Topics.TaskEvents}, retrier.Topics()...),
+			Workers:          cfg.Kafka.Consumer.Workers,
+			SessionTimeout:   cfg.Kafka.Consumer.SessionTimeout.String(),
+			RebalanceTimeout: cfg.Kafka.Consumer.RebalanceTimeout.String(),
+			OffsetInitial:    cfg.Kafka.Consumer.OffsetInitial,
+			Version:          cfg.Kafka.Version,
+			Security:         kafkaSecurity(cfg.Kafka),
+		}
+		consumer, err := kafka.NewConsumer(consumerConfig, eventHandler, log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize kafka consumer: %w", err)
+		}
+		lm.Register("kafka-consumer", consumer)
+
+		// Forget processed events once redeliveries of them are no longer expected
+		processedCleanupJob := scheduler.New("processed-events-cleanup", cfg.Kafka.Consumer.ProcessedEventsCleanupInterval, func(ctx context.Context) error {
+			deleted, err := repos.processedEvents.DeleteBefore(ctx, time.Now().Add(-cfg.Kafka.Consumer.ProcessedEventsTTL))
+			if deleted > 0 {
+				log.Info("Deleted %d processed event IDs older than %s", deleted, cfg.Kafka.Consumer.ProcessedEventsTTL)
+			}
+			return err
+		}, log)
+		lm.Register("processed-events-cleanup", processedCleanupJob)
+	} else {
+		log.Info("Kafka consumer is disabled")
 	}
-	lm.Register("kafka-consumer", consumer)
 
-	// 8. Initialize HTTP Server
+	// 7. Initialize HTTP Server
 	log.Info("Initializing HTTP server...")
+	verifier, err := auth.NewVerifier(cfg.Auth.JWTSecret, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize token verifier: %w", err)
+	}
 	serverConfig := httpdelivery.Config{
-		Host:            cfg.Server.Host,
-		Port:            cfg.Server.Port,
-		ReadTimeout:     cfg.Server.ReadTimeout,
-		WriteTimeout:    cfg.Server.WriteTimeout,
-		ShutdownTimeout: cfg.Server.ShutdownTimeout,
+		Host:                  cfg.Server.Host,
+		Port:                  cfg.Server.Port,
+		ReadTimeout:           cfg.Server.ReadTimeout,
+		WriteTimeout:          cfg.Server.WriteTimeout,
+		RequestTimeout:        cfg.Server.RequestTimeout,
+		MaxConcurrentRequests: cfg.Server.MaxConcurrentRequests,
+		ShutdownTimeout:       cfg.Server.ShutdownTimeout,
+		ReadHeaderTimeout:     cfg.Server.ReadHeaderTimeout,
+		IdleTimeout:           cfg.Server.IdleTimeout,
+		MaxHeaderBytes:        cfg.Server.MaxHeaderBytes,
+		H2C:                   cfg.Server.H2C,
+		TLS: httpdelivery.TLSConfig{
+			CertFile:   cfg.Server.TLS.CertFile,
+			KeyFile:    cfg.Server.TLS.KeyFile,
+			MinVersion: cfg.Server.TLS.MinVersion,
+		},
+		EventsHeartbeat: cfg.Events.HeartbeatInterval,
+		TaskIDFormat:    cfg.Tasks.IDFormat,
+		DLQReplayMax:    cfg.Kafka.DLQ.ReplayMax,
+		TaskLimits:      taskLimits,
+		ListCacheMaxAge: cfg.Server.ListCacheMaxAge,
+		ResponseFormat:  cfg.Server.ResponseFormat,
+		Build:           build,
+		Auth:            verifier,
 	}
-	httpServer := httpdelivery.New(serverConfig, taskUC, m, log)
-	lm.Register("http-server", httpServer)
+	httpServer := httpdelivery.New(serverConfig, taskUC, webhookUC, broker, dlqReplayer, readiness, m, log)
+	lm.Register("http-server", httpServer, lifecycle.WithShutdownPhase(lifecycle.PhaseIngress))
+
+	// Shuts down alongside the HTTP server and closes open event streams;
+	// otherwise the server would wait on them until the timeout
+	lm.Register("event-broker", broker, lifecycle.WithShutdownPhase(lifecycle.PhaseIngress))
 
 	return &application{
 		lifecycle: lm,
@@ -214,12 +533,119 @@ func initApp(cfg *config.Config, log logger.ILogger) (*application, error) {
 	}, nil
 }
 
-func printStartupInfo(cfg *config.Config, log logger.ILogger) {
+// repositories are the data access implementations of the configured database driver
+type repositories struct {
+	tasks           task.Repository
+	tx              task.TxManager
+	webhooks        webhook.Repository
+	outbox          outbox.Store
+	processedEvents kafka.ProcessedEvents
+}
+
+// initRepositories connects to the configured database and creates the repositories on top of it
+// and registers its readiness check
+func initRepositories(cfg *config.Config, lm *lifecycle.Manager, m *metrics.Metrics, readiness *health.Checker, log logger.ILogger) (*repositories, error) {
+	if cfg.DB.Driver == config.DBDriverMemory {
+		log.Warn("Using the in-memory database: data is lost on restart")
+		store := memory.NewStore()
+		return &repositories{
+			tasks:           memory.NewTaskRepository(store, log),
+			tx:              memory.NewTxManager(store, log),
+			webhooks:        memory.NewWebhookRepository(log),
+			outbox:          memory.NewOutboxRepository(store, log),
+			processedEvents: memory.NewProcessedEventRepository(log),
+		}, nil
+	}
+
+	log.Info("Initializing database...")
+	dbTracer := tracing.GetTracer("postgres")
+	db, err := postgres.New(dbConfig(cfg.DB), log, m, dbTracer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+	lm.Register("database", db,
+		lifecycle.WithDependsOn("metrics", "tracing"),
+		lifecycle.WithShutdownPhase(lifecycle.PhaseClients))
+	readiness.Register("database", db.Ping)
+
+	log.Info("Initializing repositories...")
+	return &repositories{
+		tasks:           repository.NewTaskRepository(db, log),
+		tx:              repository.NewTxManager(db, log),
+		webhooks:        repository.NewWebhookRepository(db, log),
+		outbox:          repository.NewOutboxRepository(db, log),
+		processedEvents: repository.NewProcessedEventRepository(db, log),
+	}, nil
+}
+
+// dbConfig converts the database settings into the pool configuration
+func dbConfig(c config.DBConfig) postgres.Config {
+	return postgres.Config{
+		DSN:                    c.DSN(),
+		MaxOpenConns:           int32(c.MaxOpenConns),
+		MaxIdleConns:           int32(c.MaxIdleConns),
+		ConnMaxLifetime:        c.ConnMaxLifetime,
+		ConnMaxIdleTime:        c.ConnMaxIdleTime,
+		QueryExecMode:          c.QueryExecMode,
+		StatementCacheCapacity: c.StatementCacheCapacity,
+		Retry: postgres.RetryConfig{
+			MaxAttempts:    c.RetryMaxAttempts,
+			InitialBackoff: c.RetryInitialBackoff,
+			MaxBackoff:     c.RetryMaxBackoff,
+		},
+		DeadlineStatementTimeout: c.DeadlineStatementTimeout,
+		WarmupTimeout:            c.WarmupTimeout,
+		Monitor: postgres.PoolMonitorConfig{
+			Interval:            c.PoolMonitorInterval,
+			SaturationThreshold: c.PoolSaturationThreshold,
+			SaturationDuration:  c.PoolSaturationDuration,
+		},
+	}
+}
+
+// httpClientConfig converts the outbound HTTP client settings
+func httpClientConfig(c config.HTTPClientConfig) httpclient.Config {
+	return httpclient.Config{
+		Timeout:               c.Timeout,
+		ConnectTimeout:        c.ConnectTimeout,
+		ResponseHeaderTimeout: c.ResponseHeaderTimeout,
+		MaxIdleConns:          c.MaxIdleConns,
+		MaxIdleConnsPerHost:   c.MaxIdleConnsPerHost,
+		IdleConnTimeout:       c.IdleConnTimeout,
+		KeepAlive:             c.KeepAlive,
+	}
+}
+
+// kafkaSecurity converts the SASL and TLS settings shared by every Kafka client
+func kafkaSecurity(c config.KafkaConfig) kafka.SecurityConfig {
+	return kafka.SecurityConfig{
+		SASL: kafka.SASLConfig{
+			Mechanism: c.SASL.Mechanism,
+			Username:  c.SASL.Username,
+			Password:  c.SASL.Password,
+		},
+		TLS: kafka.TLSConfig{
+			Enabled:            c.TLS.Enabled,
+			CAFile:             c.TLS.CAFile,
+			CertFile:           c.TLS.CertFile,
+			KeyFile:            c.TLS.KeyFile,
+			InsecureSkipVerify: c.TLS.InsecureSkipVerify,
+		},
+	}
+}
+
+func printStartupInfo(cfg *config.Config, build buildinfo.Info, log logger.ILogger) {
 	log.Info("===========================================")
-	log.Info("  %s v%s", cfg.App.Name, cfg.App.Version)
+	log.Info("  %s v%s (%s)", build.Name, build.Version, build.Commit)
 	log.Info("===========================================")
-	log.Info("HTTP Server:   http://%s:%d", cfg.Server.Host, cfg.Server.Port)
-	log.Info("Health Check:  http://%s:%d/health", cfg.Server.Host, cfg.Server.Port)
+	scheme := "http"
+	if cfg.Server.TLS.CertFile != "" {
+		scheme = "https"
+	}
+	log.Info("HTTP Server:   %s://%s:%d", scheme, cfg.Server.Host, cfg.Server.Port)
+	log.Info("Health Check:  %s://%s:%d/health", scheme, cfg.Server.Host, cfg.Server.Port)
+	log.Info("Readiness:     %s://%s:%d/readyz", scheme, cfg.Server.Host, cfg.Server.Port)
+	log.Info("Database:      %s", cfg.DB)
 	if cfg.Metrics.Enabled {
 		log.Info("Metrics:       http://localhost:%d%s", cfg.Metrics.Port, cfg.Metrics.Path)
 	}
@@ -1,19 +1,46 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
+	"strconv"
 	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/auth"
+	"github.com/seldomhappy/vibe_architecture/logger"
 )
 
 // Config represents the complete application configuration
 type Config struct {
-	App     AppConfig     `yaml:"app"`
-	Server  ServerConfig  `yaml:"server"`
-	Logger  LoggerConfig  `yaml:"logger"`
-	DB      DBConfig      `yaml:"db"`
-	Tracing TracingConfig `yaml:"tracing"`
-	Metrics MetricsConfig `yaml:"metrics"`
-	Kafka   KafkaConfig   `yaml:"kafka"`
+	App      AppConfig      `yaml:"app"`
+	Server   ServerConfig   `yaml:"server"`
+	Logger   LoggerConfig   `yaml:"logger"`
+	DB       DBConfig       `yaml:"db"`
+	Tracing  TracingConfig  `yaml:"tracing"`
+	Metrics  MetricsConfig  `yaml:"metrics"`
+	Kafka    KafkaConfig    `yaml:"kafka"`
+	Tasks    TasksConfig    `yaml:"tasks"`
+	Events   EventsConfig   `yaml:"events"`
+	Webhooks WebhooksConfig `yaml:"webhooks"`
+	// HTTPClient configures the clients of outbound HTTP calls, such as webhook deliveries
+	HTTPClient HTTPClientConfig `yaml:"http_client"`
+	Auth       AuthConfig       `yaml:"auth"`
+}
+
+// Redacted returns a copy of the configuration with all secrets masked
+func (c Config) Redacted() Config {
+	c.DB = c.DB.Redacted()
+	if c.Kafka.SASL.Password != "" {
+		c.Kafka.SASL.Password = redactedSecret
+	}
+	if c.Auth.JWTSecret != "" {
+		c.Auth.JWTSecret = redactedSecret
+	}
+	return c
 }
 
 // AppConfig contains application-level settings
@@ -31,40 +58,234 @@ type ServerConfig struct {
 	ReadTimeout     time.Duration `yaml:"read_timeout" env-default:"10s"`
 	WriteTimeout    time.Duration `yaml:"write_timeout" env-default:"10s"`
 	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env-default:"30s"`
+	// ReadHeaderTimeout bounds reading the request headers; without it a client
+	// can hold a connection open by sending them slowly (Slowloris)
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env-default:"5s"`
+	// IdleTimeout is how long a keep-alive connection waits for the next request
+	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"120s"`
+	// MaxHeaderBytes caps the size of the request headers
+	MaxHeaderBytes int `yaml:"max_header_bytes" env-default:"1048576"`
+	// H2C serves HTTP/2 without TLS, for service-to-service calls inside a trusted network
+	H2C bool `yaml:"h2c" env:"SERVER_H2C" env-default:"false"`
+	// TLS serves HTTPS when cert_file and key_file are set
+	TLS TLSConfig `yaml:"tls"`
+	// ShutdownPhaseTimeout bounds each shutdown phase (ingress, services, clients, telemetry); 0 disables
+	ShutdownPhaseTimeout time.Duration `yaml:"shutdown_phase_timeout" env-default:"10s"`
+	// ShutdownServiceTimeouts bounds the shutdown of single services, by their
+	// lifecycle name (http-server, kafka-consumer, database, ...), within their phase
+	ShutdownServiceTimeouts map[string]time.Duration `yaml:"shutdown_service_timeouts"`
+	// RequestTimeout is the default time budget of a request; clients may ask for
+	// less with the X-Request-Timeout header
+	RequestTimeout time.Duration `yaml:"request_timeout" env:"SERVER_REQUEST_TIMEOUT" env-default:"10s"`
+	// MaxConcurrentRequests caps the requests handled at once; more are turned
+	// away with 503. Event streams and health probes don't count. 0 disables the limit.
+	MaxConcurrentRequests int `yaml:"max_concurrent_requests" env:"SERVER_MAX_CONCURRENT_REQUESTS" env-default:"0"`
+	// ReadinessTimeout bounds each dependency check made by /readyz
+	ReadinessTimeout time.Duration `yaml:"readiness_timeout" env-default:"2s"`
+	// DependencyCheckInterval is how often the dependencies are checked in the
+	// background to keep the dependency_up gauges current without /readyz traffic
+	DependencyCheckInterval time.Duration `yaml:"dependency_check_interval" env:"SERVER_DEPENDENCY_CHECK_INTERVAL" env-default:"15s"`
+	// ListCacheMaxAge is how long clients may reuse a task list without asking
+	// again (Cache-Control: private, max-age); 0 makes them revalidate every time
+	ListCacheMaxAge time.Duration `yaml:"list_cache_max_age" env:"SERVER_LIST_CACHE_MAX_AGE" env-default:"0s"`
+	// ResponseFormat shapes successful responses: bare (the resource or list
+	// itself) or envelope ({"data": ..., "meta": ...})
+	ResponseFormat string `yaml:"response_format" env:"SERVER_RESPONSE_FORMAT" env-default:"bare"`
+}
+
+// TLSConfig contains HTTPS settings. The certificate is reloaded when the files
+// change, so it can be rotated without a restart.
+type TLSConfig struct {
+	CertFile   string `yaml:"cert_file" env:"SERVER_TLS_CERT_FILE"`
+	KeyFile    string `yaml:"key_file" env:"SERVER_TLS_KEY_FILE"`
+	MinVersion string `yaml:"min_version" env:"SERVER_TLS_MIN_VERSION" env-default:"1.2"`
+}
+
+// TasksConfig contains task use case settings
+type TasksConfig struct {
+	StatsCacheTTL            time.Duration `yaml:"stats_cache_ttl" env:"TASKS_STATS_CACHE_TTL" env-default:"30s"`
+	RecurrenceInterval       time.Duration `yaml:"recurrence_interval" env:"TASKS_RECURRENCE_INTERVAL" env-default:"1m"`
+	RequireSubtasksCompleted bool          `yaml:"require_subtasks_completed" env:"TASKS_REQUIRE_SUBTASKS_COMPLETED" env-default:"true"`
+	// DueSoonInterval is how often tasks coming up on their due date are looked for
+	DueSoonInterval time.Duration `yaml:"due_soon_interval" env:"TASKS_DUE_SOON_INTERVAL" env-default:"1m"`
+	// DueSoonWindow is how long before its due date a task gets a task.due_soon event
+	DueSoonWindow time.Duration `yaml:"due_soon_window" env:"TASKS_DUE_SOON_WINDOW" env-default:"24h"`
+	// MetricsReconcileInterval is how often the tasks_by_status gauge is recomputed from the database
+	MetricsReconcileInterval time.Duration `yaml:"metrics_reconcile_interval" env:"TASKS_METRICS_RECONCILE_INTERVAL" env-default:"1m"`
+	// IDFormat selects how tasks are addressed in URLs: int64 or uuid
+	IDFormat string `yaml:"id_format" env:"TASKS_ID_FORMAT" env-default:"int64"`
+	// BulkMaxIDs caps the number of tasks in one bulk status update
+	BulkMaxIDs int `yaml:"bulk_max_ids" env:"TASKS_BULK_MAX_IDS" env-default:"100"`
+	// DefaultPriority is given to tasks created without a priority
+	DefaultPriority string `yaml:"default_priority" env:"TASKS_DEFAULT_PRIORITY" env-default:"medium"`
+	// InitialStatus is the status new tasks start in: pending or in_progress
+	InitialStatus string `yaml:"initial_status" env:"TASKS_INITIAL_STATUS" env-default:"pending"`
+	// MaxDescriptionLength is the longest task description accepted, in characters
+	MaxDescriptionLength int `yaml:"max_description_length" env:"TASKS_MAX_DESCRIPTION_LENGTH" env-default:"10000"`
+	// Sanitize cleans up task names and descriptions before they are stored, for
+	// clients that render them as HTML: none, escape (HTML-escape) or strip (remove tags)
+	Sanitize string `yaml:"sanitize" env:"TASKS_SANITIZE" env-default:"none"`
+	// ListDefaultLimit is the page size of task lists requested without a limit
+	ListDefaultLimit int `yaml:"list_default_limit" env:"TASKS_LIST_DEFAULT_LIMIT" env-default:"50"`
+	// ListExcludeTerminal leaves completed and cancelled tasks out of task lists
+	// unless they ask for a status or ?include=completed,cancelled
+	ListExcludeTerminal bool `yaml:"list_exclude_terminal" env:"TASKS_LIST_EXCLUDE_TERMINAL" env-default:"false"`
+	// ListMaxLimit caps the page size of task lists; larger limits are lowered to it
+	ListMaxLimit int `yaml:"list_max_limit" env:"TASKS_LIST_MAX_LIMIT" env-default:"100"`
+}
+
+// EventsConfig contains live event stream and task event payload settings
+type EventsConfig struct {
+	BufferSize        int           `yaml:"buffer_size" env:"EVENTS_BUFFER_SIZE" env-default:"64"`
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval" env:"EVENTS_HEARTBEAT_INTERVAL" env-default:"15s"`
+	// IncludeTask adds the whole task to created, updated, completed and deleted
+	// events, on Kafka as well as to live subscribers and webhooks
+	IncludeTask bool `yaml:"include_task" env:"EVENTS_INCLUDE_TASK" env-default:"false"`
+	// BusMode is how the event bus hands events to Kafka, live subscribers and
+	// webhooks: sync before the request returns, async from a background queue
+	BusMode string `yaml:"bus_mode" env:"EVENTS_BUS_MODE" env-default:"sync"`
+	// BusTimeout bounds each sink's handling of an event
+	BusTimeout time.Duration `yaml:"bus_timeout" env:"EVENTS_BUS_TIMEOUT" env-default:"10s"`
+	// BusBufferSize is how many events the async bus queues before dropping them
+	BusBufferSize int `yaml:"bus_buffer_size" env:"EVENTS_BUS_BUFFER_SIZE" env-default:"1000"`
+	// PublishPolicy is what happens when an event can't be published: best_effort
+	// logs it, strict rolls the change back and fails the request when Kafka fails,
+	// outbox keeps Kafka events until Kafka is back
+	PublishPolicy string `yaml:"publish_policy" env:"EVENTS_PUBLISH_POLICY" env-default:"best_effort"`
+	// OutboxInterval is how often the outbox publishes stored events
+	OutboxInterval time.Duration `yaml:"outbox_interval" env:"EVENTS_OUTBOX_INTERVAL" env-default:"1s"`
+	// OutboxBatchSize is how many stored events the outbox reads at a time
+	OutboxBatchSize int `yaml:"outbox_batch_size" env:"EVENTS_OUTBOX_BATCH_SIZE" env-default:"100"`
+}
+
+// WebhooksConfig contains webhook delivery settings
+type WebhooksConfig struct {
+	Workers int `yaml:"workers" env:"WEBHOOKS_WORKERS" env-default:"4"`
+	// QueueSize is how many deliveries may wait for a worker before events are dropped
+	QueueSize int           `yaml:"queue_size" env:"WEBHOOKS_QUEUE_SIZE" env-default:"1000"`
+	Timeout   time.Duration `yaml:"timeout" env:"WEBHOOKS_TIMEOUT" env-default:"5s"`
+	// MaxAttempts failed attempts move an event to the webhook's dead letters
+	MaxAttempts    int           `yaml:"max_attempts" env:"WEBHOOKS_MAX_ATTEMPTS" env-default:"5"`
+	InitialBackoff time.Duration `yaml:"initial_backoff" env:"WEBHOOKS_INITIAL_BACKOFF" env-default:"1s"`
+	MaxBackoff     time.Duration `yaml:"max_backoff" env:"WEBHOOKS_MAX_BACKOFF" env-default:"1m"`
+}
+
+// HTTPClientConfig contains outbound HTTP client settings
+type HTTPClientConfig struct {
+	// Timeout bounds a whole request, from dialing to reading the response body
+	Timeout time.Duration `yaml:"timeout" env:"HTTP_CLIENT_TIMEOUT" env-default:"30s"`
+	// ConnectTimeout bounds establishing a connection, TLS handshake included
+	ConnectTimeout time.Duration `yaml:"connect_timeout" env:"HTTP_CLIENT_CONNECT_TIMEOUT" env-default:"5s"`
+	// ResponseHeaderTimeout bounds waiting for the response headers once the request is sent
+	ResponseHeaderTimeout time.Duration `yaml:"response_header_timeout" env:"HTTP_CLIENT_RESPONSE_HEADER_TIMEOUT" env-default:"10s"`
+	MaxIdleConns          int           `yaml:"max_idle_conns" env-default:"100"`
+	MaxIdleConnsPerHost   int           `yaml:"max_idle_conns_per_host" env-default:"10"`
+	IdleConnTimeout       time.Duration `yaml:"idle_conn_timeout" env-default:"90s"`
+	// KeepAlive is the interval of TCP keep-alive probes; negative disables them
+	KeepAlive time.Duration `yaml:"keep_alive" env-default:"30s"`
+}
+
+// AuthConfig contains the settings of caller authentication.
+// Callers send an HS256 JWT signed with JWTSecret as a bearer token; its sub,
+// tenant_id and role claims identify the user, their tenant and their role.
+type AuthConfig struct {
+	JWTSecret string `yaml:"jwt_secret" env:"AUTH_JWT_SECRET"`
 }
 
-// LoggerConfig contains logging settings
+// LoggerConfig contains logging settings.
+// Format is json (for log shippers), console (colorized, for local development) or text.
+// Output is stdout, stderr or a file path; files rotate by size and age.
+// DedupWindow, when set, collapses warnings and errors repeated within it into one line.
 type LoggerConfig struct {
-	Level  string `yaml:"level" env:"LOG_LEVEL" env-default:"info"`
-	Format string `yaml:"format" env:"LOG_FORMAT" env-default:"json"`
+	Level       string        `yaml:"level" env:"LOG_LEVEL" env-default:"info"`
+	Format      string        `yaml:"format" env:"LOG_FORMAT" env-default:"json"`
+	Output      string        `yaml:"output" env:"LOG_OUTPUT" env-default:"stdout"`
+	MaxSizeMB   int           `yaml:"max_size_mb" env:"LOG_MAX_SIZE_MB" env-default:"100"`
+	MaxAge      time.Duration `yaml:"max_age" env:"LOG_MAX_AGE" env-default:"24h"`
+	MaxBackups  int           `yaml:"max_backups" env:"LOG_MAX_BACKUPS" env-default:"7"`
+	DedupWindow time.Duration `yaml:"dedup_window" env:"LOG_DEDUP_WINDOW" env-default:"0s"`
 }
 
 // DBConfig contains database connection settings
 type DBConfig struct {
-	Host            string        `yaml:"host" env:"DB_HOST" env-default:"localhost"`
-	Port            int           `yaml:"port" env:"DB_PORT" env-default:"5432"`
-	User            string        `yaml:"user" env:"DB_USER" env-default:"postgres"`
-	Password        string        `yaml:"password" env:"DB_PASSWORD" env-default:"postgres"`
-	Database        string        `yaml:"database" env:"DB_NAME" env-default:"vibe_architecture"`
-	SSLMode         string        `yaml:"ssl_mode" env:"DB_SSL_MODE" env-default:"disable"`
-	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS" env-default:"25"`
-	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS" env-default:"5"`
-	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME" env-default:"5m"`
-	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME" env-default:"5m"`
-}
-
-// DSN returns the PostgreSQL connection string
+	Driver                 string        `yaml:"driver" env:"DB_DRIVER" env-default:"postgres"`
+	Host                   string        `yaml:"host" env:"DB_HOST" env-default:"localhost"`
+	Port                   int           `yaml:"port" env:"DB_PORT" env-default:"5432"`
+	User                   string        `yaml:"user" env:"DB_USER" env-default:"postgres"`
+	Password               string        `yaml:"password" env:"DB_PASSWORD" env-default:"postgres"`
+	Database               string        `yaml:"database" env:"DB_NAME" env-default:"vibe_architecture"`
+	SSLMode                string        `yaml:"ssl_mode" env:"DB_SSL_MODE" env-default:"disable"`
+	MaxOpenConns           int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS" env-default:"25"`
+	MaxIdleConns           int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS" env-default:"5"`
+	ConnMaxLifetime        time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME" env-default:"5m"`
+	ConnMaxIdleTime        time.Duration `yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME" env-default:"5m"`
+	QueryExecMode          string        `yaml:"query_exec_mode" env:"DB_QUERY_EXEC_MODE" env-default:"cache_statement"`
+	StatementCacheCapacity int           `yaml:"statement_cache_capacity" env:"DB_STATEMENT_CACHE_CAPACITY" env-default:"512"`
+	RetryMaxAttempts       int           `yaml:"retry_max_attempts" env:"DB_RETRY_MAX_ATTEMPTS" env-default:"3"`
+	RetryInitialBackoff    time.Duration `yaml:"retry_initial_backoff" env:"DB_RETRY_INITIAL_BACKOFF" env-default:"50ms"`
+	RetryMaxBackoff        time.Duration `yaml:"retry_max_backoff" env:"DB_RETRY_MAX_BACKOFF" env-default:"1s"`
+	// DeadlineStatementTimeout sets statement_timeout from the request deadline, so
+	// PostgreSQL stops queries the request no longer waits for
+	DeadlineStatementTimeout bool `yaml:"deadline_statement_timeout" env:"DB_DEADLINE_STATEMENT_TIMEOUT" env-default:"true"`
+	// WarmupTimeout bounds opening max_idle_conns connections on start; 0 disables the warm-up
+	WarmupTimeout time.Duration `yaml:"warmup_timeout" env:"DB_WARMUP_TIMEOUT" env-default:"10s"`
+	// PoolMonitorInterval is how often pool stats are exported and logged
+	PoolMonitorInterval time.Duration `yaml:"pool_monitor_interval" env:"DB_POOL_MONITOR_INTERVAL" env-default:"15s"`
+	// PoolSaturationThreshold is the share of max_open_conns in use, from 0 to 1,
+	// from which the pool counts as saturated; 0 disables the warning
+	PoolSaturationThreshold float64 `yaml:"pool_saturation_threshold" env:"DB_POOL_SATURATION_THRESHOLD" env-default:"0.9"`
+	// PoolSaturationDuration is how long the pool must stay saturated before a warning is logged
+	PoolSaturationDuration time.Duration `yaml:"pool_saturation_duration" env:"DB_POOL_SATURATION_DURATION" env-default:"1m"`
+}
+
+// redactedSecret replaces secrets in redacted output
+const redactedSecret = "****"
+
+// DSN returns the PostgreSQL connection string.
+// It contains the password: log Redacted().DSN() instead.
 func (c DBConfig) DSN() string {
 	return fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=%s",
 		c.User, c.Password, c.Host, c.Port, c.Database, c.SSLMode)
 }
 
+// Redacted returns a copy with the password masked
+func (c DBConfig) Redacted() DBConfig {
+	if c.Password != "" {
+		c.Password = redactedSecret
+	}
+	return c
+}
+
+// String returns the redacted DSN so the config is safe to print
+func (c DBConfig) String() string {
+	if c.Driver == DBDriverMemory {
+		return "in-memory"
+	}
+	return c.Redacted().DSN()
+}
+
+// Database drivers
+const (
+	DBDriverPostgres = "postgres"
+	// DBDriverMemory keeps data in memory, for demos and tests without PostgreSQL
+	DBDriverMemory = "memory"
+)
+
 // TracingConfig contains OpenTelemetry tracing settings
 type TracingConfig struct {
-	Enabled         bool    `yaml:"enabled" env:"TRACING_ENABLED" env-default:"true"`
-	ServiceName     string  `yaml:"service_name" env:"TRACING_SERVICE_NAME"`
-	JaegerEndpoint  string  `yaml:"jaeger_endpoint" env:"JAEGER_ENDPOINT" env-default:"http://localhost:14268/api/traces"`
-	SamplingRate    float64 `yaml:"sampling_rate" env:"TRACING_SAMPLING_RATE" env-default:"1.0"`
+	Enabled        bool    `yaml:"enabled" env:"TRACING_ENABLED" env-default:"true"`
+	ServiceName    string  `yaml:"service_name" env:"TRACING_SERVICE_NAME"`
+	JaegerEndpoint string  `yaml:"jaeger_endpoint" env:"JAEGER_ENDPOINT" env-default:"http://localhost:14268/api/traces"`
+	SamplingRate   float64 `yaml:"sampling_rate" env:"TRACING_SAMPLING_RATE" env-default:"1.0"`
+	// Sampler is ratio (sampling_rate of all spans) or parent_ratio (follow the
+	// caller's decision, sampling_rate of the traces that start here)
+	Sampler string `yaml:"sampler" env:"TRACING_SAMPLER" env-default:"ratio"`
+	// KeepErrors also exports spans that end with an error when their trace
+	// wasn't sampled
+	KeepErrors bool `yaml:"keep_errors" env:"TRACING_KEEP_ERRORS" env-default:"false"`
+	// KeepSlowerThan also exports spans that take longer when their trace wasn't
+	// sampled; 0 disables
+	KeepSlowerThan time.Duration `yaml:"keep_slower_than" env:"TRACING_KEEP_SLOWER_THAN" env-default:"0s"`
 }
 
 // MetricsConfig contains Prometheus metrics settings
@@ -76,53 +297,324 @@ type MetricsConfig struct {
 
 // KafkaConfig contains Kafka settings
 type KafkaConfig struct {
-	Brokers         []string      `yaml:"brokers" env:"KAFKA_BROKERS" env-default:"localhost:9092"`
-	ConsumerGroupID string        `yaml:"consumer_group_id" env:"KAFKA_CONSUMER_GROUP_ID" env-default:"vibe-architecture-group"`
-	Topics          TopicsConfig  `yaml:"topics"`
-	Producer        ProducerConfig `yaml:"producer"`
-	Consumer        ConsumerConfig `yaml:"consumer"`
+	// Enabled turns Kafka off entirely: events are dropped and nothing is
+	// consumed. Brokers may then be empty.
+	Enabled         bool            `yaml:"enabled" env:"KAFKA_ENABLED" env-default:"true"`
+	Brokers         []string        `yaml:"brokers" env:"KAFKA_BROKERS" env-default:"localhost:9092"`
+	ConsumerGroupID string          `yaml:"consumer_group_id" env:"KAFKA_CONSUMER_GROUP_ID" env-default:"vibe-architecture-group"`
+	Version         string          `yaml:"version" env:"KAFKA_VERSION" env-default:"2.6.0"`
+	Topics          TopicsConfig    `yaml:"topics"`
+	Producer        ProducerConfig  `yaml:"producer"`
+	Consumer        ConsumerConfig  `yaml:"consumer"`
+	DLQ             DLQConfig       `yaml:"dlq"`
+	Retry           RetryConfig     `yaml:"retry"`
+	SASL            KafkaSASLConfig `yaml:"sasl"`
+	TLS             KafkaTLSConfig  `yaml:"tls"`
+}
+
+// KafkaSASLConfig contains the credentials the clients authenticate with
+type KafkaSASLConfig struct {
+	// Mechanism is PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512; empty disables SASL
+	Mechanism string `yaml:"mechanism" env:"KAFKA_SASL_MECHANISM"`
+	Username  string `yaml:"username" env:"KAFKA_SASL_USERNAME"`
+	Password  string `yaml:"password" env:"KAFKA_SASL_PASSWORD"`
+}
+
+// KafkaTLSConfig contains the settings of TLS connections to the brokers
+type KafkaTLSConfig struct {
+	Enabled bool `yaml:"enabled" env:"KAFKA_TLS_ENABLED" env-default:"false"`
+	// CAFile verifies the brokers' certificates; empty trusts the system roots
+	CAFile string `yaml:"ca_file" env:"KAFKA_TLS_CA_FILE"`
+	// CertFile and KeyFile authenticate the client with mutual TLS
+	CertFile string `yaml:"cert_file" env:"KAFKA_TLS_CERT_FILE"`
+	KeyFile  string `yaml:"key_file" env:"KAFKA_TLS_KEY_FILE"`
+	// InsecureSkipVerify accepts any broker certificate. Never use it in production.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify" env:"KAFKA_TLS_INSECURE_SKIP_VERIFY" env-default:"false"`
+}
+
+// ProducerEnabled reports whether task events are published to Kafka
+func (c KafkaConfig) ProducerEnabled() bool {
+	return c.Enabled && c.Producer.Enabled
+}
+
+// ConsumerEnabled reports whether task events are consumed from Kafka
+func (c KafkaConfig) ConsumerEnabled() bool {
+	return c.Enabled && c.Consumer.Enabled
 }
 
 // TopicsConfig contains Kafka topic names
 type TopicsConfig struct {
 	TaskEvents string `yaml:"task_events" env:"KAFKA_TOPIC_TASK_EVENTS" env-default:"task.events"`
+	// DeadLetter receives messages the consumer could not process
+	DeadLetter string `yaml:"dead_letter" env:"KAFKA_TOPIC_DEAD_LETTER" env-default:"task.events.dlq"`
+	// AutoCreate creates the topics the consumer needs at startup when they are
+	// missing; otherwise a missing topic fails startup
+	AutoCreate bool `yaml:"auto_create" env:"KAFKA_TOPICS_AUTO_CREATE" env-default:"false"`
+	// Partitions and ReplicationFactor are the settings of auto-created topics
+	Partitions        int32 `yaml:"partitions" env:"KAFKA_TOPICS_PARTITIONS" env-default:"3"`
+	ReplicationFactor int16 `yaml:"replication_factor" env:"KAFKA_TOPICS_REPLICATION_FACTOR" env-default:"1"`
 }
 
 // ProducerConfig contains Kafka producer settings
 type ProducerConfig struct {
-	Compression     string        `yaml:"compression" env-default:"snappy"`
-	RetryMax        int           `yaml:"retry_max" env-default:"3"`
-	RetryBackoff    time.Duration `yaml:"retry_backoff" env-default:"100ms"`
-	Idempotent      bool          `yaml:"idempotent" env-default:"true"`
-	Timeout         time.Duration `yaml:"timeout" env-default:"10s"`
+	Enabled      bool          `yaml:"enabled" env:"KAFKA_PRODUCER_ENABLED" env-default:"true"`
+	Compression  string        `yaml:"compression" env-default:"snappy"`
+	RetryMax     int           `yaml:"retry_max" env-default:"3"`
+	RetryBackoff time.Duration `yaml:"retry_backoff" env-default:"100ms"`
+	Idempotent   bool          `yaml:"idempotent" env-default:"true"`
+	Timeout      time.Duration `yaml:"timeout" env-default:"10s"`
+	Breaker      BreakerConfig `yaml:"breaker"`
+	// KeyStrategy picks the message key, and with it the partition: task_id keeps
+	// each task's events in order, assigned_to keeps each assignee's in order,
+	// none spreads events evenly with no ordering at all
+	KeyStrategy string `yaml:"key_strategy" env:"KAFKA_PRODUCER_KEY_STRATEGY" env-default:"task_id"`
+}
+
+// BreakerConfig contains the Kafka producer circuit breaker settings
+type BreakerConfig struct {
+	// FailureThreshold consecutive failed sends open the circuit; 0 disables the breaker
+	FailureThreshold int `yaml:"failure_threshold" env:"KAFKA_PRODUCER_BREAKER_FAILURE_THRESHOLD" env-default:"5"`
+	// OpenTimeout is how long events are dropped before a send probes the broker again
+	OpenTimeout time.Duration `yaml:"open_timeout" env:"KAFKA_PRODUCER_BREAKER_OPEN_TIMEOUT" env-default:"30s"`
 }
 
 // ConsumerConfig contains Kafka consumer settings
 type ConsumerConfig struct {
-	Workers         int           `yaml:"workers" env:"KAFKA_CONSUMER_WORKERS" env-default:"3"`
-	SessionTimeout  time.Duration `yaml:"session_timeout" env-default:"10s"`
+	Enabled          bool          `yaml:"enabled" env:"KAFKA_CONSUMER_ENABLED" env-default:"true"`
+	Workers          int           `yaml:"workers" env:"KAFKA_CONSUMER_WORKERS" env-default:"3"`
+	SessionTimeout   time.Duration `yaml:"session_timeout" env-default:"10s"`
 	RebalanceTimeout time.Duration `yaml:"rebalance_timeout" env-default:"60s"`
+	OffsetInitial    string        `yaml:"offset_initial" env:"KAFKA_CONSUMER_OFFSET_INITIAL" env-default:"newest"`
+	// ProcessedEventsTTL is how long the IDs of processed events are kept to
+	// skip redeliveries of them
+	ProcessedEventsTTL time.Duration `yaml:"processed_events_ttl" env:"KAFKA_CONSUMER_PROCESSED_EVENTS_TTL" env-default:"168h"`
+	// ProcessedEventsCleanupInterval is how often IDs older than ProcessedEventsTTL are deleted
+	ProcessedEventsCleanupInterval time.Duration `yaml:"processed_events_cleanup_interval" env-default:"1h"`
 }
 
-// Validate performs validation on the configuration
+// DLQConfig contains dead letter queue settings
+type DLQConfig struct {
+	// ReplayMax caps how many messages one replay request may republish
+	ReplayMax int `yaml:"replay_max" env:"KAFKA_DLQ_REPLAY_MAX" env-default:"1000"`
+}
+
+// RetryConfig contains the retry tiers for messages the consumer failed to process.
+// A failed message moves to the next tier and waits out its delay before being
+// retried; after the last tier it goes to the dead letter queue.
+type RetryConfig struct {
+	Tiers []RetryTierConfig `yaml:"tiers"`
+}
+
+// RetryTierConfig is a single retry topic and its delay
+type RetryTierConfig struct {
+	Topic string        `yaml:"topic"`
+	Delay time.Duration `yaml:"delay"`
+}
+
+// sslModes are the sslmode values accepted by PostgreSQL
+var sslModes = map[string]bool{
+	"disable":     true,
+	"allow":       true,
+	"prefer":      true,
+	"require":     true,
+	"verify-ca":   true,
+	"verify-full": true,
+}
+
+// queryExecModes are the accepted db.query_exec_mode values
+var queryExecModes = map[string]bool{
+	"cache_statement": true,
+	"cache_describe":  true,
+	"describe_exec":   true,
+	"exec":            true,
+	"simple_protocol": true,
+}
+
+// Validate performs validation on the configuration.
+// All problems are reported together, joined into a single error.
 func (c *Config) Validate() error {
-	if c.App.Name == "" {
-		return fmt.Errorf("app.name is required")
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.App.Name != "", "app.name is required")
+
+	check(validPort(c.Server.Port), "server.port must be between 1 and 65535")
+	check(c.Server.ReadTimeout > 0, "server.read_timeout must be positive")
+	check(c.Server.WriteTimeout > 0, "server.write_timeout must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(c.Server.ReadHeaderTimeout > 0, "server.read_header_timeout must be positive")
+	check(c.Server.IdleTimeout >= 0, "server.idle_timeout must not be negative")
+	check(c.Server.MaxHeaderBytes > 0, "server.max_header_bytes must be positive")
+	check((c.Server.TLS.CertFile == "") == (c.Server.TLS.KeyFile == ""), "server.tls.cert_file and server.tls.key_file must be set together")
+	check(c.Server.TLS.MinVersion == "1.2" || c.Server.TLS.MinVersion == "1.3", "server.tls.min_version must be 1.2 or 1.3")
+	check(c.Server.ShutdownPhaseTimeout >= 0, "server.shutdown_phase_timeout must not be negative")
+	for name, timeout := range c.Server.ShutdownServiceTimeouts {
+		check(timeout > 0, "server.shutdown_service_timeouts.%s must be positive", name)
 	}
-	if c.Server.Port <= 0 || c.Server.Port > 65535 {
-		return fmt.Errorf("server.port must be between 1 and 65535")
+	check(c.Server.RequestTimeout > 0, "server.request_timeout must be positive")
+	check(c.Server.RequestTimeout <= c.Server.WriteTimeout, "server.request_timeout must not exceed server.write_timeout")
+	check(c.Server.MaxConcurrentRequests >= 0, "server.max_concurrent_requests must not be negative")
+	check(c.Server.ReadinessTimeout > 0, "server.readiness_timeout must be positive")
+	check(c.Server.DependencyCheckInterval > 0, "server.dependency_check_interval must be positive")
+	check(c.Server.ListCacheMaxAge >= 0, "server.list_cache_max_age must not be negative")
+	check(c.Server.ResponseFormat == "bare" || c.Server.ResponseFormat == "envelope", "server.response_format must be bare or envelope")
+
+	if _, err := logger.ParseLevel(c.Logger.Level); err != nil {
+		errs = append(errs, fmt.Errorf("logger.level: %w", err))
 	}
-	if c.DB.Host == "" {
-		return fmt.Errorf("db.host is required")
+	if _, err := logger.ParseFormat(c.Logger.Format); err != nil {
+		errs = append(errs, fmt.Errorf("logger.format: %w", err))
 	}
-	if c.DB.Database == "" {
-		return fmt.Errorf("db.database is required")
+	check(c.Logger.Output != "", "logger.output is required")
+	check(c.Logger.MaxSizeMB >= 0, "logger.max_size_mb must not be negative")
+	check(c.Logger.MaxAge >= 0, "logger.max_age must not be negative")
+	check(c.Logger.MaxBackups >= 0, "logger.max_backups must not be negative")
+	check(c.Logger.DedupWindow >= 0, "logger.dedup_window must not be negative")
+
+	check(c.DB.Driver == DBDriverPostgres || c.DB.Driver == DBDriverMemory, "db.driver must be postgres or memory")
+	check(c.DB.Host != "", "db.host is required")
+	check(validPort(c.DB.Port), "db.port must be between 1 and 65535")
+	check(c.DB.Database != "", "db.database is required")
+	check(sslModes[c.DB.SSLMode], "db.ssl_mode %q is not one of disable, allow, prefer, require, verify-ca, verify-full", c.DB.SSLMode)
+	check(c.DB.MaxOpenConns > 0, "db.max_open_conns must be positive")
+	check(c.DB.MaxIdleConns >= 0 && c.DB.MaxIdleConns <= c.DB.MaxOpenConns, "db.max_idle_conns must be between 0 and db.max_open_conns")
+	check(c.DB.ConnMaxLifetime > 0, "db.conn_max_lifetime must be positive")
+	check(c.DB.ConnMaxIdleTime > 0, "db.conn_max_idle_time must be positive")
+	check(queryExecModes[c.DB.QueryExecMode], "db.query_exec_mode %q is not one of cache_statement, cache_describe, describe_exec, exec, simple_protocol", c.DB.QueryExecMode)
+	check(c.DB.StatementCacheCapacity >= 0, "db.statement_cache_capacity must not be negative")
+	check(c.DB.RetryMaxAttempts >= 1, "db.retry_max_attempts must be at least 1")
+	check(c.DB.RetryInitialBackoff > 0, "db.retry_initial_backoff must be positive")
+	check(c.DB.RetryMaxBackoff >= c.DB.RetryInitialBackoff, "db.retry_max_backoff must not be less than db.retry_initial_backoff")
+	check(c.DB.WarmupTimeout >= 0, "db.warmup_timeout must not be negative")
+	check(c.DB.PoolMonitorInterval > 0, "db.pool_monitor_interval must be positive")
+	check(c.DB.PoolSaturationThreshold >= 0 && c.DB.PoolSaturationThreshold <= 1, "db.pool_saturation_threshold must be between 0 and 1")
+	check(c.DB.PoolSaturationDuration >= 0, "db.pool_saturation_duration must not be negative")
+
+	check(c.Tracing.SamplingRate >= 0 && c.Tracing.SamplingRate <= 1, "tracing.sampling_rate must be between 0 and 1")
+	check(c.Tracing.Sampler == "ratio" || c.Tracing.Sampler == "parent_ratio", "tracing.sampler must be ratio or parent_ratio")
+	check(c.Tracing.KeepSlowerThan >= 0, "tracing.keep_slower_than must not be negative")
+	if c.Tracing.Enabled {
+		endpoint, err := url.Parse(c.Tracing.JaegerEndpoint)
+		if err != nil || endpoint.Host == "" {
+			errs = append(errs, fmt.Errorf("tracing.jaeger_endpoint %q is not a valid URL", c.Tracing.JaegerEndpoint))
+		} else if isLocalHost(endpoint.Hostname()) && endpoint.Port() == strconv.Itoa(c.Server.Port) {
+			errs = append(errs, fmt.Errorf("tracing.jaeger_endpoint port collides with server.port %d", c.Server.Port))
+		}
+	}
+
+	if c.Metrics.Enabled {
+		check(validPort(c.Metrics.Port), "metrics.port must be between 1 and 65535")
+		check(c.Metrics.Port != c.Server.Port, "metrics.port collides with server.port %d", c.Server.Port)
 	}
-	if len(c.Kafka.Brokers) == 0 {
-		return fmt.Errorf("kafka.brokers is required")
+
+	if c.Kafka.Enabled {
+		check(len(c.Kafka.Brokers) > 0, "kafka.brokers is required while kafka.enabled is true; set kafka.enabled to false (KAFKA_ENABLED=false) to run without Kafka")
+		for _, broker := range c.Kafka.Brokers {
+			host, port, err := net.SplitHostPort(broker)
+			p, perr := strconv.Atoi(port)
+			check(err == nil && host != "" && perr == nil && validPort(p), "kafka.brokers: %q is not a host:port address", broker)
+		}
+		_, verr := sarama.ParseKafkaVersion(c.Kafka.Version)
+		check(verr == nil, "kafka.version: %q is not a Kafka version", c.Kafka.Version)
+		check(c.Kafka.Topics.TaskEvents != "", "kafka.topics.task_events is required")
+		check(c.Kafka.Topics.DeadLetter != "", "kafka.topics.dead_letter is required")
+		check(c.Kafka.Topics.DeadLetter != c.Kafka.Topics.TaskEvents, "kafka.topics.dead_letter must differ from kafka.topics.task_events")
+		check(c.Kafka.Topics.Partitions > 0, "kafka.topics.partitions must be positive")
+		check(c.Kafka.Topics.ReplicationFactor > 0, "kafka.topics.replication_factor must be positive")
+		check(c.Kafka.Producer.RetryMax >= 0, "kafka.producer.retry_max must not be negative")
+		check(c.Kafka.Producer.RetryBackoff >= 0, "kafka.producer.retry_backoff must not be negative")
+		check(c.Kafka.Producer.Timeout > 0, "kafka.producer.timeout must be positive")
+		check(c.Kafka.Producer.Breaker.FailureThreshold >= 0, "kafka.producer.breaker.failure_threshold must not be negative")
+		check(c.Kafka.Producer.Breaker.OpenTimeout > 0, "kafka.producer.breaker.open_timeout must be positive")
+		check(c.Kafka.Producer.KeyStrategy == "task_id" || c.Kafka.Producer.KeyStrategy == "assigned_to" || c.Kafka.Producer.KeyStrategy == "none",
+			"kafka.producer.key_strategy must be task_id, assigned_to or none")
+		check(c.Kafka.Consumer.Workers > 0, "kafka.consumer.workers must be positive")
+		check(c.Kafka.Consumer.SessionTimeout > 0, "kafka.consumer.session_timeout must be positive")
+		check(c.Kafka.Consumer.RebalanceTimeout > 0, "kafka.consumer.rebalance_timeout must be positive")
+		check(c.Kafka.Consumer.OffsetInitial == "oldest" || c.Kafka.Consumer.OffsetInitial == "newest", "kafka.consumer.offset_initial must be oldest or newest")
+		check(c.Kafka.Consumer.ProcessedEventsTTL > 0, "kafka.consumer.processed_events_ttl must be positive")
+		check(c.Kafka.Consumer.ProcessedEventsCleanupInterval > 0, "kafka.consumer.processed_events_cleanup_interval must be positive")
+		check(c.Kafka.DLQ.ReplayMax > 0, "kafka.dlq.replay_max must be positive")
+		retryTopics := map[string]bool{c.Kafka.Topics.TaskEvents: true, c.Kafka.Topics.DeadLetter: true}
+		for i, tier := range c.Kafka.Retry.Tiers {
+			check(tier.Topic != "", "kafka.retry.tiers[%d].topic is required", i)
+			check(tier.Topic == "" || !retryTopics[tier.Topic], "kafka.retry.tiers[%d].topic %q is already in use", i, tier.Topic)
+			check(tier.Delay > 0, "kafka.retry.tiers[%d].delay must be positive", i)
+			retryTopics[tier.Topic] = true
+		}
+		switch c.Kafka.SASL.Mechanism {
+		case "":
+		case "PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512":
+			check(c.Kafka.SASL.Username != "" && c.Kafka.SASL.Password != "", "kafka.sasl.username and kafka.sasl.password are required with kafka.sasl.mechanism %s", c.Kafka.SASL.Mechanism)
+		default:
+			check(false, "kafka.sasl.mechanism must be PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512")
+		}
+		check((c.Kafka.TLS.CertFile == "") == (c.Kafka.TLS.KeyFile == ""), "kafka.tls.cert_file and kafka.tls.key_file must be set together")
+		check(c.Kafka.TLS.Enabled || (c.Kafka.TLS.CAFile == "" && c.Kafka.TLS.CertFile == ""), "kafka.tls.ca_file and kafka.tls.cert_file require kafka.tls.enabled")
+		// Retries and the dead letter queue publish through the producer
+		check(!c.Kafka.ConsumerEnabled() || c.Kafka.ProducerEnabled(), "kafka.consumer.enabled requires kafka.producer.enabled")
+	}
+
+	check(c.Tasks.StatsCacheTTL >= 0, "tasks.stats_cache_ttl must not be negative")
+	check(c.Tasks.RecurrenceInterval > 0, "tasks.recurrence_interval must be positive")
+	check(c.Tasks.DueSoonInterval > 0, "tasks.due_soon_interval must be positive")
+	check(c.Tasks.DueSoonWindow > 0, "tasks.due_soon_window must be positive")
+	check(c.Tasks.MetricsReconcileInterval > 0, "tasks.metrics_reconcile_interval must be positive")
+	check(c.Tasks.IDFormat == "int64" || c.Tasks.IDFormat == "uuid", "tasks.id_format must be int64 or uuid")
+	check(c.Tasks.BulkMaxIDs > 0, "tasks.bulk_max_ids must be positive")
+	check(c.Tasks.ListDefaultLimit > 0, "tasks.list_default_limit must be positive")
+	check(c.Tasks.ListMaxLimit >= c.Tasks.ListDefaultLimit, "tasks.list_max_limit must not be less than tasks.list_default_limit")
+	check(domain.Priority(c.Tasks.DefaultPriority).IsValid(), "tasks.default_priority must be low, medium or high")
+	initialStatus := domain.TaskStatus(c.Tasks.InitialStatus)
+	check(c.Tasks.MaxDescriptionLength > 0, "tasks.max_description_length must be positive")
+	check(c.Tasks.Sanitize == "none" || c.Tasks.Sanitize == "escape" || c.Tasks.Sanitize == "strip", "tasks.sanitize must be none, escape or strip")
+	check(initialStatus == domain.TaskStatusPending || initialStatus == domain.TaskStatusInProgress, "tasks.initial_status must be pending or in_progress")
+
+	check(c.Events.BufferSize > 0, "events.buffer_size must be positive")
+	check(c.Events.HeartbeatInterval > 0, "events.heartbeat_interval must be positive")
+	check(c.Events.BusMode == "sync" || c.Events.BusMode == "async", "events.bus_mode must be sync or async")
+	check(c.Events.BusTimeout > 0, "events.bus_timeout must be positive")
+	check(c.Events.BusBufferSize > 0, "events.bus_buffer_size must be positive")
+	switch c.Events.PublishPolicy {
+	case "best_effort", "strict":
+	case "outbox":
+		check(c.Kafka.ProducerEnabled(), "events.publish_policy outbox needs the kafka producer")
+		check(c.Events.OutboxInterval > 0, "events.outbox_interval must be positive")
+		check(c.Events.OutboxBatchSize > 0, "events.outbox_batch_size must be positive")
+	default:
+		check(false, "events.publish_policy must be best_effort, strict or outbox")
 	}
+	check(c.Webhooks.Workers > 0, "webhooks.workers must be positive")
+	check(c.Webhooks.QueueSize > 0, "webhooks.queue_size must be positive")
+	check(c.Webhooks.Timeout > 0, "webhooks.timeout must be positive")
+	check(c.Webhooks.MaxAttempts >= 1, "webhooks.max_attempts must be at least 1")
+	check(c.Webhooks.InitialBackoff > 0, "webhooks.initial_backoff must be positive")
+	check(c.Webhooks.MaxBackoff >= c.Webhooks.InitialBackoff, "webhooks.max_backoff must not be less than webhooks.initial_backoff")
+
+	check(c.HTTPClient.Timeout > 0, "http_client.timeout must be positive")
+	check(c.HTTPClient.ConnectTimeout > 0, "http_client.connect_timeout must be positive")
+	check(c.HTTPClient.ResponseHeaderTimeout > 0, "http_client.response_header_timeout must be positive")
+	check(c.HTTPClient.MaxIdleConns >= 0, "http_client.max_idle_conns must not be negative")
+	check(c.HTTPClient.MaxIdleConnsPerHost >= 0, "http_client.max_idle_conns_per_host must not be negative")
+	check(c.HTTPClient.IdleConnTimeout >= 0, "http_client.idle_conn_timeout must not be negative")
+
+	check(len(c.Auth.JWTSecret) >= auth.MinSecretLength, "auth.jwt_secret must be at least %d bytes (AUTH_JWT_SECRET)", auth.MinSecretLength)
+
 	if c.Tracing.Enabled && c.Tracing.ServiceName == "" {
 		c.Tracing.ServiceName = c.App.Name
 	}
-	return nil
+
+	return errors.Join(errs...)
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+func isLocalHost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1" || host == "0.0.0.0"
 }
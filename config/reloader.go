@@ -0,0 +1,145 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/seldomhappy/vibe_architecture/logger"
+)
+
+// LoadFunc reads the configuration from its source
+type LoadFunc func() (*Config, error)
+
+// ApplyFunc pushes hot-swappable settings from a reloaded config into a running component
+type ApplyFunc func(cfg *Config)
+
+// Reloader re-reads the configuration on SIGHUP and applies the settings that
+// are safe to change at runtime: logger.level and tracing.sampling_rate.
+// Changes to any other setting are logged as requiring a restart and ignored.
+type Reloader struct {
+	load     LoadFunc
+	current  atomic.Pointer[Config]
+	appliers []ApplyFunc
+	logger   logger.ILogger
+
+	mu      sync.Mutex
+	signals chan os.Signal
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// NewReloader creates a reloader starting from the given, already validated config
+func NewReloader(cfg *Config, load LoadFunc, log logger.ILogger) *Reloader {
+	r := &Reloader{
+		load:    load,
+		logger:  log,
+		signals: make(chan os.Signal, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	r.current.Store(cfg)
+	return r
+}
+
+// OnReload registers a function called with the new config after each successful reload.
+// It must be called before Start.
+func (r *Reloader) OnReload(fn ApplyFunc) {
+	r.appliers = append(r.appliers, fn)
+}
+
+// Current returns the active config. The returned value must not be modified.
+func (r *Reloader) Current() *Config {
+	return r.current.Load()
+}
+
+// Start begins listening for SIGHUP
+func (r *Reloader) Start(ctx context.Context) error {
+	signal.Notify(r.signals, syscall.SIGHUP)
+
+	go func() {
+		defer close(r.stopped)
+		for {
+			select {
+			case <-r.signals:
+				r.Reload()
+			case <-r.done:
+				return
+			}
+		}
+	}()
+
+	r.logger.Info("Config reloader started (send SIGHUP to reload)")
+	return nil
+}
+
+// Shutdown stops listening for SIGHUP
+func (r *Reloader) Shutdown(ctx context.Context) error {
+	signal.Stop(r.signals)
+	close(r.done)
+
+	select {
+	case <-r.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Reload re-reads and applies the config. On any error the current config is kept.
+func (r *Reloader) Reload() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.logger.Info("Reloading configuration...")
+
+	loaded, err := r.load()
+	if err != nil {
+		r.logger.Error("Config reload failed: %v", err)
+		return
+	}
+	if err := loaded.Validate(); err != nil {
+		r.logger.Error("Config reload rejected: %v", err)
+		return
+	}
+
+	old := r.current.Load()
+
+	next := *old
+	next.Logger.Level = loaded.Logger.Level
+	next.Tracing.SamplingRate = loaded.Tracing.SamplingRate
+
+	// Anything else that differs can't be applied to the running process
+	ignored := *loaded
+	ignored.Logger.Level = old.Logger.Level
+	ignored.Tracing.SamplingRate = old.Tracing.SamplingRate
+	for _, section := range changedSections(old, &ignored) {
+		r.logger.Warn("Config section %q changed and requires restart; ignoring", section)
+	}
+
+	for _, apply := range r.appliers {
+		apply(&next)
+	}
+	r.current.Store(&next)
+
+	r.logger.Info("Configuration reloaded (logger.level=%s, tracing.sampling_rate=%v)",
+		next.Logger.Level, next.Tracing.SamplingRate)
+}
+
+// changedSections returns the yaml names of the top-level sections that differ
+func changedSections(a, b *Config) []string {
+	var changed []string
+
+	va, vb := reflect.ValueOf(*a), reflect.ValueOf(*b)
+	for i := 0; i < va.NumField(); i++ {
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			changed = append(changed, va.Type().Field(i).Tag.Get("yaml"))
+		}
+	}
+
+	return changed
+}
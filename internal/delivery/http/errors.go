@@ -0,0 +1,93 @@
+package http
+
+import "github.com/seldomhappy/vibe_architecture/internal/domain"
+
+// Error codes returned in the "code" field of error responses.
+// These are part of the public API contract and must stay stable.
+const (
+	CodeTaskNotFound            = "TASK_NOT_FOUND"
+	CodeDependencyNotFound      = "DEPENDENCY_NOT_FOUND"
+	CodeDependencyCycle         = "DEPENDENCY_CYCLE"
+	CodeDependenciesIncomplete  = "DEPENDENCIES_INCOMPLETE"
+	CodeSubtasksIncomplete      = "SUBTASKS_INCOMPLETE"
+	CodeTaskHasSubtasks         = "TASK_HAS_SUBTASKS"
+	CodeTaskTreeBlocked         = "TASK_TREE_BLOCKED"
+	CodeTaskAlreadyCompleted    = "TASK_ALREADY_COMPLETED"
+	CodeTaskAlreadyCancelled    = "TASK_ALREADY_CANCELLED"
+	CodeTaskCancelled           = "TASK_CANCELLED"
+	CodeTaskNotAssignable       = "TASK_NOT_ASSIGNABLE"
+	CodeInvalidTransition       = "INVALID_TRANSITION"
+	CodeCommentNotFound         = "COMMENT_NOT_FOUND"
+	CodeCommentEmpty            = "COMMENT_EMPTY"
+	CodeCommentTooLong          = "COMMENT_TOO_LONG"
+	CodeTaskNameEmpty           = "TASK_NAME_EMPTY"
+	CodeTaskNameTooLong         = "TASK_NAME_TOO_LONG"
+	CodeTaskNameInvalid         = "TASK_NAME_INVALID"
+	CodeTaskDescriptionTooLong  = "TASK_DESCRIPTION_TOO_LONG"
+	CodeWebhookNotFound         = "WEBHOOK_NOT_FOUND"
+	CodeWebhookURLInvalid       = "WEBHOOK_URL_INVALID"
+	CodeWebhookEventTypeInvalid = "WEBHOOK_EVENT_TYPE_INVALID"
+	CodeInvalidInput            = "INVALID_INPUT"
+	CodeBatchTooLarge           = "BATCH_TOO_LARGE"
+	CodeConflict                = "CONFLICT"
+	CodeReferenceNotFound       = "REFERENCE_NOT_FOUND"
+	CodeInvalidRecurrenceRule   = "INVALID_RECURRENCE_RULE"
+	CodeInvalidRequestBody      = "INVALID_REQUEST_BODY"
+	CodeInvalidTaskID           = "INVALID_TASK_ID"
+	CodeValidationFailed        = "VALIDATION_FAILED"
+	CodeUnauthorized            = "UNAUTHORIZED"
+	CodeForbidden               = "FORBIDDEN"
+	CodeInvalidTenant           = "INVALID_TENANT"
+	CodeMethodNotAllowed        = "METHOD_NOT_ALLOWED"
+	CodePreconditionFailed      = "PRECONDITION_FAILED"
+	CodeRequestTimeout          = "REQUEST_TIMEOUT"
+	CodeRequestCancelled        = "REQUEST_CANCELLED"
+	CodeFeatureDisabled         = "FEATURE_DISABLED"
+	CodeEventNotPublished       = "EVENT_NOT_PUBLISHED"
+	CodeOverloaded              = "OVERLOADED"
+	CodeInternal                = "INTERNAL_ERROR"
+)
+
+// statusClientClosedRequest is the non-standard status (popularised by nginx)
+// recorded when the client goes away before the response is ready
+const statusClientClosedRequest = 499
+
+// problemContentType is the media type used for error responses (RFC 7807)
+const problemContentType = "application/problem+json"
+
+// ErrorResponse represents an error response
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody holds the machine-readable details of an error
+type ErrorBody struct {
+	Code      string           `json:"code"`
+	Message   string           `json:"message"`
+	RequestID string           `json:"request_id,omitempty"`
+	Fields    ValidationErrors `json:"fields,omitempty"`
+	// Blockers lists the tasks that kept a task tree from being completed
+	Blockers []domain.TaskBlocker `json:"blockers,omitempty"`
+}
+
+// Validation failure reasons reported per field
+const (
+	ReasonRequired = "required"
+	ReasonTooLong  = "too_long"
+	ReasonInvalid  = "invalid"
+)
+
+// ValidationErrors maps request field names to the reason they were rejected
+type ValidationErrors map[string]string
+
+// Add records a failure for the given field, keeping the first reason reported
+func (v ValidationErrors) Add(field, reason string) {
+	if _, exists := v[field]; !exists {
+		v[field] = reason
+	}
+}
+
+// HasErrors returns true if at least one field failed validation
+func (v ValidationErrors) HasErrors() bool {
+	return len(v) > 0
+}
@@ -0,0 +1,48 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	"github.com/seldomhappy/vibe_architecture/internal/repository"
+)
+
+// taskETag computes a strong ETag for a task from its ID and last modification time.
+// It only depends on stored values, so it is stable across serializations and
+// changes whenever the task is updated. Subtask changes don't touch the parent's
+// updated_at, so with ?include=subtask_count the count is folded in as well:
+// the representation with the count gets its own tag, which changes whenever
+// the count does.
+func taskETag(t *domain.Task) string {
+	version := fmt.Sprintf("%d:%d", t.ID, t.UpdatedAt.UnixNano())
+	if t.SubtaskCount != nil {
+		version += fmt.Sprintf(":subtask_count=%d", *t.SubtaskCount)
+	}
+	sum := sha256.Sum256([]byte(version))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// taskListETag computes a weak ETag for a task list from its version. It is weak
+// because the same list may be rendered in different pages and orders.
+func taskListETag(v repository.ListVersion) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%d", v.Count, v.LastModified.UnixNano())))
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether an If-Match / If-None-Match header value matches the ETag.
+// The header may contain "*" or a comma separated list of (possibly weak) tags.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
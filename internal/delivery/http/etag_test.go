@@ -0,0 +1,31 @@
+package http
+
+import (
+	"testing"
+	"time"
+
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+)
+
+func TestTaskETagIncludesSubtaskCount(t *testing.T) {
+	task := &domain.Task{ID: 1, UpdatedAt: time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)}
+	bare := taskETag(task)
+
+	one, two := 1, 2
+	task.SubtaskCount = &one
+	withOne := taskETag(task)
+	task.SubtaskCount = &two
+	withTwo := taskETag(task)
+
+	if withOne == bare {
+		t.Error("ETag with include=subtask_count equals the bare ETag")
+	}
+	if withTwo == withOne {
+		t.Error("ETag didn't change with the subtask count")
+	}
+
+	task.SubtaskCount = nil
+	if taskETag(task) != bare {
+		t.Error("bare ETag isn't stable")
+	}
+}
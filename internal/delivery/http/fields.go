@@ -0,0 +1,86 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+)
+
+// taskFields lists the JSON fields of a task in the order they are rendered.
+// It is read from domain.Task so ?fields= accepts exactly what tasks contain.
+var taskFields = jsonFieldNames(reflect.TypeOf(domain.Task{}))
+
+func jsonFieldNames(t reflect.Type) []string {
+	names := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// parseFields parses ?fields=id,name,status, the task fields a list should be
+// limited to. It returns nil when the parameter is absent.
+func parseFields(r *http.Request, errs ValidationErrors) map[string]bool {
+	raw := r.URL.Query().Get("fields")
+	if raw == "" {
+		return nil
+	}
+
+	fields := make(map[string]bool)
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if !isTaskField(name) {
+			errs.Add("fields", ReasonInvalid)
+			return nil
+		}
+		fields[name] = true
+	}
+	return fields
+}
+
+func isTaskField(name string) bool {
+	for _, field := range taskFields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// projectTask renders a task with only the given fields. Fields a task omits
+// when empty stay omitted.
+func projectTask(task *domain.Task, fields map[string]bool) (json.RawMessage, error) {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for _, name := range taskFields {
+		value, ok := values[name]
+		if !ok || !fields[name] {
+			continue
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
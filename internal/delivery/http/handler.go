@@ -1,45 +1,134 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"reflect"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/google/uuid"
 	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/buildinfo"
+	pkgcontext "github.com/seldomhappy/vibe_architecture/internal/pkg/context"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/health"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/metrics"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/pubsub"
 	"github.com/seldomhappy/vibe_architecture/internal/usecase/task"
+	"github.com/seldomhappy/vibe_architecture/internal/usecase/webhook"
 	"github.com/seldomhappy/vibe_architecture/logger"
 )
 
 // TaskHandler handles HTTP requests for tasks
 type TaskHandler struct {
-	useCase task.UseCase
-	logger  logger.ILogger
+	useCase      task.UseCase
+	webhooks     webhook.UseCase
+	broker       *pubsub.Broker
+	dlq          DeadLetterReplayer
+	readiness    *health.Checker
+	heartbeat    time.Duration
+	idFormat     string
+	dlqReplayMax int
+	taskLimits   domain.Limits
+	listMaxAge   time.Duration
+	build        buildinfo.Info
+	render       renderer
+	metrics      *metrics.Metrics
+	logger       logger.ILogger
 }
 
 // NewTaskHandler creates a new task handler
-func NewTaskHandler(uc task.UseCase, log logger.ILogger) *TaskHandler {
+func NewTaskHandler(cfg Config, uc task.UseCase, webhooks webhook.UseCase, broker *pubsub.Broker, dlq DeadLetterReplayer, readiness *health.Checker, m *metrics.Metrics, log logger.ILogger) *TaskHandler {
 	return &TaskHandler{
-		useCase: uc,
-		logger:  log,
+		useCase:      uc,
+		webhooks:     webhooks,
+		broker:       broker,
+		dlq:          dlq,
+		readiness:    readiness,
+		heartbeat:    cfg.EventsHeartbeat,
+		idFormat:     cfg.TaskIDFormat,
+		dlqReplayMax: cfg.DLQReplayMax,
+		taskLimits:   cfg.TaskLimits,
+		listMaxAge:   cfg.ListCacheMaxAge,
+		build:        cfg.Build,
+		render:       newRenderer(cfg.ResponseFormat, log),
+		metrics:      m,
+		logger:       log,
 	}
 }
 
 // CreateTaskRequest represents a request to create a task
 type CreateTaskRequest struct {
-	Name        string          `json:"name"`
-	Description string          `json:"description"`
-	Priority    domain.Priority `json:"priority"`
-	CreatedBy   int64           `json:"created_by"`
+	Name           string          `json:"name"`
+	Description    string          `json:"description"`
+	Priority       domain.Priority `json:"priority"`
+	DueDate        *time.Time      `json:"due_date,omitempty"`
+	RecurrenceRule *string         `json:"recurrence_rule,omitempty"`
+	CreatedBy      int64           `json:"created_by"`
+}
+
+// input converts the request to the use case input
+func (req CreateTaskRequest) input() task.CreateTaskInput {
+	return task.CreateTaskInput{
+		Name:           req.Name,
+		Description:    req.Description,
+		Priority:       req.Priority,
+		DueDate:        req.DueDate,
+		RecurrenceRule: req.RecurrenceRule,
+		CreatedBy:      req.CreatedBy,
+	}
+}
+
+// ValidateTaskResponse reports that a task would be created
+type ValidateTaskResponse struct {
+	Valid bool `json:"valid"`
 }
 
 // UpdateTaskRequest represents a request to update a task
+// Description and AssignedTo tell an omitted field (left unchanged) apart from
+// null (cleared).
 type UpdateTaskRequest struct {
-	Name        *string             `json:"name,omitempty"`
-	Description *string             `json:"description,omitempty"`
-	Status      *domain.TaskStatus  `json:"status,omitempty"`
-	Priority    *domain.Priority    `json:"priority,omitempty"`
+	Name           *string               `json:"name,omitempty"`
+	Description    task.Optional[string] `json:"description"`
+	Status         *domain.TaskStatus    `json:"status,omitempty"`
+	Priority       *domain.Priority      `json:"priority,omitempty"`
+	AssignedTo     task.Optional[int64]  `json:"assigned_to"`
+	DueDate        *time.Time            `json:"due_date,omitempty"`
+	RecurrenceRule *string               `json:"recurrence_rule,omitempty"`
+}
+
+// AddDependencyRequest represents a request to add a task dependency
+type AddDependencyRequest struct {
+	DependsOnID int64 `json:"depends_on_id"`
+}
+
+// BulkStatusRequest represents a request to move many tasks to one status
+type BulkStatusRequest struct {
+	IDs    []int64           `json:"ids"`
+	Status domain.TaskStatus `json:"status"`
+}
+
+// BulkStatusResponse reports the outcome for each requested task
+type BulkStatusResponse struct {
+	Results []task.BulkStatusResult `json:"results"`
+}
+
+// CompleteTaskTreeResponse lists the tasks completed along with their parent
+type CompleteTaskTreeResponse struct {
+	Message   string  `json:"message"`
+	Completed []int64 `json:"completed"`
+}
+
+// AddCommentRequest represents a request to comment on a task
+type AddCommentRequest struct {
+	Body string `json:"body"`
 }
 
 // AssignTaskRequest represents a request to assign a task
@@ -47,51 +136,86 @@ type AssignTaskRequest struct {
 	UserID int64 `json:"user_id"`
 }
 
-// ErrorResponse represents an error response
-type ErrorResponse struct {
-	Error string `json:"error"`
-}
-
 // CreateTask handles POST /tasks
 func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 	var req CreateTaskRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidRequestBody, decodeErrorMessage(err))
 		return
 	}
 
-	if err := h.validateCreateTaskRequest(req); err != nil {
-		h.respondError(w, http.StatusBadRequest, err.Error())
+	if errs := h.validateCreateTaskRequest(req); errs.HasErrors() {
+		h.rejectTaskRequest(w, r, errs)
 		return
 	}
 
-	input := task.CreateTaskInput{
-		Name:        req.Name,
-		Description: req.Description,
-		Priority:    req.Priority,
-		CreatedBy:   req.CreatedBy,
-	}
-
-	createdTask, err := h.useCase.CreateTask(r.Context(), input)
+	createdTask, err := h.useCase.CreateTask(r.Context(), req.input())
 	if err != nil {
-		h.handleUseCaseError(w, err)
+		h.handleUseCaseError(w, r, err)
 		return
 	}
 
 	h.respondJSON(w, http.StatusCreated, createdTask)
 }
 
+// ValidateTask handles POST /tasks/validate. It runs the validation of
+// CreateTask without creating the task, so forms can check input before saving.
+func (h *TaskHandler) ValidateTask(w http.ResponseWriter, r *http.Request) {
+	var req CreateTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidRequestBody, decodeErrorMessage(err))
+		return
+	}
+
+	errs := h.validateCreateTaskRequest(req)
+	if !errs.HasErrors() {
+		if err := h.useCase.ValidateTask(r.Context(), req.input()); err != nil {
+			field, reason, ok := taskValidationField(err)
+			if !ok {
+				h.handleUseCaseError(w, r, err)
+				return
+			}
+			errs.Add(field, reason)
+		}
+	}
+	if errs.HasErrors() {
+		h.writeError(w, r, http.StatusUnprocessableEntity, ErrorBody{
+			Code:    CodeValidationFailed,
+			Message: "task validation failed",
+			Fields:  errs,
+		})
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, ValidateTaskResponse{Valid: true})
+}
+
 // GetTask handles GET /tasks/{id}
 func (h *TaskHandler) GetTask(w http.ResponseWriter, r *http.Request) {
-	id, err := h.extractIDFromPath(r.URL.Path)
-	if err != nil {
-		h.respondError(w, http.StatusBadRequest, "invalid task id")
+	id, ok := h.taskIDFromPath(w, r, "tasks")
+	if !ok {
 		return
 	}
 
 	task, err := h.useCase.GetTask(r.Context(), id)
 	if err != nil {
-		h.handleUseCaseError(w, err)
+		h.handleUseCaseError(w, r, err)
+		return
+	}
+
+	if includes(r, "subtask_count") {
+		count, err := h.useCase.CountSubtasks(r.Context(), id)
+		if err != nil {
+			h.handleUseCaseError(w, r, err)
+			return
+		}
+		task.SubtaskCount = &count
+	}
+
+	etag := taskETag(task)
+	w.Header().Set("ETag", etag)
+	if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, etag) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
@@ -100,91 +224,206 @@ func (h *TaskHandler) GetTask(w http.ResponseWriter, r *http.Request) {
 
 // ListTasks handles GET /tasks
 func (h *TaskHandler) ListTasks(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query()
-	
-	filter := task.ListTasksFilter{
-		Limit:  50,
-		Offset: 0,
+	filter, errs := h.parseListFilter(r)
+	fields := parseFields(r, errs)
+	if errs.HasErrors() {
+		h.respondValidationError(w, r, errs)
+		return
 	}
 
-	if status := query.Get("status"); status != "" {
-		s := domain.TaskStatus(status)
-		filter.Status = &s
+	if !h.listModified(w, r, filter) {
+		return
 	}
 
-	if priority := query.Get("priority"); priority != "" {
-		p := domain.Priority(priority)
-		filter.Priority = &p
+	h.streamTasks(w, r, filter, fields)
+}
+
+// listModified sets the caching headers of a task list and answers conditional
+// requests. It returns false once it has written the response: 304 when the
+// client's copy is current, or an error.
+func (h *TaskHandler) listModified(w http.ResponseWriter, r *http.Request, filter task.ListTasksFilter) bool {
+	version, err := h.useCase.GetListVersion(r.Context(), filter)
+	if err != nil {
+		h.handleUseCaseError(w, r, err)
+		return false
 	}
 
-	if assignedTo := query.Get("assigned_to"); assignedTo != "" {
-		id, err := strconv.ParseInt(assignedTo, 10, 64)
-		if err == nil {
-			filter.AssignedTo = &id
-		}
+	etag := taskListETag(version)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(h.listMaxAge.Seconds())))
+	lastModified := version.LastModified.UTC().Truncate(time.Second)
+	if !version.LastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
 	}
 
-	if limit := query.Get("limit"); limit != "" {
-		if l, err := strconv.Atoi(limit); err == nil && l > 0 && l <= 100 {
-			filter.Limit = l
-		}
+	// If-None-Match takes precedence; it also catches tasks that left the list,
+	// which If-Modified-Since can't see
+	notModified := false
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		notModified = etagMatches(match, etag)
+	} else if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !version.LastModified.IsZero() {
+		notModified = !lastModified.After(since)
 	}
+	if notModified {
+		w.WriteHeader(http.StatusNotModified)
+		return false
+	}
+	return true
+}
 
-	if offset := query.Get("offset"); offset != "" {
-		if o, err := strconv.Atoi(offset); err == nil && o >= 0 {
-			filter.Offset = o
-		}
+// ListMyTasks handles GET /me/tasks, listing tasks assigned to the authenticated user
+func (h *TaskHandler) ListMyTasks(w http.ResponseWriter, r *http.Request) {
+	userID := pkgcontext.GetUserID(r.Context())
+	if userID <= 0 {
+		h.respondError(w, r, http.StatusUnauthorized, CodeUnauthorized, domain.ErrUnauthorized.Error())
+		return
+	}
+
+	// The user always comes from the context; ?assigned_to= is ignored
+	filter, errs := h.parseListFilter(r)
+	fields := parseFields(r, errs)
+	if errs.HasErrors() {
+		h.respondValidationError(w, r, errs)
+		return
+	}
+	filter.AssignedTo = &userID
+
+	if !h.listModified(w, r, filter) {
+		return
+	}
+
+	h.streamTasks(w, r, filter, fields)
+}
+
+// ListSubtasks handles GET /tasks/{id}/subtasks
+func (h *TaskHandler) ListSubtasks(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.taskIDFromPath(w, r, "tasks")
+	if !ok {
+		return
+	}
+
+	if err := h.useCase.CheckTaskExists(r.Context(), id); err != nil {
+		h.handleUseCaseError(w, r, err)
+		return
+	}
+
+	filter, errs := h.parseListFilter(r)
+	fields := parseFields(r, errs)
+	if errs.HasErrors() {
+		h.respondValidationError(w, r, errs)
+		return
+	}
+	filter.ParentID = &id
+
+	if !h.listModified(w, r, filter) {
+		return
+	}
+
+	h.streamTasks(w, r, filter, fields)
+}
+
+// CreateSubtask handles POST /tasks/{id}/subtasks
+func (h *TaskHandler) CreateSubtask(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.taskIDFromPath(w, r, "tasks")
+	if !ok {
+		return
+	}
+
+	var req CreateTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidRequestBody, decodeErrorMessage(err))
+		return
+	}
+
+	if errs := h.validateCreateTaskRequest(req); errs.HasErrors() {
+		h.rejectTaskRequest(w, r, errs)
+		return
+	}
+
+	input := task.CreateTaskInput{
+		Name:           req.Name,
+		Description:    req.Description,
+		Priority:       req.Priority,
+		DueDate:        req.DueDate,
+		RecurrenceRule: req.RecurrenceRule,
+		ParentID:       &id,
+		CreatedBy:      req.CreatedBy,
 	}
 
-	tasks, err := h.useCase.ListTasks(r.Context(), filter)
+	createdTask, err := h.useCase.CreateTask(r.Context(), input)
 	if err != nil {
-		h.handleUseCaseError(w, err)
+		h.handleUseCaseError(w, r, err)
 		return
 	}
 
-	h.respondJSON(w, http.StatusOK, tasks)
+	h.respondJSON(w, http.StatusCreated, createdTask)
 }
 
 // UpdateTask handles PUT /tasks/{id}
 func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
-	id, err := h.extractIDFromPath(r.URL.Path)
-	if err != nil {
-		h.respondError(w, http.StatusBadRequest, "invalid task id")
+	id, ok := h.taskIDFromPath(w, r, "tasks")
+	if !ok {
 		return
 	}
 
 	var req UpdateTaskRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidRequestBody, decodeErrorMessage(err))
+		return
+	}
+
+	if errs := h.validateUpdateTaskRequest(req); errs.HasErrors() {
+		h.rejectTaskRequest(w, r, errs)
 		return
 	}
 
 	input := task.UpdateTaskInput{
-		Name:        req.Name,
-		Description: req.Description,
-		Status:      req.Status,
-		Priority:    req.Priority,
+		Name:           req.Name,
+		Description:    req.Description,
+		Status:         req.Status,
+		Priority:       req.Priority,
+		AssignedTo:     req.AssignedTo,
+		DueDate:        req.DueDate,
+		RecurrenceRule: req.RecurrenceRule,
+	}
+
+	// Enforce optimistic concurrency when the client sends the ETag it last saw.
+	// The version it matched is checked again by the UPDATE itself, so a write
+	// landing between this read and the update still fails with 412.
+	if match := r.Header.Get("If-Match"); match != "" {
+		current, err := h.useCase.GetTask(r.Context(), id)
+		if err != nil {
+			h.handleUseCaseError(w, r, err)
+			return
+		}
+		if !etagMatches(match, taskETag(current)) {
+			h.respondError(w, r, http.StatusPreconditionFailed, CodePreconditionFailed, domain.ErrTaskModified.Error())
+			return
+		}
+		input.IfUpdatedAt = &current.UpdatedAt
 	}
 
 	updatedTask, err := h.useCase.UpdateTask(r.Context(), id, input)
 	if err != nil {
-		h.handleUseCaseError(w, err)
+		h.handleUseCaseError(w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", taskETag(updatedTask))
 	h.respondJSON(w, http.StatusOK, updatedTask)
 }
 
 // DeleteTask handles DELETE /tasks/{id}
 func (h *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
-	id, err := h.extractIDFromPath(r.URL.Path)
-	if err != nil {
-		h.respondError(w, http.StatusBadRequest, "invalid task id")
+	id, ok := h.taskIDFromPath(w, r, "tasks")
+	if !ok {
 		return
 	}
 
-	if err := h.useCase.DeleteTask(r.Context(), id); err != nil {
-		h.handleUseCaseError(w, err)
+	cascade := r.URL.Query().Get("cascade") == "true"
+
+	if err := h.useCase.DeleteTask(r.Context(), id, cascade); err != nil {
+		h.handleUseCaseError(w, r, err)
 		return
 	}
 
@@ -193,25 +432,24 @@ func (h *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 
 // AssignTask handles POST /tasks/{id}/assign
 func (h *TaskHandler) AssignTask(w http.ResponseWriter, r *http.Request) {
-	id, err := h.extractIDFromPath(r.URL.Path)
-	if err != nil {
-		h.respondError(w, http.StatusBadRequest, "invalid task id")
+	id, ok := h.taskIDFromPath(w, r, "tasks")
+	if !ok {
 		return
 	}
 
 	var req AssignTaskRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidRequestBody, decodeErrorMessage(err))
 		return
 	}
 
 	if req.UserID <= 0 {
-		h.respondError(w, http.StatusBadRequest, "user_id is required")
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidInput, "user_id is required")
 		return
 	}
 
 	if err := h.useCase.AssignTask(r.Context(), id, req.UserID); err != nil {
-		h.handleUseCaseError(w, err)
+		h.handleUseCaseError(w, r, err)
 		return
 	}
 
@@ -220,83 +458,745 @@ func (h *TaskHandler) AssignTask(w http.ResponseWriter, r *http.Request) {
 
 // CompleteTask handles POST /tasks/{id}/complete
 func (h *TaskHandler) CompleteTask(w http.ResponseWriter, r *http.Request) {
-	id, err := h.extractIDFromPath(r.URL.Path)
-	if err != nil {
-		h.respondError(w, http.StatusBadRequest, "invalid task id")
+	id, ok := h.taskIDFromPath(w, r, "tasks")
+	if !ok {
+		return
+	}
+
+	if r.URL.Query().Get("cascade") == "true" {
+		tasks, err := h.useCase.CompleteTaskTree(r.Context(), id)
+		if err != nil {
+			h.handleUseCaseError(w, r, err)
+			return
+		}
+
+		completed := make([]int64, len(tasks))
+		for i, t := range tasks {
+			completed[i] = t.ID
+		}
+		h.respondJSON(w, http.StatusOK, CompleteTaskTreeResponse{
+			Message:   "task tree completed successfully",
+			Completed: completed,
+		})
 		return
 	}
 
 	if err := h.useCase.CompleteTask(r.Context(), id); err != nil {
-		h.handleUseCaseError(w, err)
+		h.handleUseCaseError(w, r, err)
 		return
 	}
 
 	h.respondJSON(w, http.StatusOK, map[string]string{"message": "task completed successfully"})
 }
 
-// Health handles GET /health
+// BulkStatus handles POST /tasks/bulk-status
+func (h *TaskHandler) BulkStatus(w http.ResponseWriter, r *http.Request) {
+	var req BulkStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidRequestBody, decodeErrorMessage(err))
+		return
+	}
+
+	errs := ValidationErrors{}
+	if len(req.IDs) == 0 {
+		errs.Add("ids", ReasonRequired)
+	}
+	for _, id := range req.IDs {
+		if id <= 0 {
+			errs.Add("ids", ReasonInvalid)
+		}
+	}
+	if req.Status == "" {
+		errs.Add("status", ReasonRequired)
+	} else if !req.Status.IsValid() {
+		errs.Add("status", ReasonInvalid)
+	}
+	if errs.HasErrors() {
+		h.respondValidationError(w, r, errs)
+		return
+	}
+
+	results, err := h.useCase.BulkUpdateStatus(r.Context(), req.IDs, req.Status)
+	if err != nil {
+		h.handleUseCaseError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, BulkStatusResponse{Results: results})
+}
+
+// AddDependency handles POST /tasks/{id}/dependencies
+func (h *TaskHandler) AddDependency(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.taskIDFromPath(w, r, "tasks")
+	if !ok {
+		return
+	}
+
+	var req AddDependencyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidRequestBody, decodeErrorMessage(err))
+		return
+	}
+
+	if req.DependsOnID <= 0 {
+		h.respondValidationError(w, r, ValidationErrors{"depends_on_id": ReasonRequired})
+		return
+	}
+
+	if err := h.useCase.AddDependency(r.Context(), id, req.DependsOnID); err != nil {
+		h.handleUseCaseError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, map[string]string{"message": "dependency added successfully"})
+}
+
+// RemoveDependency handles DELETE /tasks/{id}/dependencies/{depends_on_id}
+func (h *TaskHandler) RemoveDependency(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.taskIDFromPath(w, r, "tasks")
+	if !ok {
+		return
+	}
+
+	dependsOnID, ok := h.taskIDFromPath(w, r, "dependencies")
+	if !ok {
+		return
+	}
+
+	if err := h.useCase.RemoveDependency(r.Context(), id, dependsOnID); err != nil {
+		h.handleUseCaseError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListComments handles GET /tasks/{id}/comments
+func (h *TaskHandler) ListComments(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.taskIDFromPath(w, r, "tasks")
+	if !ok {
+		return
+	}
+
+	comments, err := h.useCase.ListComments(r.Context(), id)
+	if err != nil {
+		h.handleUseCaseError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, comments)
+}
+
+// AddComment handles POST /tasks/{id}/comments
+func (h *TaskHandler) AddComment(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.taskIDFromPath(w, r, "tasks")
+	if !ok {
+		return
+	}
+
+	var req AddCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidRequestBody, decodeErrorMessage(err))
+		return
+	}
+
+	if strings.TrimSpace(req.Body) == "" {
+		h.respondValidationError(w, r, ValidationErrors{"body": ReasonRequired})
+		return
+	}
+	if utf8.RuneCountInString(req.Body) > domain.MaxCommentLength {
+		h.respondValidationError(w, r, ValidationErrors{"body": ReasonTooLong})
+		return
+	}
+
+	comment, err := h.useCase.AddComment(r.Context(), id, req.Body)
+	if err != nil {
+		h.handleUseCaseError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, comment)
+}
+
+// DeleteComment handles DELETE /tasks/{id}/comments/{comment_id}
+func (h *TaskHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.taskIDFromPath(w, r, "tasks")
+	if !ok {
+		return
+	}
+
+	commentID, err := h.extractSubresourceID(r.URL.Path, "comments")
+	if err != nil {
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidInput, "invalid comment id")
+		return
+	}
+
+	if err := h.useCase.DeleteComment(r.Context(), id, commentID); err != nil {
+		h.handleUseCaseError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Stats handles GET /stats
+func (h *TaskHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.methodNotAllowed(w, r)
+		return
+	}
+
+	stats, err := h.useCase.GetStats(r.Context())
+	if err != nil {
+		h.handleUseCaseError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, stats)
+}
+
+// ReconcileMetricsResponse holds the task counts the gauges were refreshed with
+type ReconcileMetricsResponse struct {
+	ByStatus map[domain.TaskStatus]int64 `json:"by_status"`
+}
+
+// ReconcileMetrics handles POST /admin/reconcile-metrics. It counts every
+// tenant's tasks, so it is left to operators.
+func (h *TaskHandler) ReconcileMetrics(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, RoleOperator) {
+		return
+	}
+
+	counts, err := h.useCase.ReconcileMetrics(r.Context())
+	if err != nil {
+		h.handleUseCaseError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, ReconcileMetricsResponse{ByStatus: counts})
+}
+
+// defaultDLQReplayMax is how many messages a replay handles when max isn't given
+const defaultDLQReplayMax = 100
+
+// ReplayDLQ handles POST /admin/dlq/replay?max=100&dry_run=true. The dead
+// letter topic holds every tenant's messages, so it is left to operators.
+func (h *TaskHandler) ReplayDLQ(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, RoleOperator) {
+		return
+	}
+	if h.dlq == nil {
+		h.respondError(w, r, http.StatusServiceUnavailable, CodeFeatureDisabled, "kafka is disabled")
+		return
+	}
+
+	query := r.URL.Query()
+	errs := ValidationErrors{}
+
+	limit := defaultDLQReplayMax
+	if v := query.Get("max"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > h.dlqReplayMax {
+			errs.Add("max", ReasonInvalid)
+		}
+		limit = n
+	}
+
+	dryRun := false
+	if v := query.Get("dry_run"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			errs.Add("dry_run", ReasonInvalid)
+		}
+		dryRun = b
+	}
+
+	if errs.HasErrors() {
+		h.respondValidationError(w, r, errs)
+		return
+	}
+
+	requestID := pkgcontext.GetRequestID(r.Context())
+	h.logger.Info("[%s] Replaying up to %d dlq messages (dry_run=%t)", requestID, limit, dryRun)
+
+	result, err := h.dlq.Replay(r.Context(), limit, dryRun)
+	if err != nil {
+		h.logger.Error("[%s] DLQ replay failed after %d messages: %v", requestID, result.Replayed+result.Skipped, err)
+		h.handleUseCaseError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, result)
+}
+
+// Health handles GET /health. Probes rely on its shape, so it's never enveloped.
 func (h *TaskHandler) Health(w http.ResponseWriter, r *http.Request) {
-	h.respondJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	h.render.raw(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readyz handles GET /readyz. It answers 503 while an enabled dependency is down;
+// dependencies that are turned off are reported as disabled. Like Health, it's
+// never enveloped.
+func (h *TaskHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	report := h.readiness.Check(r.Context())
+	if !report.Ready() {
+		h.logger.Warn("Not ready: %+v", report.Checks)
+		h.render.raw(w, http.StatusServiceUnavailable, report)
+		return
+	}
+	h.render.raw(w, http.StatusOK, report)
+}
+
+// Version handles GET /version and GET /buildinfo. Deploy checks rely on its
+// shape, so it's never enveloped.
+func (h *TaskHandler) Version(w http.ResponseWriter, r *http.Request) {
+	h.render.raw(w, http.StatusOK, h.build)
 }
 
 // Helper methods
 
-func (h *TaskHandler) extractIDFromPath(path string) (int64, error) {
-	parts := strings.Split(strings.Trim(path, "/"), "/")
-	if len(parts) < 2 {
-		return 0, fmt.Errorf("invalid path")
+// taskIDFromPath resolves the task reference that follows segment in the URL path.
+// References are int64 IDs or, when the id format is uuid, task UUIDs. On
+// failure the error response is written and false is returned.
+func (h *TaskHandler) taskIDFromPath(w http.ResponseWriter, r *http.Request, segment string) (int64, bool) {
+	raw, err := pathSegmentAfter(r.URL.Path, segment)
+	if err != nil {
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidTaskID, "invalid task id")
+		return 0, false
+	}
+
+	if h.idFormat != IDFormatUUID {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			h.respondError(w, r, http.StatusBadRequest, CodeInvalidTaskID, "invalid task id")
+			return 0, false
+		}
+		return id, true
+	}
+
+	taskUUID, err := uuid.Parse(raw)
+	if err != nil {
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidTaskID, "invalid task id (expected a UUID)")
+		return 0, false
+	}
+
+	id, err := h.useCase.ResolveTaskUUID(r.Context(), taskUUID)
+	if err != nil {
+		h.handleUseCaseError(w, r, err)
+		return 0, false
 	}
-	
-	// Find the ID after /tasks/
+	return id, true
+}
+
+// pathSegmentAfter returns the path segment that follows segment
+func pathSegmentAfter(path, segment string) (string, error) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
 	for i, part := range parts {
-		if part == "tasks" && i+1 < len(parts) {
-			return strconv.ParseInt(parts[i+1], 10, 64)
+		if part == segment && i+1 < len(parts) && parts[i+1] != "" {
+			return parts[i+1], nil
 		}
 	}
-	
-	return 0, fmt.Errorf("task id not found in path")
+	return "", fmt.Errorf("%s id not found in path", segment)
 }
 
-func (h *TaskHandler) validateCreateTaskRequest(req CreateTaskRequest) error {
-	if strings.TrimSpace(req.Name) == "" {
-		return fmt.Errorf("name is required")
+// parseListFilter parses the common list query parameters. Malformed
+// status, priority, assignee and paging values are ignored; malformed creator
+// and date filters are reported, since ignoring them would widen the result.
+func (h *TaskHandler) parseListFilter(r *http.Request) (task.ListTasksFilter, ValidationErrors) {
+	query := r.URL.Query()
+
+	// The use case applies the default and maximum limit
+	filter := task.ListTasksFilter{}
+
+	if status := query.Get("status"); status != "" {
+		s := domain.TaskStatus(status)
+		filter.Status = &s
+	}
+
+	if priority := query.Get("priority"); priority != "" {
+		p := domain.Priority(priority)
+		filter.Priority = &p
+	}
+
+	if assignedTo := query.Get("assigned_to"); assignedTo != "" {
+		id, err := strconv.ParseInt(assignedTo, 10, 64)
+		if err == nil {
+			filter.AssignedTo = &id
+		}
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		if l, err := strconv.Atoi(limit); err == nil && l > 0 {
+			filter.Limit = l
+		}
+	}
+
+	if offset := query.Get("offset"); offset != "" {
+		if o, err := strconv.Atoi(offset); err == nil && o >= 0 {
+			filter.Offset = o
+		}
 	}
-	if len(req.Name) > 255 {
-		return fmt.Errorf("name is too long (max 255 characters)")
+
+	errs := ValidationErrors{}
+	if createdBy := query.Get("created_by"); createdBy != "" {
+		if id, err := strconv.ParseInt(createdBy, 10, 64); err == nil && id > 0 {
+			filter.CreatedBy = &id
+		} else {
+			errs.Add("created_by", ReasonInvalid)
+		}
 	}
-	if req.Priority == "" {
-		return fmt.Errorf("priority is required")
+	if parentID := query.Get("parent_id"); parentID != "" {
+		if id, err := strconv.ParseInt(parentID, 10, 64); err == nil && id > 0 {
+			filter.ParentID = &id
+		} else {
+			errs.Add("parent_id", ReasonInvalid)
+		}
 	}
-	if !req.Priority.IsValid() {
-		return fmt.Errorf("invalid priority (allowed: low, medium, high)")
+	filter.CreatedAfter = parseTimeParam(query.Get("created_after"), "created_after", errs)
+	filter.CreatedBefore = parseTimeParam(query.Get("created_before"), "created_before", errs)
+	filter.DueBefore = parseTimeParam(query.Get("due_before"), "due_before", errs)
+	if filter.CreatedAfter != nil && filter.CreatedBefore != nil && filter.CreatedAfter.After(*filter.CreatedBefore) {
+		errs.Add("created_before", ReasonInvalid)
 	}
-	if req.CreatedBy <= 0 {
-		return fmt.Errorf("created_by is required")
+
+	// ?include=completed,cancelled lists terminal tasks where they are hidden by default
+	for _, status := range []domain.TaskStatus{domain.TaskStatusCompleted, domain.TaskStatusCancelled} {
+		if includes(r, string(status)) {
+			filter.Include = append(filter.Include, status)
+		}
+	}
+
+	return filter, errs
+}
+
+// parseTimeParam parses an RFC 3339 timestamp or a YYYY-MM-DD date (midnight
+// UTC), recording name as invalid in errs if it is neither. Empty means unset.
+func parseTimeParam(value, name string, errs ValidationErrors) *time.Time {
+	if value == "" {
+		return nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t
+		}
 	}
+	errs.Add(name, ReasonInvalid)
 	return nil
 }
 
-func (h *TaskHandler) handleUseCaseError(w http.ResponseWriter, err error) {
-	switch err {
-	case domain.ErrTaskNotFound:
-		h.respondError(w, http.StatusNotFound, err.Error())
-	case domain.ErrEmptyTaskName, domain.ErrTaskNameTooLong, domain.ErrInvalidInput:
-		h.respondError(w, http.StatusBadRequest, err.Error())
-	case domain.ErrUnauthorized:
-		h.respondError(w, http.StatusUnauthorized, err.Error())
+// includes reports whether the comma separated ?include= parameter contains name
+func includes(r *http.Request, name string) bool {
+	for _, v := range strings.Split(r.URL.Query().Get("include"), ",") {
+		if strings.TrimSpace(v) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// extractSubresourceID returns the numeric ID that follows segment in the path
+func (h *TaskHandler) extractSubresourceID(path, segment string) (int64, error) {
+	raw, err := pathSegmentAfter(path, segment)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(raw, 10, 64)
+}
+
+func (h *TaskHandler) validateCreateTaskRequest(req CreateTaskRequest) ValidationErrors {
+	errs := ValidationErrors{}
+	if strings.TrimSpace(req.Name) == "" {
+		errs.Add("name", ReasonRequired)
+	} else if len(req.Name) > 255 {
+		errs.Add("name", ReasonTooLong)
+	} else if strings.IndexFunc(req.Name, unicode.IsControl) >= 0 {
+		errs.Add("name", ReasonInvalid)
+	}
+	if utf8.RuneCountInString(req.Description) > h.taskLimits.DescriptionLength() {
+		errs.Add("description", ReasonTooLong)
+	}
+	// An empty priority falls back to tasks.default_priority
+	if req.Priority != "" && !req.Priority.IsValid() {
+		errs.Add("priority", ReasonInvalid)
+	}
+	if req.CreatedBy <= 0 {
+		errs.Add("created_by", ReasonRequired)
+	}
+	if req.RecurrenceRule != nil && *req.RecurrenceRule != "" && domain.ValidateRecurrenceRule(*req.RecurrenceRule) != nil {
+		errs.Add("recurrence_rule", ReasonInvalid)
+	}
+	return errs
+}
+
+// taskValidationField maps a domain validation error to the request field it
+// rejects. It reports false for errors that aren't about a single field.
+func taskValidationField(err error) (field, reason string, ok bool) {
+	switch {
+	case errors.Is(err, domain.ErrEmptyTaskName):
+		return "name", ReasonRequired, true
+	case errors.Is(err, domain.ErrTaskNameTooLong):
+		return "name", ReasonTooLong, true
+	case errors.Is(err, domain.ErrTaskNameInvalid):
+		return "name", ReasonInvalid, true
+	case errors.Is(err, domain.ErrDescriptionTooLong):
+		return "description", ReasonTooLong, true
+	case errors.Is(err, domain.ErrInvalidRecurrenceRule):
+		return "recurrence_rule", ReasonInvalid, true
+	default:
+		return "", "", false
+	}
+}
+
+func (h *TaskHandler) validateUpdateTaskRequest(req UpdateTaskRequest) ValidationErrors {
+	errs := ValidationErrors{}
+	if req.Name != nil {
+		if strings.TrimSpace(*req.Name) == "" {
+			errs.Add("name", ReasonRequired)
+		} else if len(*req.Name) > 255 {
+			errs.Add("name", ReasonTooLong)
+		} else if strings.IndexFunc(*req.Name, unicode.IsControl) >= 0 {
+			errs.Add("name", ReasonInvalid)
+		}
+	}
+	if description, ok := req.Description.Get(); ok && utf8.RuneCountInString(description) > h.taskLimits.DescriptionLength() {
+		errs.Add("description", ReasonTooLong)
+	}
+	if req.Status != nil && !req.Status.IsValid() {
+		errs.Add("status", ReasonInvalid)
+	}
+	if req.Priority != nil && !req.Priority.IsValid() {
+		errs.Add("priority", ReasonInvalid)
+	}
+	if assignee, ok := req.AssignedTo.Get(); ok && assignee <= 0 {
+		errs.Add("assigned_to", ReasonInvalid)
+	}
+	if req.RecurrenceRule != nil && *req.RecurrenceRule != "" && domain.ValidateRecurrenceRule(*req.RecurrenceRule) != nil {
+		errs.Add("recurrence_rule", ReasonInvalid)
+	}
+	return errs
+}
+
+func (h *TaskHandler) handleUseCaseError(w http.ResponseWriter, r *http.Request, err error) {
+	// Cancellation arrives wrapped by the repository; the client either gave up
+	// or the request ran past its deadline, neither of which is a server fault
+	if errors.Is(err, context.Canceled) {
+		h.respondError(w, r, statusClientClosedRequest, CodeRequestCancelled, "request cancelled")
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		h.respondError(w, r, http.StatusGatewayTimeout, CodeRequestTimeout, "request timed out")
+		return
+	}
+	var blocked *domain.TaskTreeBlockedError
+	if errors.As(err, &blocked) {
+		h.writeError(w, r, http.StatusUnprocessableEntity, ErrorBody{
+			Code:     CodeTaskTreeBlocked,
+			Message:  domain.ErrTaskTreeBlocked.Error(),
+			Blockers: blocked.Blockers,
+		})
+		return
+	}
+
+	// Sentinels are matched with errors.Is so they still map when the use case
+	// wraps them with more context; the message shows that context to the client
+	switch {
+	case errors.Is(err, domain.ErrTaskNotFound):
+		h.respondError(w, r, http.StatusNotFound, CodeTaskNotFound, err.Error())
+	case errors.Is(err, domain.ErrDependencyNotFound):
+		h.respondError(w, r, http.StatusNotFound, CodeDependencyNotFound, err.Error())
+	case errors.Is(err, domain.ErrDependencyCycle):
+		h.respondError(w, r, http.StatusConflict, CodeDependencyCycle, err.Error())
+	case errors.Is(err, domain.ErrDependenciesIncomplete):
+		h.respondError(w, r, http.StatusUnprocessableEntity, CodeDependenciesIncomplete, err.Error())
+	case errors.Is(err, domain.ErrSubtasksIncomplete):
+		h.respondError(w, r, http.StatusUnprocessableEntity, CodeSubtasksIncomplete, err.Error())
+	case errors.Is(err, domain.ErrTaskHasSubtasks):
+		h.respondError(w, r, http.StatusConflict, CodeTaskHasSubtasks, err.Error())
+	case errors.Is(err, domain.ErrTaskAlreadyCompleted):
+		h.respondError(w, r, http.StatusConflict, CodeTaskAlreadyCompleted, err.Error())
+	case errors.Is(err, domain.ErrTaskAlreadyCancelled):
+		h.respondError(w, r, http.StatusConflict, CodeTaskAlreadyCancelled, err.Error())
+	case errors.Is(err, domain.ErrTaskCancelled):
+		h.respondError(w, r, http.StatusUnprocessableEntity, CodeTaskCancelled, err.Error())
+	case errors.Is(err, domain.ErrTaskNotAssignable):
+		h.respondError(w, r, http.StatusUnprocessableEntity, CodeTaskNotAssignable, err.Error())
+	case errors.Is(err, domain.ErrTaskModified):
+		h.respondError(w, r, http.StatusPreconditionFailed, CodePreconditionFailed, err.Error())
+	case errors.Is(err, domain.ErrInvalidTransition):
+		h.respondError(w, r, http.StatusConflict, CodeInvalidTransition, err.Error())
+	case errors.Is(err, domain.ErrCommentNotFound):
+		h.respondError(w, r, http.StatusNotFound, CodeCommentNotFound, err.Error())
+	case errors.Is(err, domain.ErrEmptyComment):
+		h.respondError(w, r, http.StatusBadRequest, CodeCommentEmpty, err.Error())
+	case errors.Is(err, domain.ErrCommentTooLong):
+		h.respondError(w, r, http.StatusBadRequest, CodeCommentTooLong, err.Error())
+	case errors.Is(err, domain.ErrEmptyTaskName):
+		h.respondError(w, r, http.StatusBadRequest, CodeTaskNameEmpty, err.Error())
+	case errors.Is(err, domain.ErrTaskNameTooLong):
+		h.respondError(w, r, http.StatusBadRequest, CodeTaskNameTooLong, err.Error())
+	case errors.Is(err, domain.ErrTaskNameInvalid):
+		h.respondError(w, r, http.StatusBadRequest, CodeTaskNameInvalid, err.Error())
+	case errors.Is(err, domain.ErrDescriptionTooLong):
+		h.respondError(w, r, http.StatusBadRequest, CodeTaskDescriptionTooLong, err.Error())
+	case errors.Is(err, domain.ErrInvalidRecurrenceRule):
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidRecurrenceRule, err.Error())
+	case errors.Is(err, domain.ErrConflict):
+		h.respondError(w, r, http.StatusConflict, CodeConflict, err.Error())
+	case errors.Is(err, domain.ErrReferenceNotFound):
+		h.respondError(w, r, http.StatusUnprocessableEntity, CodeReferenceNotFound, err.Error())
+	case errors.Is(err, domain.ErrBatchTooLarge):
+		h.respondError(w, r, http.StatusBadRequest, CodeBatchTooLarge, err.Error())
+	case errors.Is(err, domain.ErrInvalidInput):
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidInput, err.Error())
+	case errors.Is(err, domain.ErrWebhookNotFound):
+		h.respondError(w, r, http.StatusNotFound, CodeWebhookNotFound, err.Error())
+	case errors.Is(err, domain.ErrWebhookURLInvalid):
+		h.respondError(w, r, http.StatusBadRequest, CodeWebhookURLInvalid, err.Error())
+	case errors.Is(err, domain.ErrWebhookEventTypeInvalid):
+		h.respondError(w, r, http.StatusBadRequest, CodeWebhookEventTypeInvalid, err.Error())
+	case errors.Is(err, domain.ErrUnauthorized):
+		h.respondError(w, r, http.StatusUnauthorized, CodeUnauthorized, err.Error())
+	case errors.Is(err, domain.ErrEventNotPublished):
+		// The change was rolled back; the request can be retried
+		h.respondError(w, r, http.StatusServiceUnavailable, CodeEventNotPublished, err.Error())
 	default:
-		h.respondError(w, http.StatusInternalServerError, "internal server error")
+		h.respondError(w, r, http.StatusInternalServerError, CodeInternal, "internal server error")
+	}
+}
+
+// requireRole writes an error response and returns false unless the caller's token has role
+func (h *TaskHandler) requireRole(w http.ResponseWriter, r *http.Request, role string) bool {
+	if pkgcontext.GetUserID(r.Context()) <= 0 {
+		h.respondError(w, r, http.StatusUnauthorized, CodeUnauthorized, domain.ErrUnauthorized.Error())
+		return false
+	}
+	if pkgcontext.GetUserRole(r.Context()) != role {
+		h.respondError(w, r, http.StatusForbidden, CodeForbidden, role+" role required")
+		return false
+	}
+	return true
+}
+
+func (h *TaskHandler) methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	h.respondError(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+}
+
+func (h *TaskHandler) respondValidationError(w http.ResponseWriter, r *http.Request, errs ValidationErrors) {
+	h.writeError(w, r, http.StatusBadRequest, ErrorBody{
+		Code:    CodeValidationFailed,
+		Message: "request validation failed",
+		Fields:  errs,
+	})
+}
+
+// rejectTaskRequest answers a task create or update request that failed
+// validation, counting each rejected field so the fields clients most often get
+// wrong show up in validation_failures_total. Field names come from the
+// validators, so the label set stays bounded.
+func (h *TaskHandler) rejectTaskRequest(w http.ResponseWriter, r *http.Request, errs ValidationErrors) {
+	for field, reason := range errs {
+		h.metrics.RecordValidationFailure(field, reason)
 	}
+	h.respondValidationError(w, r, errs)
 }
 
 func (h *TaskHandler) respondJSON(w http.ResponseWriter, status int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
+	h.render.JSON(w, status, data)
+}
+
+// streamTasks writes the tasks matching filter as they are read from the
+// repository, limited to fields when any were requested, so memory use doesn't
+// grow with the page size. A failure once the list has started can't become
+// an error response; the response is aborted instead, so the client sees a
+// truncated body rather than a list that looks complete.
+func (h *TaskHandler) streamTasks(w http.ResponseWriter, r *http.Request, filter task.ListTasksFilter, fields map[string]bool) {
+	list := h.render.list(w)
+	err := h.useCase.StreamTasks(r.Context(), filter, func(t *domain.Task) error {
+		if fields == nil {
+			return list.Add(t)
+		}
+		projected, err := projectTask(t, fields)
+		if err != nil {
+			return err
+		}
+		return list.Add(projected)
+	})
+	if err == nil {
+		err = list.Close()
+	}
+	if err == nil {
+		return
+	}
+
+	if !list.Started() {
+		h.handleUseCaseError(w, r, err)
+		return
+	}
+	h.logger.Error("[%s][trace:%s] Task list aborted mid-response: %v",
+		pkgcontext.GetRequestID(r.Context()), pkgcontext.GetTraceID(r.Context()), err)
+	panic(http.ErrAbortHandler)
+}
+
+func (h *TaskHandler) respondError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	h.writeError(w, r, status, ErrorBody{Code: code, Message: message})
+}
+
+func (h *TaskHandler) writeError(w http.ResponseWriter, r *http.Request, status int, body ErrorBody) {
+	body.RequestID = pkgcontext.GetRequestID(r.Context())
+	w.Header().Set("Content-Type", problemContentType)
 	w.WriteHeader(status)
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		h.logger.Error("Failed to encode response: %v", err)
+	if err := json.NewEncoder(w).Encode(ErrorResponse{Error: body}); err != nil {
+		h.logger.Error("Failed to encode error response: %v", err)
+	}
+}
+
+// decodeErrorMessage explains why a request body could not be decoded, pointing
+// at the offending offset or field where the decoder reports one
+func decodeErrorMessage(err error) string {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var timeErr *time.ParseError
+	switch {
+	case errors.Is(err, io.EOF):
+		return "request body is empty"
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return "request body is incomplete JSON"
+	case errors.As(err, &syntaxErr):
+		return fmt.Sprintf("invalid JSON at offset %d", syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		if typeErr.Field != "" {
+			return fmt.Sprintf("field '%s' must be %s", typeErr.Field, jsonTypeName(typeErr.Type))
+		}
+		if typeErr.Type.Kind() == reflect.Struct {
+			return "request body must be a JSON object"
+		}
+		// Fields with their own UnmarshalJSON (like task.Optional) report no field name
+		return fmt.Sprintf("request body has a value that must be %s", jsonTypeName(typeErr.Type))
+	case errors.As(err, &timeErr):
+		return fmt.Sprintf("%q is not an RFC 3339 timestamp", timeErr.Value)
+	default:
+		return "invalid request body"
 	}
 }
 
-func (h *TaskHandler) respondError(w http.ResponseWriter, status int, message string) {
-	h.respondJSON(w, status, ErrorResponse{Error: message})
+// jsonTypeName names the JSON type a Go type is decoded from
+func jsonTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
 }
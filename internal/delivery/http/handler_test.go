@@ -0,0 +1,139 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/auth"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/buildinfo"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/metrics"
+	"github.com/seldomhappy/vibe_architecture/logger"
+)
+
+func TestHandleUseCaseErrorUnwraps(t *testing.T) {
+	log := logger.New("test", logger.WithOutput(io.Discard))
+	h := NewTaskHandler(Config{}, nil, nil, nil, nil, nil, metrics.New(buildinfo.Info{}, 0, false), log)
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "wrapped task not found",
+			err:        fmt.Errorf("failed to get task: %w", domain.ErrTaskNotFound),
+			wantStatus: http.StatusNotFound,
+			wantCode:   CodeTaskNotFound,
+		},
+		{
+			name:       "twice wrapped task not found",
+			err:        fmt.Errorf("failed to complete task: %w", fmt.Errorf("failed to get task: %w", domain.ErrTaskNotFound)),
+			wantStatus: http.StatusNotFound,
+			wantCode:   CodeTaskNotFound,
+		},
+		{
+			name:       "joined with another error",
+			err:        errors.Join(errors.New("lookup failed"), domain.ErrTaskNotFound),
+			wantStatus: http.StatusNotFound,
+			wantCode:   CodeTaskNotFound,
+		},
+		{
+			name:       "wrapped conflict",
+			err:        fmt.Errorf("failed to create task: %w", domain.ErrConflict),
+			wantStatus: http.StatusConflict,
+			wantCode:   CodeConflict,
+		},
+		{
+			name:       "wrapped cancellation",
+			err:        fmt.Errorf("failed to get task: %w", context.Canceled),
+			wantStatus: statusClientClosedRequest,
+			wantCode:   CodeRequestCancelled,
+		},
+		{
+			name:       "unknown error",
+			err:        errors.New("connection reset"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   CodeInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.handleUseCaseError(rec, httptest.NewRequest(http.MethodGet, "/tasks/1", nil), tt.err)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			var body ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decode error response: %v", err)
+			}
+			if body.Error.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", body.Error.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestListTasksRejectsInvalidParentID(t *testing.T) {
+	srv, token := newTestServer(t, Config{TaskIDFormat: IDFormatInt64})
+
+	for _, parentID := range []string{"abc", "0", "-1"} {
+		t.Run(parentID, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/tasks?parent_id="+parentID, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			srv.server.Handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+			var body ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decode error response: %v", err)
+			}
+			if body.Error.Code != CodeValidationFailed || body.Error.Fields["parent_id"] != ReasonInvalid {
+				t.Errorf("error = %+v, want parent_id rejected as %s", body.Error, ReasonInvalid)
+			}
+		})
+	}
+}
+
+func TestOperatorEndpointsRequireOperatorRole(t *testing.T) {
+	srv, adminToken := newTestServer(t, Config{})
+	verifier, err := auth.NewVerifier(testJWTSecret, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	operatorToken := verifier.Sign(auth.Claims{UserID: 2, TenantID: "acme", Role: RoleOperator, ExpiresAt: time.Now().Add(time.Hour)})
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+	}{
+		{name: "tenant admin", token: adminToken, wantStatus: http.StatusForbidden},
+		{name: "operator", token: operatorToken, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/admin/reconcile-metrics", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			srv.server.Handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
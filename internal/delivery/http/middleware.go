@@ -1,34 +1,102 @@
 package http
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/auth"
 	pkgcontext "github.com/seldomhappy/vibe_architecture/internal/pkg/context"
 	"github.com/seldomhappy/vibe_architecture/internal/pkg/metrics"
 	"github.com/seldomhappy/vibe_architecture/internal/pkg/tracing"
 	"github.com/seldomhappy/vibe_architecture/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// RecoveryMiddleware handles panics and returns a 500 error
-func RecoveryMiddleware(log logger.ILogger) func(http.Handler) http.Handler {
+// PanicHook is told about every panic the recovery middleware catches, e.g. to
+// report it to an error tracker. stack is the stack trace of the panicking goroutine.
+type PanicHook func(ctx context.Context, recovered any, stack []byte)
+
+// RecoveryMiddleware turns panics into a 500 error. The stack trace is logged,
+// recorded on the request span and passed to hook, if set, but never sent to
+// the client. It runs inside the tracing middleware so the request and trace
+// IDs are known, and outside the logging and metrics ones so they see the 500.
+func RecoveryMiddleware(m *metrics.Metrics, hook PanicHook, log logger.ILogger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
-				if err := recover(); err != nil {
-					log.Error("Panic recovered: %v", err)
-					w.WriteHeader(http.StatusInternalServerError)
-					fmt.Fprintf(w, `{"error":"internal server error"}`)
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				// Handlers abort responses with it on purpose; net/http handles it quietly
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+
+				stack := debug.Stack()
+				ctx := r.Context()
+				requestID := pkgcontext.GetRequestID(ctx)
+				log.Error("[%s][trace:%s] Panic recovered in %s %s: %v\n%s",
+					requestID, pkgcontext.GetTraceID(ctx), r.Method, r.URL.Path, recovered, stack)
+
+				span := trace.SpanFromContext(ctx)
+				span.RecordError(fmt.Errorf("panic: %v", recovered),
+					trace.WithAttributes(attribute.String("exception.stacktrace", string(stack))))
+				span.SetStatus(codes.Error, "panic")
+				m.RecordPanic(r.Method, routeFromContext(r))
+				if hook != nil {
+					callPanicHook(ctx, hook, recovered, stack, log)
 				}
+
+				w.Header().Set("Content-Type", problemContentType)
+				w.WriteHeader(http.StatusInternalServerError)
+				_ = json.NewEncoder(w).Encode(ErrorResponse{
+					Error: ErrorBody{
+						Code:      CodeInternal,
+						Message:   "internal server error",
+						RequestID: requestID,
+					},
+				})
 			}()
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
+// callPanicHook runs hook, making sure a panicking hook can't take the error
+// response down with it
+func callPanicHook(ctx context.Context, hook PanicHook, recovered any, stack []byte, log logger.ILogger) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Error("Panic hook panicked: %v", err)
+		}
+	}()
+	hook(ctx, recovered, stack)
+}
+
+// RouteMiddleware matches the request to its route template once and stores it in
+// the context, so tracing, logging and metrics all label the request the same way
+func RouteMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := withRoute(r.Context(), routeTemplate(r.URL.Path))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
 // RequestIDMiddleware generates or extracts request ID
 func RequestIDMiddleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -46,19 +114,128 @@ func RequestIDMiddleware() func(http.Handler) http.Handler {
 	}
 }
 
-// TracingMiddleware creates a root span for the request
+// publicPaths are served without a token: probes, build information and the
+// API documentation
+var publicPaths = map[string]bool{
+	"/health":       true,
+	"/readyz":       true,
+	"/version":      true,
+	"/buildinfo":    true,
+	"/openapi.json": true,
+	"/docs":         true,
+}
+
+// AuthMiddleware identifies the caller from the bearer token in the
+// Authorization header and puts their user, role and tenant into the request
+// context. Identity headers sent by clients are never trusted. Requests outside
+// publicPaths are rejected without a valid token (401), and when the token
+// names no valid tenant (403), so every task query has a tenant to be scoped to.
+func AuthMiddleware(verifier *auth.Verifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if publicPaths[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeAuthError(w, r, http.StatusUnauthorized, CodeUnauthorized, "missing bearer token")
+				return
+			}
+			claims, err := verifier.Verify(token)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				writeAuthError(w, r, http.StatusUnauthorized, CodeUnauthorized, err.Error())
+				return
+			}
+			if err := domain.ValidateTenantID(claims.TenantID); err != nil {
+				writeAuthError(w, r, http.StatusForbidden, CodeInvalidTenant, "token has no valid tenant_id claim")
+				return
+			}
+
+			ctx := pkgcontext.WithUserID(r.Context(), claims.UserID)
+			if claims.Role != "" {
+				ctx = pkgcontext.WithUserRole(ctx, claims.Role)
+			}
+			ctx = pkgcontext.WithTenantID(ctx, claims.TenantID)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// writeAuthError writes the error response of a rejected token
+func writeAuthError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{
+		Error: ErrorBody{
+			Code:      code,
+			Message:   message,
+			RequestID: pkgcontext.GetRequestID(r.Context()),
+		},
+	})
+}
+
+// BaggageMiddleware puts the tenant and user of the request into the trace
+// baggage, so they follow the request across spans and into Kafka
+func BaggageMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// A value baggage can't carry is left out; the request goes on
+			// without it
+			ctx := r.Context()
+			if tenantID := pkgcontext.GetTenantID(ctx); tenantID != "" {
+				ctx, _ = tracing.SetBaggage(ctx, tracing.BaggageTenantID, tenantID)
+			}
+			if userID := pkgcontext.GetUserID(ctx); userID > 0 {
+				ctx, _ = tracing.SetBaggage(ctx, tracing.BaggageUserID, strconv.FormatInt(userID, 10))
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// TracingMiddleware creates a root span for the request, named after the route
+// rather than the raw path to keep span names low-cardinality
 func TracingMiddleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ctx, span := tracing.StartSpan(r.Context(), "http-server", r.URL.Path)
+			route := routeFromContext(r)
+			ctx, span := tracing.StartSpan(r.Context(), "http-server", r.Method+" "+route)
 			defer span.End()
 
+			span.SetAttributes(
+				attribute.String("http.method", r.Method),
+				attribute.String("http.route", route),
+				attribute.String("http.client_ip", clientIP(r)),
+				attribute.String("http.user_agent", r.UserAgent()),
+			)
+
 			traceID := pkgcontext.GetTraceID(ctx)
 			if traceID != "" {
 				w.Header().Set("X-Trace-ID", traceID)
 			}
 
-			next.ServeHTTP(w, r.WithContext(ctx))
+			if !span.IsRecording() {
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			// Server errors mark the span failed, so tracing.keep_errors keeps it;
+			// client errors leave its status unset
+			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(wrapped, r.WithContext(ctx))
+			span.SetAttributes(attribute.Int("http.status_code", wrapped.statusCode))
+			switch {
+			case wrapped.statusCode >= http.StatusInternalServerError:
+				span.SetStatus(codes.Error, http.StatusText(wrapped.statusCode))
+			case wrapped.statusCode < http.StatusBadRequest:
+				span.SetStatus(codes.Ok, "")
+			}
 		})
 	}
 }
@@ -78,13 +255,13 @@ func LoggingMiddleware(log logger.ILogger) func(http.Handler) http.Handler {
 			next.ServeHTTP(wrapped, r)
 
 			duration := time.Since(start)
-			log.Info("[%s][trace:%s] %s %s - %d (%v)",
-				requestID, traceID, r.Method, r.URL.Path, wrapped.statusCode, duration)
+			log.Info("[%s][trace:%s] %s %s route=%s - %d (%v)",
+				requestID, traceID, r.Method, r.URL.Path, routeFromContext(r), wrapped.statusCode, duration)
 		})
 	}
 }
 
-// MetricsMiddleware records HTTP metrics
+// MetricsMiddleware records HTTP metrics, labelled by route so there is one series per endpoint
 func MetricsMiddleware(m *metrics.Metrics) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -98,7 +275,7 @@ func MetricsMiddleware(m *metrics.Metrics) func(http.Handler) http.Handler {
 			duration := time.Since(start)
 			m.RecordHTTPRequest(
 				r.Method,
-				r.URL.Path,
+				routeFromContext(r),
 				fmt.Sprintf("%d", wrapped.statusCode),
 				duration,
 			)
@@ -106,10 +283,34 @@ func MetricsMiddleware(m *metrics.Metrics) func(http.Handler) http.Handler {
 	}
 }
 
-// TimeoutMiddleware adds a timeout to requests
-func TimeoutMiddleware(timeout time.Duration) func(http.Handler) http.Handler {
+// TimeoutMiddleware gives each request a time budget. Clients may ask for a
+// shorter one with X-Request-Timeout (a duration such as 500ms); longer ones are
+// clamped to max. The budget applied is echoed in the response header.
+func TimeoutMiddleware(max time.Duration) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout := max
+			if header := r.Header.Get("X-Request-Timeout"); header != "" {
+				requested, err := time.ParseDuration(header)
+				if err != nil || requested <= 0 {
+					w.Header().Set("Content-Type", problemContentType)
+					w.WriteHeader(http.StatusBadRequest)
+					_ = json.NewEncoder(w).Encode(ErrorResponse{
+						Error: ErrorBody{
+							Code:      CodeValidationFailed,
+							Message:   "request validation failed",
+							RequestID: pkgcontext.GetRequestID(r.Context()),
+							Fields:    ValidationErrors{"X-Request-Timeout": ReasonInvalid},
+						},
+					})
+					return
+				}
+				if requested < timeout {
+					timeout = requested
+				}
+			}
+			w.Header().Set("X-Request-Timeout", timeout.String())
+
 			ctx, cancel := context.WithTimeout(r.Context(), timeout)
 			defer cancel()
 
@@ -118,6 +319,45 @@ func TimeoutMiddleware(timeout time.Duration) func(http.Handler) http.Handler {
 	}
 }
 
+// ConcurrencyLimitMiddleware caps the number of requests handled at once. A
+// request over the limit is turned away with 503 and Retry-After right away
+// rather than queued, so a burst can't pile up waiting for database
+// connections. A limit of 0 disables it.
+func ConcurrencyLimitMiddleware(limit int, m *metrics.Metrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 {
+			return next
+		}
+		slots := make(chan struct{}, limit)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case slots <- struct{}{}:
+			default:
+				m.RecordHTTPRequestShed()
+				w.Header().Set("Retry-After", "1")
+				w.Header().Set("Content-Type", problemContentType)
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_ = json.NewEncoder(w).Encode(ErrorResponse{
+					Error: ErrorBody{
+						Code:      CodeOverloaded,
+						Message:   "too many concurrent requests, retry later",
+						RequestID: pkgcontext.GetRequestID(r.Context()),
+					},
+				})
+				return
+			}
+			m.SetHTTPConcurrentRequests(len(slots))
+			// Deferred so a panicking handler gives its slot back too
+			defer func() {
+				<-slots
+				m.SetHTTPConcurrentRequests(len(slots))
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // responseWriter wraps http.ResponseWriter to capture status code
 type responseWriter struct {
 	http.ResponseWriter
@@ -128,3 +368,13 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.statusCode = code
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+// Hijack lets the WebSocket upgrader take over the connection
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(rw.ResponseWriter).Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer (flushing, deadlines)
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
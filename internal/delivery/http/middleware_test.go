@@ -0,0 +1,117 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/auth"
+	pkgcontext "github.com/seldomhappy/vibe_architecture/internal/pkg/context"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	verifier, err := auth.NewVerifier("test-secret-0123456789abcdef-0123456789", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires := time.Now().Add(time.Hour)
+
+	var user int64
+	var role, tenant string
+	handler := AuthMiddleware(verifier)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user = pkgcontext.GetUserID(r.Context())
+		role = pkgcontext.GetUserRole(r.Context())
+		tenant = pkgcontext.GetTenantID(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		headers    map[string]string
+		wantStatus int
+		wantCode   string
+		wantUser   int64
+		wantRole   string
+		wantTenant string
+	}{
+		{
+			name:       "public path without token",
+			path:       "/health",
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "no token",
+			path:       "/tasks",
+			headers:    map[string]string{"X-User-ID": "1", "X-User-Role": RoleAdmin, "X-Tenant-ID": "acme"},
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   CodeUnauthorized,
+		},
+		{
+			name:       "invalid token",
+			path:       "/tasks",
+			token:      "not-a-token",
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   CodeUnauthorized,
+		},
+		{
+			name:       "token without tenant",
+			path:       "/tasks",
+			token:      verifier.Sign(auth.Claims{UserID: 7, ExpiresAt: expires}),
+			wantStatus: http.StatusForbidden,
+			wantCode:   CodeInvalidTenant,
+		},
+		{
+			name:       "identity headers are ignored",
+			path:       "/admin/webhooks",
+			token:      verifier.Sign(auth.Claims{UserID: 7, TenantID: "acme", ExpiresAt: expires}),
+			headers:    map[string]string{"X-User-ID": "1", "X-User-Role": RoleAdmin, "X-Tenant-ID": "globex"},
+			wantStatus: http.StatusNoContent,
+			wantUser:   7,
+			wantTenant: "acme",
+		},
+		{
+			name:       "role from token",
+			path:       "/admin/webhooks",
+			token:      verifier.Sign(auth.Claims{UserID: 1, TenantID: "acme", Role: RoleAdmin, ExpiresAt: expires}),
+			wantStatus: http.StatusNoContent,
+			wantUser:   1,
+			wantRole:   RoleAdmin,
+			wantTenant: "acme",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, role, tenant = 0, "", ""
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantCode != "" {
+				var body ErrorResponse
+				if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+					t.Fatalf("decode error response: %v", err)
+				}
+				if body.Error.Code != tt.wantCode {
+					t.Errorf("code = %q, want %q", body.Error.Code, tt.wantCode)
+				}
+				return
+			}
+			if user != tt.wantUser || role != tt.wantRole || tenant != tt.wantTenant {
+				t.Errorf("context = (%d, %q, %q), want (%d, %q, %q)", user, role, tenant, tt.wantUser, tt.wantRole, tt.wantTenant)
+			}
+		})
+	}
+}
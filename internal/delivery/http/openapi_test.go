@@ -0,0 +1,113 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// undocumentedRoutes are served but deliberately left out of the spec
+var undocumentedRoutes = map[string]bool{
+	"/buildinfo":    true, // alias of /version
+	"/openapi.json": true,
+	"/docs":         true,
+}
+
+var pathParam = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// specOperations returns the methods the spec documents for each path
+func specOperations(t *testing.T) map[string][]string {
+	t.Helper()
+
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("decode openapi.json: %v", err)
+	}
+
+	ops := make(map[string][]string, len(spec.Paths))
+	for path, item := range spec.Paths {
+		for method := range item {
+			if method == "parameters" {
+				continue
+			}
+			ops[path] = append(ops[path], strings.ToUpper(method))
+		}
+		sort.Strings(ops[path])
+	}
+	return ops
+}
+
+// registeredRoutes lists every route template the router serves
+func registeredRoutes() []string {
+	var routes []string
+	for route := range staticRoutes {
+		routes = append(routes, route)
+	}
+	routes = append(routes, "/tasks/{id}", "/admin/webhooks/{id}", "/admin/webhooks/{id}/dead-letters")
+	for sub, param := range taskSubresources {
+		routes = append(routes, "/tasks/{id}/"+sub)
+		if param != "" {
+			routes = append(routes, "/tasks/{id}/"+sub+"/"+param)
+		}
+	}
+	return routes
+}
+
+func TestOpenAPIPathsAreRoutes(t *testing.T) {
+	for path := range specOperations(t) {
+		concrete := pathParam.ReplaceAllString(path, "1")
+		if got := routeTemplate(concrete); got != path {
+			t.Errorf("spec path %s is routed as %s", path, got)
+		}
+	}
+}
+
+func TestRoutesAreDocumented(t *testing.T) {
+	ops := specOperations(t)
+	for _, route := range registeredRoutes() {
+		if _, ok := ops[route]; !ok && !undocumentedRoutes[route] {
+			t.Errorf("route %s is missing from openapi.json", route)
+		}
+	}
+}
+
+func TestOpenAPIMethodsAreServed(t *testing.T) {
+	srv, token := newTestServer(t, Config{TaskIDFormat: IDFormatInt64})
+	handler := srv.server.Handler
+
+	for path, methods := range specOperations(t) {
+		// Streams stay open; their paths are checked above
+		if path == "/events" || path == "/ws" {
+			continue
+		}
+
+		concrete := pathParam.ReplaceAllString(path, "1")
+		for _, method := range methods {
+			req := httptest.NewRequest(method, concrete, strings.NewReader("{}"))
+			req.Header.Set("Authorization", "Bearer "+token)
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code == http.StatusMethodNotAllowed || isRouteNotFound(rec) {
+				t.Errorf("%s %s is documented but not served: status %d", method, path, rec.Code)
+			}
+		}
+	}
+}
+
+// isRouteNotFound tells a path nobody serves from a resource that doesn't
+// exist, which comes with an error code such as TASK_NOT_FOUND
+func isRouteNotFound(rec *httptest.ResponseRecorder) bool {
+	if rec.Code != http.StatusNotFound {
+		return false
+	}
+	var body ErrorResponse
+	return json.NewDecoder(rec.Body).Decode(&body) != nil || body.Error.Code == ""
+}
@@ -0,0 +1,130 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+
+	"github.com/seldomhappy/vibe_architecture/logger"
+)
+
+// Response formats of successful JSON responses
+const (
+	// ResponseFormatBare writes the resource or list itself
+	ResponseFormatBare = "bare"
+	// ResponseFormatEnvelope wraps it as {"data": ..., "meta": ...}
+	ResponseFormatEnvelope = "envelope"
+)
+
+// Envelope is the shape of successful responses in the envelope format
+type Envelope struct {
+	Data interface{} `json:"data"`
+	Meta *Meta       `json:"meta,omitempty"`
+}
+
+// Meta describes the data of an enveloped response; it is set for lists
+type Meta struct {
+	Count int `json:"count"`
+}
+
+// renderer writes successful JSON responses, deciding their shape in one place.
+// Error responses always keep their {"error": ...} shape.
+type renderer struct {
+	envelope bool
+	logger   logger.ILogger
+}
+
+func newRenderer(format string, log logger.ILogger) renderer {
+	return renderer{envelope: format == ResponseFormatEnvelope, logger: log}
+}
+
+// JSON writes data, enveloped if the format says so. Slices are lists and get
+// a meta with their length.
+func (rd renderer) JSON(w http.ResponseWriter, status int, data interface{}) {
+	if rd.envelope {
+		envelope := Envelope{Data: data}
+		if v := reflect.ValueOf(data); v.Kind() == reflect.Slice {
+			envelope.Meta = &Meta{Count: v.Len()}
+		}
+		data = envelope
+	}
+	rd.raw(w, status, data)
+}
+
+// list starts a list response written one element at a time, in the shape
+// JSON gives lists
+func (rd renderer) list(w http.ResponseWriter) *listWriter {
+	return &listWriter{w: w, envelope: rd.envelope}
+}
+
+// listWriter streams a JSON list so it is never held in memory as a whole.
+// Nothing is written before the first element or Close, so a failure up to
+// then can still be answered with an error response.
+type listWriter struct {
+	w        http.ResponseWriter
+	envelope bool
+	count    int
+	started  bool
+}
+
+// Add writes the next element of the list
+func (lw *listWriter) Add(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if lw.started {
+		if _, err := io.WriteString(lw.w, ","); err != nil {
+			return err
+		}
+	} else if err := lw.start(); err != nil {
+		return err
+	}
+	lw.count++
+	_, err = lw.w.Write(data)
+	return err
+}
+
+// Close ends the list, with its meta when enveloped
+func (lw *listWriter) Close() error {
+	if !lw.started {
+		if err := lw.start(); err != nil {
+			return err
+		}
+	}
+	end := "]\n"
+	if lw.envelope {
+		end = fmt.Sprintf(`],"meta":{"count":%d}}`+"\n", lw.count)
+	}
+	_, err := io.WriteString(lw.w, end)
+	return err
+}
+
+// Started reports whether the response has been committed
+func (lw *listWriter) Started() bool {
+	return lw.started
+}
+
+func (lw *listWriter) start() error {
+	lw.started = true
+	lw.w.Header().Set("Content-Type", "application/json")
+	lw.w.WriteHeader(http.StatusOK)
+	open := "["
+	if lw.envelope {
+		open = `{"data":[`
+	}
+	_, err := io.WriteString(lw.w, open)
+	return err
+}
+
+// raw writes data as is, whatever the format. It's meant for responses whose
+// shape is fixed by something other than API clients, such as health probes.
+func (rd renderer) raw(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		rd.logger.Error("Failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type routeContextKey struct{}
+
+// withRoute stores the matched route template in the context
+func withRoute(ctx context.Context, route string) context.Context {
+	return context.WithValue(ctx, routeContextKey{}, route)
+}
+
+// routeFromContext returns the route template stored by RouteMiddleware,
+// falling back to matching the path when the middleware didn't run
+func routeFromContext(r *http.Request) string {
+	if route, ok := r.Context().Value(routeContextKey{}).(string); ok {
+		return route
+	}
+	return routeTemplate(r.URL.Path)
+}
+
+// routeUnmatched labels requests that don't belong to any route
+const routeUnmatched = "unmatched"
+
+// staticRoutes are the routes without path parameters
+var staticRoutes = map[string]bool{
+	"/health":                  true,
+	"/readyz":                  true,
+	"/version":                 true,
+	"/buildinfo":               true,
+	"/openapi.json":            true,
+	"/docs":                    true,
+	"/stats":                   true,
+	"/events":                  true,
+	"/ws":                      true,
+	"/tasks":                   true,
+	"/tasks/bulk-status":       true,
+	"/tasks/validate":          true,
+	"/me/tasks":                true,
+	"/admin/reconcile-metrics": true,
+	"/admin/dlq/replay":        true,
+	"/admin/webhooks":          true,
+}
+
+// taskSubresources maps the segment after /tasks/{id} to the parameter that may follow it
+var taskSubresources = map[string]string{
+	"assign":       "",
+	"complete":     "",
+	"subtasks":     "",
+	"dependencies": "{depends_on_id}",
+	"comments":     "{comment_id}",
+}
+
+// routeTemplate returns the route pattern serving path, with ids replaced by
+// placeholders (/tasks/42/complete becomes /tasks/{id}/complete). It labels spans
+// and metrics, so unknown paths collapse into one value instead of one per URL.
+func routeTemplate(path string) string {
+	path = strings.TrimSuffix(path, "/")
+	if staticRoutes[path] {
+		return path
+	}
+
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(parts) >= 3 && parts[0] == "admin" && parts[1] == "webhooks" {
+		return webhookRouteTemplate(parts[2:])
+	}
+	if len(parts) < 2 || len(parts) > 4 || parts[0] != "tasks" || parts[1] == "" {
+		return routeUnmatched
+	}
+
+	route := "/tasks/{id}"
+	if len(parts) == 2 {
+		return route
+	}
+
+	param, ok := taskSubresources[parts[2]]
+	if !ok {
+		return routeUnmatched
+	}
+	route += "/" + parts[2]
+	if len(parts) == 4 {
+		if param == "" || parts[3] == "" {
+			return routeUnmatched
+		}
+		route += "/" + param
+	}
+	return route
+}
+
+// webhookRouteTemplate returns the route of the path segments after /admin/webhooks
+func webhookRouteTemplate(parts []string) string {
+	if parts[0] == "" {
+		return routeUnmatched
+	}
+	switch {
+	case len(parts) == 1:
+		return "/admin/webhooks/{id}"
+	case len(parts) == 2 && parts[1] == "dead-letters":
+		return "/admin/webhooks/{id}/dead-letters"
+	}
+	return routeUnmatched
+}
+
+// clientIP returns the address of the client that made the request. The API
+// gateway reports it in X-Forwarded-For or X-Real-IP; without them it is the peer address.
+func clientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		first, _, _ := strings.Cut(forwarded, ",")
+		return strings.TrimSpace(first)
+	}
+	if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+		return realIP
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
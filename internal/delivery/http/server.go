@@ -3,20 +3,32 @@ package http
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	"github.com/seldomhappy/vibe_architecture/internal/infrastructure/kafka"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/auth"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/buildinfo"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/health"
 	"github.com/seldomhappy/vibe_architecture/internal/pkg/metrics"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/pubsub"
 	"github.com/seldomhappy/vibe_architecture/internal/usecase/task"
+	"github.com/seldomhappy/vibe_architecture/internal/usecase/webhook"
 	"github.com/seldomhappy/vibe_architecture/logger"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 // Server represents the HTTP server
 type Server struct {
-	server  *http.Server
-	handler *TaskHandler
-	logger  logger.ILogger
+	server   *http.Server
+	listener net.Listener
+	tls      TLSConfig
+	handler  *TaskHandler
+	logger   logger.ILogger
 }
 
 // Config holds server configuration
@@ -26,17 +38,125 @@ type Config struct {
 	ReadTimeout     time.Duration
 	WriteTimeout    time.Duration
 	ShutdownTimeout time.Duration
+	// ReadHeaderTimeout bounds reading the request headers
+	ReadHeaderTimeout time.Duration
+	// IdleTimeout is how long a keep-alive connection waits for the next request
+	IdleTimeout time.Duration
+	// MaxHeaderBytes caps the size of the request headers
+	MaxHeaderBytes int
+	// H2C enables HTTP/2 over plaintext connections
+	H2C bool
+	// TLS serves HTTPS when a certificate is configured
+	TLS TLSConfig
+	// RequestTimeout is the default and maximum time budget of a request
+	RequestTimeout time.Duration
+	// MaxConcurrentRequests caps the requests handled at once; 0 means no limit
+	MaxConcurrentRequests int
+	// EventsHeartbeat is how often idle event streams send a keep-alive
+	EventsHeartbeat time.Duration
+	// TaskIDFormat is how tasks are referenced in URL paths: IDFormatInt64 or IDFormatUUID
+	TaskIDFormat string
+	// DLQReplayMax caps the max parameter of a dead letter queue replay
+	DLQReplayMax int
+	// TaskLimits bounds task fields; requests beyond them are rejected up front
+	TaskLimits domain.Limits
+	// ListCacheMaxAge is the max-age of the Cache-Control header of task lists
+	ListCacheMaxAge time.Duration
+	// ResponseFormat shapes successful responses: ResponseFormatBare or ResponseFormatEnvelope
+	ResponseFormat string
+	// Build is served at /version
+	Build buildinfo.Info
+	// PanicHook, if set, is called with every panic recovered from a handler
+	PanicHook PanicHook
+	// Auth verifies the bearer tokens that identify callers
+	Auth *auth.Verifier
 }
 
+// DeadLetterReplayer republishes messages parked in the dead letter queue
+type DeadLetterReplayer interface {
+	Replay(ctx context.Context, max int, dryRun bool) (kafka.ReplayResult, error)
+}
+
+// Role claims that grant access to /admin endpoints
+const (
+	// RoleAdmin manages the resources of its tenant, such as webhooks
+	RoleAdmin = "admin"
+	// RoleOperator runs the operations that affect every tenant, such as
+	// metrics reconciliation and dead letter replays
+	RoleOperator = "operator"
+)
+
+// Task ID formats accepted in URL paths
+const (
+	IDFormatInt64 = "int64"
+	IDFormatUUID  = "uuid"
+)
+
 // New creates a new HTTP server
-func New(cfg Config, taskUC task.UseCase, m *metrics.Metrics, log logger.ILogger) *Server {
-	handler := NewTaskHandler(taskUC, log)
+// A nil dlq means Kafka is disabled.
+func New(cfg Config, taskUC task.UseCase, webhookUC webhook.UseCase, broker *pubsub.Broker, dlq DeadLetterReplayer, readiness *health.Checker, m *metrics.Metrics, log logger.ILogger) *Server {
+	handler := NewTaskHandler(cfg, taskUC, webhookUC, broker, dlq, readiness, m, log)
 
 	mux := http.NewServeMux()
-	
+
 	// Health check
 	mux.HandleFunc("/health", handler.Health)
-	
+	mux.HandleFunc("/readyz", handler.Readyz)
+	mux.HandleFunc("/version", handler.Version)
+	mux.HandleFunc("/buildinfo", handler.Version)
+
+	// API documentation
+	mux.HandleFunc("/openapi.json", handler.OpenAPI)
+	mux.HandleFunc("/docs", handler.Docs)
+
+	// Task aggregates
+	mux.HandleFunc("/stats", handler.Stats)
+
+	// Operator endpoints
+	mux.HandleFunc("/admin/reconcile-metrics", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			handler.methodNotAllowed(w, r)
+			return
+		}
+		handler.ReconcileMetrics(w, r)
+	})
+	mux.HandleFunc("/admin/dlq/replay", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			handler.methodNotAllowed(w, r)
+			return
+		}
+		handler.ReplayDLQ(w, r)
+	})
+	mux.HandleFunc("/admin/webhooks", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handler.ListWebhooks(w, r)
+		case http.MethodPost:
+			handler.CreateWebhook(w, r)
+		default:
+			handler.methodNotAllowed(w, r)
+		}
+	})
+	mux.HandleFunc("/admin/webhooks/", func(w http.ResponseWriter, r *http.Request) {
+		if contains(r.URL.Path, "/dead-letters") {
+			if r.Method == http.MethodGet {
+				handler.ListWebhookDeadLetters(w, r)
+			} else {
+				handler.methodNotAllowed(w, r)
+			}
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			handler.GetWebhook(w, r)
+		case http.MethodDelete:
+			handler.DeleteWebhook(w, r)
+		default:
+			handler.methodNotAllowed(w, r)
+		}
+	})
+
 	// Task routes
 	mux.HandleFunc("/tasks", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
@@ -45,30 +165,92 @@ func New(cfg Config, taskUC task.UseCase, m *metrics.Metrics, log logger.ILogger
 		case http.MethodPost:
 			handler.CreateTask(w, r)
 		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			handler.methodNotAllowed(w, r)
+		}
+	})
+
+	mux.HandleFunc("/tasks/validate", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			handler.methodNotAllowed(w, r)
+			return
+		}
+		handler.ValidateTask(w, r)
+	})
+
+	mux.HandleFunc("/tasks/bulk-status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			handler.methodNotAllowed(w, r)
+			return
+		}
+		handler.BulkStatus(w, r)
+	})
+
+	mux.HandleFunc("/me/tasks", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			handler.methodNotAllowed(w, r)
+			return
 		}
+		handler.ListMyTasks(w, r)
 	})
-	
+
 	mux.HandleFunc("/tasks/", func(w http.ResponseWriter, r *http.Request) {
 		// Check if it's an action endpoint
+		if contains(r.URL.Path, "/dependencies") {
+			switch r.Method {
+			case http.MethodPost:
+				handler.AddDependency(w, r)
+			case http.MethodDelete:
+				handler.RemoveDependency(w, r)
+			default:
+				handler.methodNotAllowed(w, r)
+			}
+			return
+		}
+
+		if contains(r.URL.Path, "/comments") {
+			switch r.Method {
+			case http.MethodGet:
+				handler.ListComments(w, r)
+			case http.MethodPost:
+				handler.AddComment(w, r)
+			case http.MethodDelete:
+				handler.DeleteComment(w, r)
+			default:
+				handler.methodNotAllowed(w, r)
+			}
+			return
+		}
+
+		if contains(r.URL.Path, "/subtasks") {
+			switch r.Method {
+			case http.MethodGet:
+				handler.ListSubtasks(w, r)
+			case http.MethodPost:
+				handler.CreateSubtask(w, r)
+			default:
+				handler.methodNotAllowed(w, r)
+			}
+			return
+		}
+
 		if contains(r.URL.Path, "/assign") {
 			if r.Method == http.MethodPost {
 				handler.AssignTask(w, r)
 			} else {
-				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				handler.methodNotAllowed(w, r)
 			}
 			return
 		}
-		
+
 		if contains(r.URL.Path, "/complete") {
 			if r.Method == http.MethodPost {
 				handler.CompleteTask(w, r)
 			} else {
-				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				handler.methodNotAllowed(w, r)
 			}
 			return
 		}
-		
+
 		// Regular CRUD operations
 		switch r.Method {
 		case http.MethodGet:
@@ -78,32 +260,59 @@ func New(cfg Config, taskUC task.UseCase, m *metrics.Metrics, log logger.ILogger
 		case http.MethodDelete:
 			handler.DeleteTask(w, r)
 		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			handler.methodNotAllowed(w, r)
 		}
 	})
 
+	// Long-lived streams bypass the request timeout. Everything else gets a
+	// deadline no later than the write timeout: past it the response can't be
+	// sent anyway, so in-flight queries are cancelled instead of running on.
+	// Health probes and streams aren't counted against the concurrency limit:
+	// a busy instance must still answer its probes.
+	root := http.NewServeMux()
+	root.HandleFunc("/events", handler.Events)
+	root.HandleFunc("/ws", handler.WebSocket)
+	root.Handle("/health", TimeoutMiddleware(cfg.RequestTimeout)(mux))
+	root.Handle("/readyz", TimeoutMiddleware(cfg.RequestTimeout)(mux))
+	root.Handle("/", TimeoutMiddleware(cfg.RequestTimeout)(ConcurrencyLimitMiddleware(cfg.MaxConcurrentRequests, m)(mux)))
+
 	// Apply middleware chain in correct order
-	finalHandler := RecoveryMiddleware(log)(
+	finalHandler := RouteMiddleware()(
 		RequestIDMiddleware()(
-			TracingMiddleware()(
-				LoggingMiddleware(log)(
-					MetricsMiddleware(m)(
-						TimeoutMiddleware(30*time.Second)(mux),
+			AuthMiddleware(cfg.Auth)(
+				BaggageMiddleware()(
+					TracingMiddleware()(
+						LoggingMiddleware(log)(
+							MetricsMiddleware(m)(
+								RecoveryMiddleware(m, cfg.PanicHook, log)(root),
+							),
+						),
 					),
 				),
 			),
 		),
 	)
 
+	// h2c upgrades plaintext HTTP/2 connections and passes HTTP/1 requests through unchanged
+	var rootHandler http.Handler = finalHandler
+	if cfg.H2C {
+		rootHandler = h2c.NewHandler(finalHandler, &http2.Server{IdleTimeout: cfg.IdleTimeout})
+		log.Info("HTTP/2 over plaintext (h2c) is enabled")
+	}
+
 	server := &http.Server{
-		Addr:         fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
-		Handler:      finalHandler,
-		ReadTimeout:  cfg.ReadTimeout,
-		WriteTimeout: cfg.WriteTimeout,
+		Addr:              fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		Handler:           rootHandler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
 	}
 
 	return &Server{
 		server:  server,
+		tls:     cfg.TLS,
 		handler: handler,
 		logger:  log,
 	}
@@ -111,10 +320,34 @@ func New(cfg Config, taskUC task.UseCase, m *metrics.Metrics, log logger.ILogger
 
 // Start starts the HTTP server
 func (s *Server) Start(ctx context.Context) error {
-	s.logger.Info("Starting HTTP server on %s", s.server.Addr)
+	if s.tls.Enabled() {
+		tlsConfig, err := newTLSConfig(s.tls, s.logger)
+		if err != nil {
+			return err
+		}
+		s.server.TLSConfig = tlsConfig
+		s.logger.Info("Starting HTTPS server on %s", s.server.Addr)
+	} else {
+		s.logger.Info("Starting HTTP server on %s", s.server.Addr)
+	}
+
+	// Bind before returning so a taken port or a missing permission fails
+	// startup instead of leaving the app running without a listener
+	ln, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.server.Addr, err)
+	}
+	s.listener = ln
 
 	go func() {
-		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if s.server.TLSConfig != nil {
+			// The certificate comes from TLSConfig.GetCertificate
+			err = s.server.ServeTLS(ln, "", "")
+		} else {
+			err = s.server.Serve(ln)
+		}
+		if err != nil && err != http.ErrServerClosed {
 			s.logger.Error("HTTP server error: %v", err)
 		}
 	}()
@@ -122,6 +355,15 @@ func (s *Server) Start(ctx context.Context) error {
 	return nil
 }
 
+// Addr returns the address the server listens on, which tells the port picked
+// when the configured one is 0. It is empty until Start succeeds.
+func (s *Server) Addr() string {
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
 // Shutdown gracefully shuts down the HTTP server
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.logger.Info("Shutting down HTTP server")
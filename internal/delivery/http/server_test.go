@@ -0,0 +1,102 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/auth"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/buildinfo"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/health"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/metrics"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/pubsub"
+	"github.com/seldomhappy/vibe_architecture/internal/repository/memory"
+	"github.com/seldomhappy/vibe_architecture/internal/usecase/task"
+	"github.com/seldomhappy/vibe_architecture/internal/usecase/webhook"
+	"github.com/seldomhappy/vibe_architecture/logger"
+)
+
+func TestServerStartOnPortZero(t *testing.T) {
+	srv, _ := newTestServer(t, Config{Host: "127.0.0.1"})
+	if err := srv.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer srv.Shutdown(context.Background())
+
+	addr := srv.Addr()
+	if _, port, err := net.SplitHostPort(addr); err != nil || port == "0" {
+		t.Fatalf("Addr = %q, want the port the OS picked", addr)
+	}
+
+	resp, err := http.Get("http://" + addr + "/health")
+	if err != nil {
+		t.Fatalf("GET /health: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /health status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestServerStartPortInUse(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+
+	port := taken.Addr().(*net.TCPAddr).Port
+	srv, _ := newTestServer(t, Config{Host: "127.0.0.1", Port: port})
+	err = srv.Start(context.Background())
+	if err == nil {
+		srv.Shutdown(context.Background())
+		t.Fatalf("Start on taken port %d succeeded, want an error", port)
+	}
+	if want := fmt.Sprintf("127.0.0.1:%d", port); !strings.Contains(err.Error(), want) {
+		t.Errorf("Start error = %q, want it to name %s", err, want)
+	}
+	if srv.Addr() != "" {
+		t.Errorf("Addr = %q after a failed Start, want empty", srv.Addr())
+	}
+}
+
+// testJWTSecret signs the tokens of the test server
+const testJWTSecret = "test-secret-0123456789abcdef-0123456789"
+
+// newTestServer returns a server backed by the in-memory repositories, and an
+// admin token for it
+func newTestServer(t testing.TB, cfg Config) (*Server, string) {
+	t.Helper()
+
+	log := logger.New("test", logger.WithOutput(io.Discard))
+	verifier, err := auth.NewVerifier(testJWTSecret, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Auth = verifier
+	if cfg.RequestTimeout == 0 {
+		cfg.RequestTimeout = 5 * time.Second
+	}
+
+	store := memory.NewStore()
+	taskUC := task.New(task.Config{
+		DefaultPriority: domain.PriorityMedium,
+		PublishPolicy:   task.PublishBestEffort,
+	}, memory.NewTaskRepository(store, log), memory.NewTxManager(store, log), nopEventBus{}, log, nil)
+	webhookUC := webhook.New(memory.NewWebhookRepository(log), log)
+
+	srv := New(cfg, taskUC, webhookUC, pubsub.New(1, log), nil, health.New(time.Second), metrics.New(buildinfo.Info{}, 0, false), log)
+
+	token := verifier.Sign(auth.Claims{UserID: 1, TenantID: "acme", Role: RoleAdmin, ExpiresAt: time.Now().Add(time.Hour)})
+	return srv, token
+}
+
+type nopEventBus struct{}
+
+func (nopEventBus) Publish(ctx context.Context, event domain.TaskEvent) {}
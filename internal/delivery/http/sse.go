@@ -0,0 +1,122 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	pkgcontext "github.com/seldomhappy/vibe_architecture/internal/pkg/context"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/pubsub"
+)
+
+// parseEventFilter builds a subscription filter from the ?status= and ?assigned_to= query params.
+// Subscribers only ever receive the events of their own tenant.
+func parseEventFilter(r *http.Request) (pubsub.Filter, ValidationErrors) {
+	query := r.URL.Query()
+	errs := ValidationErrors{}
+
+	var status *domain.TaskStatus
+	if v := query.Get("status"); v != "" {
+		s := domain.TaskStatus(v)
+		if !s.IsValid() {
+			errs.Add("status", ReasonInvalid)
+		}
+		status = &s
+	}
+
+	var assignedTo *int64
+	if v := query.Get("assigned_to"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			errs.Add("assigned_to", ReasonInvalid)
+		}
+		assignedTo = &id
+	}
+
+	tenantID := pkgcontext.GetTenantID(r.Context())
+	return func(event domain.TaskEvent) bool {
+		if event.TenantID != tenantID {
+			return false
+		}
+		if status != nil && event.Status != *status {
+			return false
+		}
+		if assignedTo != nil && (event.AssignedTo == nil || *event.AssignedTo != *assignedTo) {
+			return false
+		}
+		return true
+	}, errs
+}
+
+// Events handles GET /events and streams task events as Server-Sent Events
+func (h *TaskHandler) Events(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.methodNotAllowed(w, r)
+		return
+	}
+
+	filter, errs := parseEventFilter(r)
+	if errs.HasErrors() {
+		h.respondValidationError(w, r, errs)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// The server write timeout would otherwise cut the stream off
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.Warn("Failed to clear write deadline for event stream: %v", err)
+	}
+
+	requestID := pkgcontext.GetRequestID(r.Context())
+
+	sub := h.broker.Subscribe(filter)
+	defer h.broker.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		h.logger.Error("[%s] Streaming not supported: %v", requestID, err)
+		return
+	}
+
+	h.logger.Info("[%s] Event stream opened", requestID)
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			h.logger.Info("[%s] Event stream closed by client", requestID)
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-sub.Events():
+			if !ok {
+				// Dropped for falling behind, or the broker is shutting down
+				h.logger.Info("[%s] Event stream closed by server", requestID)
+				return
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				h.logger.Error("[%s] Failed to encode event: %v", requestID, err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
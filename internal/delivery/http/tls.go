@@ -0,0 +1,132 @@
+package http
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/seldomhappy/vibe_architecture/logger"
+)
+
+// TLS versions accepted for TLSConfig.MinVersion
+const (
+	TLSVersion12 = "1.2"
+	TLSVersion13 = "1.3"
+)
+
+// TLSConfig enables HTTPS. Leaving CertFile and KeyFile empty serves plaintext.
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// MinVersion is TLSVersion12 or TLSVersion13; empty means TLSVersion12
+	MinVersion string
+}
+
+// Enabled reports whether a certificate is configured
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
+
+// tlsCipherSuites are the TLS 1.2 suites offered: forward secret AEAD only.
+// TLS 1.3 suites are not configurable and are all safe.
+var tlsCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+// newTLSConfig loads the certificate and builds the server's TLS settings
+func newTLSConfig(cfg TLSConfig, log logger.ILogger) (*tls.Config, error) {
+	minVersion := uint16(tls.VersionTLS12)
+	switch cfg.MinVersion {
+	case "", TLSVersion12:
+	case TLSVersion13:
+		minVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported minimum TLS version: %q", cfg.MinVersion)
+	}
+
+	reloader := &certReloader{certFile: cfg.CertFile, keyFile: cfg.KeyFile, logger: log}
+	if err := reloader.load(); err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   tlsCipherSuites,
+		GetCertificate: reloader.GetCertificate,
+	}, nil
+}
+
+// certReloader serves a certificate from disk and picks up a new one as soon as
+// the files change, so rotations (e.g. by cert-manager) need no restart
+type certReloader struct {
+	certFile string
+	keyFile  string
+	logger   logger.ILogger
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time // latest modification time of the two files when last loaded
+}
+
+// GetCertificate implements tls.Config.GetCertificate. A failed reload keeps
+// serving the previous certificate.
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if modTime, err := c.latestModTime(); err == nil && c.changed(modTime) {
+		if err := c.load(); err != nil {
+			c.logger.Error("Failed to reload TLS certificate, keeping the current one: %v", err)
+		}
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+func (c *certReloader) changed(modTime time.Time) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return !modTime.Equal(c.modTime)
+}
+
+func (c *certReloader) load() error {
+	modTime, err := c.latestModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	reloaded := c.cert != nil
+	c.cert = &cert
+	c.modTime = modTime
+	if reloaded {
+		c.logger.Info("Reloaded TLS certificate from %s", c.certFile)
+	}
+	return nil
+}
+
+// latestModTime stats both files; they follow symlinks, as mounted secrets use them
+func (c *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, name := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to stat TLS file: %w", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
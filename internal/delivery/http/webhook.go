@@ -0,0 +1,145 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	"github.com/seldomhappy/vibe_architecture/internal/usecase/webhook"
+)
+
+// CreateWebhookRequest represents a request to subscribe a URL to task events
+type CreateWebhookRequest struct {
+	URL string `json:"url"`
+	// EventTypes are the events delivered; empty means all of them
+	EventTypes []domain.EventType `json:"event_types"`
+	// Secret signs deliveries; a random one is generated when it is empty
+	Secret string `json:"secret"`
+}
+
+// CreateWebhookResponse is a new webhook along with its secret, which is not shown again
+type CreateWebhookResponse struct {
+	*domain.Webhook
+	Secret string `json:"secret"`
+}
+
+// CreateWebhook handles POST /admin/webhooks
+func (h *TaskHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, RoleAdmin) {
+		return
+	}
+
+	var req CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidRequestBody, decodeErrorMessage(err))
+		return
+	}
+
+	errs := ValidationErrors{}
+	if req.URL == "" {
+		errs.Add("url", ReasonRequired)
+	}
+	for _, t := range req.EventTypes {
+		if !t.IsValid() {
+			errs.Add("event_types", ReasonInvalid)
+		}
+	}
+	if errs.HasErrors() {
+		h.respondValidationError(w, r, errs)
+		return
+	}
+
+	created, err := h.webhooks.CreateWebhook(r.Context(), webhook.CreateWebhookInput{
+		URL:        req.URL,
+		EventTypes: req.EventTypes,
+		Secret:     req.Secret,
+	})
+	if err != nil {
+		h.handleUseCaseError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, CreateWebhookResponse{Webhook: created, Secret: created.Secret})
+}
+
+// ListWebhooks handles GET /admin/webhooks
+func (h *TaskHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, RoleAdmin) {
+		return
+	}
+
+	webhooks, err := h.webhooks.ListWebhooks(r.Context())
+	if err != nil {
+		h.handleUseCaseError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, webhooks)
+}
+
+// GetWebhook handles GET /admin/webhooks/{id}
+func (h *TaskHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, RoleAdmin) {
+		return
+	}
+	id, ok := h.webhookIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	found, err := h.webhooks.GetWebhook(r.Context(), id)
+	if err != nil {
+		h.handleUseCaseError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, found)
+}
+
+// DeleteWebhook handles DELETE /admin/webhooks/{id}
+func (h *TaskHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, RoleAdmin) {
+		return
+	}
+	id, ok := h.webhookIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.webhooks.DeleteWebhook(r.Context(), id); err != nil {
+		h.handleUseCaseError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListWebhookDeadLetters handles GET /admin/webhooks/{id}/dead-letters
+func (h *TaskHandler) ListWebhookDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRole(w, r, RoleAdmin) {
+		return
+	}
+	id, ok := h.webhookIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	letters, err := h.webhooks.ListDeadLetters(r.Context(), id)
+	if err != nil {
+		h.handleUseCaseError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, letters)
+}
+
+// webhookIDFromPath parses the webhook ID in the URL path. On failure the error
+// response is written and false is returned.
+func (h *TaskHandler) webhookIDFromPath(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := h.extractSubresourceID(r.URL.Path, "webhooks")
+	if err != nil {
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidInput, "invalid webhook id")
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,220 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	pkgcontext "github.com/seldomhappy/vibe_architecture/internal/pkg/context"
+)
+
+// WebSocket control message actions
+const (
+	wsActionSubscribe   = "subscribe"
+	wsActionUnsubscribe = "unsubscribe"
+)
+
+// wsWriteWait is the time allowed to write a single message to the peer
+const wsWriteWait = 10 * time.Second
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// WSControlMessage is sent by clients to change which events they receive
+type WSControlMessage struct {
+	Action   string              `json:"action"`
+	TaskIDs  []int64             `json:"task_ids,omitempty"`
+	Statuses []domain.TaskStatus `json:"statuses,omitempty"`
+}
+
+// WSMessage is sent to clients: either a task event or a reply to a control message
+type WSMessage struct {
+	Type    string            `json:"type"`
+	Event   *domain.TaskEvent `json:"event,omitempty"`
+	Control *WSControlMessage `json:"control,omitempty"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// wsSelection tracks the task IDs and statuses a connection subscribed to.
+// With nothing selected every event (matching the query filter) is delivered.
+type wsSelection struct {
+	mu       sync.RWMutex
+	taskIDs  map[int64]struct{}
+	statuses map[domain.TaskStatus]struct{}
+}
+
+func newWSSelection() *wsSelection {
+	return &wsSelection{
+		taskIDs:  make(map[int64]struct{}),
+		statuses: make(map[domain.TaskStatus]struct{}),
+	}
+}
+
+func (s *wsSelection) apply(msg WSControlMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range msg.TaskIDs {
+		if msg.Action == wsActionSubscribe {
+			s.taskIDs[id] = struct{}{}
+		} else {
+			delete(s.taskIDs, id)
+		}
+	}
+	for _, status := range msg.Statuses {
+		if msg.Action == wsActionSubscribe {
+			s.statuses[status] = struct{}{}
+		} else {
+			delete(s.statuses, status)
+		}
+	}
+}
+
+func (s *wsSelection) matches(event domain.TaskEvent) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.taskIDs) == 0 && len(s.statuses) == 0 {
+		return true
+	}
+	if _, ok := s.taskIDs[event.TaskID]; ok {
+		return true
+	}
+	_, ok := s.statuses[event.Status]
+	return ok
+}
+
+// WebSocket handles GET /ws and streams the same task events as /events.
+// Clients narrow the stream with subscribe/unsubscribe control messages.
+func (h *TaskHandler) WebSocket(w http.ResponseWriter, r *http.Request) {
+	queryFilter, errs := parseEventFilter(r)
+	if errs.HasErrors() {
+		h.respondValidationError(w, r, errs)
+		return
+	}
+
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already written an error response
+		h.logger.Warn("Failed to upgrade websocket: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	requestID := pkgcontext.GetRequestID(r.Context())
+
+	selection := newWSSelection()
+	sub := h.broker.Subscribe(func(event domain.TaskEvent) bool {
+		if queryFilter != nil && !queryFilter(event) {
+			return false
+		}
+		return selection.matches(event)
+	})
+	defer h.broker.Unsubscribe(sub)
+
+	h.logger.Info("[%s] WebSocket opened", requestID)
+
+	// Only the write loop below writes to conn; the reader hands replies over
+	replies := make(chan WSMessage, 8)
+	quit := make(chan struct{})
+	readDone := make(chan struct{})
+	defer close(quit)
+	go h.readWebSocket(conn, selection, replies, quit, readDone)
+
+	pongWait := 2 * h.heartbeat
+	ping := time.NewTicker(h.heartbeat)
+	defer ping.Stop()
+
+	write := func(msg WSMessage) bool {
+		_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+		return conn.WriteJSON(msg) == nil
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		select {
+		case <-r.Context().Done():
+			h.closeWebSocket(conn, websocket.CloseGoingAway, "")
+			return
+		case <-readDone:
+			h.logger.Info("[%s] WebSocket closed by client", requestID)
+			return
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+		case reply := <-replies:
+			if !write(reply) {
+				return
+			}
+		case event, ok := <-sub.Events():
+			if !ok {
+				// Dropped for falling behind, or the broker is shutting down
+				h.logger.Info("[%s] WebSocket closed by server", requestID)
+				h.closeWebSocket(conn, websocket.CloseGoingAway, "event stream closed")
+				return
+			}
+			if !write(WSMessage{Type: "event", Event: &event}) {
+				return
+			}
+		}
+	}
+}
+
+// readWebSocket processes control messages until the connection fails or is closed
+func (h *TaskHandler) readWebSocket(conn *websocket.Conn, selection *wsSelection, replies chan<- WSMessage, quit <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	reply := func(msg WSMessage) bool {
+		select {
+		case replies <- msg:
+			return true
+		case <-quit:
+			return false
+		}
+	}
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				h.logger.Debug("WebSocket read failed: %v", err)
+			}
+			return
+		}
+
+		var msg WSControlMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			if !reply(WSMessage{Type: "error", Error: "invalid control message"}) {
+				return
+			}
+			continue
+		}
+
+		if msg.Action != wsActionSubscribe && msg.Action != wsActionUnsubscribe {
+			if !reply(WSMessage{Type: "error", Error: "unknown action (allowed: subscribe, unsubscribe)"}) {
+				return
+			}
+			continue
+		}
+
+		selection.apply(msg)
+		if !reply(WSMessage{Type: "ack", Control: &msg}) {
+			return
+		}
+	}
+}
+
+// closeWebSocket sends a close frame to the peer, ignoring errors
+func (h *TaskHandler) closeWebSocket(conn *websocket.Conn, code int, text string) {
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(wsWriteWait))
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// MaxCommentLength is the maximum length of a comment body in characters
+const MaxCommentLength = 2000
+
+// Comment represents a comment left on a task
+type Comment struct {
+	ID        int64     `json:"id"`
+	TaskID    int64     `json:"task_id"`
+	Author    int64     `json:"author"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate validates the comment
+func (c *Comment) Validate() error {
+	if strings.TrimSpace(c.Body) == "" {
+		return ErrEmptyComment
+	}
+	if len([]rune(c.Body)) > MaxCommentLength {
+		return ErrCommentTooLong
+	}
+	return nil
+}
@@ -1,19 +1,80 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 // Domain errors
 var (
 	// Task errors
-	ErrEmptyTaskName    = errors.New("task name cannot be empty")
-	ErrTaskNotFound     = errors.New("task not found")
-	ErrTaskNameTooLong  = errors.New("task name is too long (max 255 characters)")
-	
+	ErrEmptyTaskName         = errors.New("task name cannot be empty")
+	ErrTaskNotFound          = errors.New("task not found")
+	ErrTaskNameTooLong       = errors.New("task name is too long (max 255 characters)")
+	ErrTaskNameInvalid       = errors.New("task name cannot contain control characters")
+	ErrDescriptionTooLong    = errors.New("task description is too long")
+	ErrInvalidRecurrenceRule = errors.New("invalid recurrence rule (allowed: daily, weekly, monthly or FREQ=...;INTERVAL=n)")
+	ErrInvalidTransition     = errors.New("invalid status transition")
+	ErrBatchTooLarge         = errors.New("too many tasks in one batch")
+	ErrTaskAlreadyCompleted  = errors.New("task is already completed")
+	ErrTaskAlreadyCancelled  = errors.New("task is already cancelled")
+	ErrTaskCancelled         = errors.New("cannot complete a cancelled task")
+	ErrTaskNotAssignable     = errors.New("task cannot be assigned in its current status")
+	ErrTaskModified          = errors.New("task has been modified")
+
+	// Dependency errors
+	ErrDependencyCycle        = errors.New("dependency would create a cycle")
+	ErrDependencyNotFound     = errors.New("dependency not found")
+	ErrDependenciesIncomplete = errors.New("task has incomplete dependencies")
+
+	// Subtask errors
+	ErrSubtasksIncomplete = errors.New("task has incomplete subtasks")
+	ErrTaskHasSubtasks    = errors.New("task has subtasks (use cascade=true to delete them)")
+	ErrTaskTreeBlocked    = errors.New("task tree cannot be completed")
+
+	// Comment errors
+	ErrCommentNotFound = errors.New("comment not found")
+	ErrEmptyComment    = errors.New("comment cannot be empty")
+	ErrCommentTooLong  = errors.New("comment is too long (max 2000 characters)")
+
+	// Webhook errors
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrWebhookURLInvalid       = errors.New("webhook url must be an absolute http or https url")
+	ErrWebhookEventTypeInvalid = errors.New("unknown webhook event type")
+
+	// Event errors
+	ErrEventNotPublished = errors.New("event could not be published")
+
 	// User errors
-	ErrUserNotFound     = errors.New("user not found")
-	ErrUnauthorized     = errors.New("unauthorized")
-	
+	ErrUserNotFound = errors.New("user not found")
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// Tenant errors
+	ErrInvalidTenant = errors.New("invalid tenant id (1-64 letters, digits, '.', '_' or '-')")
+
 	// General errors
-	ErrInvalidInput     = errors.New("invalid input")
-	ErrInternal         = errors.New("internal error")
+	ErrInvalidInput      = errors.New("invalid input")
+	ErrConflict          = errors.New("conflicts with an existing record")
+	ErrReferenceNotFound = errors.New("referenced record not found")
+	ErrInternal          = errors.New("internal error")
 )
+
+// TaskBlocker is a task that keeps its tree from being completed, and why
+type TaskBlocker struct {
+	TaskID int64  `json:"task_id"`
+	Reason string `json:"reason"`
+}
+
+// TaskTreeBlockedError lists every task that keeps a task tree from being
+// completed. It matches ErrTaskTreeBlocked with errors.Is.
+type TaskTreeBlockedError struct {
+	Blockers []TaskBlocker
+}
+
+func (e *TaskTreeBlockedError) Error() string {
+	return fmt.Sprintf("%v: %d blocking tasks", ErrTaskTreeBlocked, len(e.Blockers))
+}
+
+func (e *TaskTreeBlockedError) Unwrap() error {
+	return ErrTaskTreeBlocked
+}
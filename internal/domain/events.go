@@ -1,6 +1,10 @@
 package domain
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 // EventType represents the type of domain event
 type EventType string
@@ -10,14 +14,37 @@ const (
 	EventTypeTaskUpdated   EventType = "task.updated"
 	EventTypeTaskCompleted EventType = "task.completed"
 	EventTypeTaskDeleted   EventType = "task.deleted"
+	EventTypeTaskCommented EventType = "task.commented"
+	EventTypeTaskDueSoon   EventType = "task.due_soon"
 )
 
+// EventTypes lists every task event type, in the order they are documented
+var EventTypes = []EventType{
+	EventTypeTaskCreated,
+	EventTypeTaskUpdated,
+	EventTypeTaskCompleted,
+	EventTypeTaskDeleted,
+	EventTypeTaskCommented,
+	EventTypeTaskDueSoon,
+}
+
+// IsValid checks if the event type is one of the task event types
+func (t EventType) IsValid() bool {
+	for _, known := range EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
 // TaskCreatedEvent is published when a task is created
 type TaskCreatedEvent struct {
 	TaskID      int64      `json:"task_id"`
 	Name        string     `json:"name"`
 	Description string     `json:"description"`
 	Priority    Priority   `json:"priority"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
 	CreatedBy   int64      `json:"created_by"`
 	CreatedAt   time.Time  `json:"created_at"`
 }
@@ -30,6 +57,7 @@ type TaskUpdatedEvent struct {
 	Status      TaskStatus `json:"status"`
 	Priority    Priority   `json:"priority"`
 	AssignedTo  *int64     `json:"assigned_to,omitempty"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
@@ -44,3 +72,128 @@ type TaskDeletedEvent struct {
 	TaskID    int64     `json:"task_id"`
 	DeletedAt time.Time `json:"deleted_at"`
 }
+
+// TaskCommentedEvent is published when a comment is added to a task
+type TaskCommentedEvent struct {
+	TaskID    int64     `json:"task_id"`
+	CommentID int64     `json:"comment_id"`
+	Author    int64     `json:"author"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TaskDueSoonEvent is published once when an open task comes within the
+// reminder window of its due date
+type TaskDueSoonEvent struct {
+	TaskID     int64     `json:"task_id"`
+	Name       string    `json:"name"`
+	AssignedTo *int64    `json:"assigned_to,omitempty"`
+	DueDate    time.Time `json:"due_date"`
+	NotifiedAt time.Time `json:"notified_at"`
+}
+
+// Validate checks the fields every task created event carries
+func (e TaskCreatedEvent) Validate() error {
+	switch {
+	case e.TaskID <= 0:
+		return fmt.Errorf("task_id is required")
+	case e.Name == "":
+		return fmt.Errorf("name is required")
+	case e.CreatedAt.IsZero():
+		return fmt.Errorf("created_at is required")
+	}
+	return nil
+}
+
+// Validate checks the fields every task updated event carries
+func (e TaskUpdatedEvent) Validate() error {
+	switch {
+	case e.TaskID <= 0:
+		return fmt.Errorf("task_id is required")
+	case e.Name == "":
+		return fmt.Errorf("name is required")
+	case e.Status == "":
+		return fmt.Errorf("status is required")
+	case e.UpdatedAt.IsZero():
+		return fmt.Errorf("updated_at is required")
+	}
+	return nil
+}
+
+// Validate checks the fields every task completed event carries
+func (e TaskCompletedEvent) Validate() error {
+	switch {
+	case e.TaskID <= 0:
+		return fmt.Errorf("task_id is required")
+	case e.CompletedAt.IsZero():
+		return fmt.Errorf("completed_at is required")
+	}
+	return nil
+}
+
+// Validate checks the fields every task deleted event carries
+func (e TaskDeletedEvent) Validate() error {
+	switch {
+	case e.TaskID <= 0:
+		return fmt.Errorf("task_id is required")
+	case e.DeletedAt.IsZero():
+		return fmt.Errorf("deleted_at is required")
+	}
+	return nil
+}
+
+// Validate checks the fields every task commented event carries
+func (e TaskCommentedEvent) Validate() error {
+	switch {
+	case e.TaskID <= 0:
+		return fmt.Errorf("task_id is required")
+	case e.CommentID <= 0:
+		return fmt.Errorf("comment_id is required")
+	case e.Body == "":
+		return fmt.Errorf("body is required")
+	case e.CreatedAt.IsZero():
+		return fmt.Errorf("created_at is required")
+	}
+	return nil
+}
+
+// Validate checks the fields every task due soon event carries
+func (e TaskDueSoonEvent) Validate() error {
+	switch {
+	case e.TaskID <= 0:
+		return fmt.Errorf("task_id is required")
+	case e.DueDate.IsZero():
+		return fmt.Errorf("due_date is required")
+	case e.NotifiedAt.IsZero():
+		return fmt.Errorf("notified_at is required")
+	}
+	return nil
+}
+
+// TaskEvent is the envelope pushed to live subscribers (SSE, WebSocket).
+// Status and AssignedTo reflect the task after the change and are used for
+// filtering; they're empty for deleted tasks. Task is the full task after the
+// change (before it, for deleted tasks), set only when events include it.
+// ID is unique to the event and stays the same however often it is delivered.
+type TaskEvent struct {
+	ID         string      `json:"id"`
+	Type       EventType   `json:"type"`
+	TaskID     int64       `json:"task_id"`
+	TenantID   string      `json:"tenant_id,omitempty"`
+	Status     TaskStatus  `json:"status,omitempty"`
+	AssignedTo *int64      `json:"assigned_to,omitempty"`
+	Payload    interface{} `json:"payload"`
+	Task       *Task       `json:"task,omitempty"`
+	OccurredAt time.Time   `json:"occurred_at"`
+}
+
+// OutboxEvent is a task event held in the outbox until it reaches Kafka
+type OutboxEvent struct {
+	ID        int64
+	EventType EventType
+	TaskID    int64
+	// Event is the TaskEvent as JSON
+	Event     json.RawMessage
+	RequestID string
+	CreatedAt time.Time
+}
@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence frequencies
+const (
+	RecurrenceDaily   = "daily"
+	RecurrenceWeekly  = "weekly"
+	RecurrenceMonthly = "monthly"
+)
+
+// maxRecurrenceInterval bounds INTERVAL to keep generated dates sane
+const maxRecurrenceInterval = 1000
+
+// ValidateRecurrenceRule checks that a recurrence rule is supported
+func ValidateRecurrenceRule(rule string) error {
+	_, _, err := parseRecurrenceRule(rule)
+	return err
+}
+
+// NextOccurrence returns the next occurrence after from for a recurrence rule.
+// Supported rules are "daily", "weekly", "monthly" and the RRULE subset
+// "FREQ=DAILY|WEEKLY|MONTHLY[;INTERVAL=n]".
+func NextOccurrence(rule string, from time.Time) (time.Time, error) {
+	freq, interval, err := parseRecurrenceRule(rule)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	switch freq {
+	case RecurrenceDaily:
+		return from.AddDate(0, 0, interval), nil
+	case RecurrenceWeekly:
+		return from.AddDate(0, 0, 7*interval), nil
+	default:
+		return from.AddDate(0, interval, 0), nil
+	}
+}
+
+func parseRecurrenceRule(rule string) (string, int, error) {
+	rule = strings.TrimSpace(rule)
+	switch strings.ToLower(rule) {
+	case RecurrenceDaily, RecurrenceWeekly, RecurrenceMonthly:
+		return strings.ToLower(rule), 1, nil
+	}
+
+	freq := ""
+	interval := 1
+	for _, part := range strings.Split(strings.TrimPrefix(rule, "RRULE:"), ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return "", 0, ErrInvalidRecurrenceRule
+		}
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			freq = strings.ToLower(value)
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 || n > maxRecurrenceInterval {
+				return "", 0, ErrInvalidRecurrenceRule
+			}
+			interval = n
+		default:
+			return "", 0, ErrInvalidRecurrenceRule
+		}
+	}
+
+	switch freq {
+	case RecurrenceDaily, RecurrenceWeekly, RecurrenceMonthly:
+		return freq, interval, nil
+	}
+	return "", 0, ErrInvalidRecurrenceRule
+}
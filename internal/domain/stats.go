@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+// TaskStats holds aggregated task counts
+type TaskStats struct {
+	ByStatus    map[TaskStatus]int64 `json:"by_status"`
+	ByPriority  map[Priority]int64   `json:"by_priority"`
+	Overdue     int64                `json:"overdue"`
+	GeneratedAt time.Time            `json:"generated_at"`
+}
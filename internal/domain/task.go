@@ -4,8 +4,29 @@ import (
 	"fmt"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
 )
 
+// DefaultMaxDescriptionLength is the default limit on task descriptions, in characters
+const DefaultMaxDescriptionLength = 10000
+
+// Limits holds the configurable bounds of task fields. Zero fields take their default.
+type Limits struct {
+	// MaxDescriptionLength is the longest description accepted, in characters
+	MaxDescriptionLength int
+}
+
+// DescriptionLength returns the longest description accepted, in characters
+func (l Limits) DescriptionLength() int {
+	if l.MaxDescriptionLength > 0 {
+		return l.MaxDescriptionLength
+	}
+	return DefaultMaxDescriptionLength
+}
+
 // TaskStatus represents the status of a task
 type TaskStatus string
 
@@ -25,27 +46,44 @@ const (
 	PriorityHigh   Priority = "high"
 )
 
-// Task represents a task entity
+// Task represents a task entity.
+// Tasks with a RecurrenceRule regenerate after completion; the generated
+// occurrence points back to its predecessor through ParentTaskID.
+// Subtasks point to the task they belong to through ParentID.
 type Task struct {
-	ID          int64      `json:"id"`
-	Name        string     `json:"name"`
-	Description string     `json:"description"`
-	Status      TaskStatus `json:"status"`
-	Priority    Priority   `json:"priority"`
-	AssignedTo  *int64     `json:"assigned_to,omitempty"`
-	CreatedBy   int64      `json:"created_by"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
-}
-
-// Validate validates the task entity
-func (t *Task) Validate() error {
+	ID             int64      `json:"id"`
+	UUID           uuid.UUID  `json:"uuid"`
+	TenantID       string     `json:"tenant_id"`
+	Name           string     `json:"name"`
+	Description    string     `json:"description"`
+	Status         TaskStatus `json:"status"`
+	Priority       Priority   `json:"priority"`
+	AssignedTo     *int64     `json:"assigned_to,omitempty"`
+	DueDate        *time.Time `json:"due_date,omitempty"`
+	RecurrenceRule *string    `json:"recurrence_rule,omitempty"`
+	ParentTaskID   *int64     `json:"parent_task_id,omitempty"`
+	ParentID       *int64     `json:"parent_id,omitempty"`
+	SubtaskCount   *int       `json:"subtask_count,omitempty"`
+	DependsOn      []int64    `json:"depends_on,omitempty"`
+	CreatedBy      int64      `json:"created_by"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// Validate validates the task entity within limits
+func (t *Task) Validate(limits Limits) error {
 	if strings.TrimSpace(t.Name) == "" {
 		return ErrEmptyTaskName
 	}
 	if len(t.Name) > 255 {
 		return ErrTaskNameTooLong
 	}
+	if strings.IndexFunc(t.Name, unicode.IsControl) >= 0 {
+		return ErrTaskNameInvalid
+	}
+	if limit := limits.DescriptionLength(); utf8.RuneCountInString(t.Description) > limit {
+		return fmt.Errorf("%w (max %d characters)", ErrDescriptionTooLong, limit)
+	}
 	if !t.Status.IsValid() {
 		return ErrInvalidInput
 	}
@@ -55,36 +93,100 @@ func (t *Task) Validate() error {
 	if t.CreatedBy <= 0 {
 		return ErrInvalidInput
 	}
+	if err := ValidateTenantID(t.TenantID); err != nil {
+		return err
+	}
+	if t.RecurrenceRule != nil {
+		if err := ValidateRecurrenceRule(*t.RecurrenceRule); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// IsRecurring returns true if the task has a recurrence rule
+func (t *Task) IsRecurring() bool {
+	return t.RecurrenceRule != nil
+}
+
+// NextOccurrence builds the next instance of a completed recurring task.
+// The due date advances from the previous due date, or from now if there was none.
+func (t *Task) NextOccurrence(now time.Time) (*Task, error) {
+	if !t.IsRecurring() {
+		return nil, ErrInvalidRecurrenceRule
+	}
+
+	base := now
+	if t.DueDate != nil {
+		base = *t.DueDate
+	}
+	due, err := NextOccurrence(*t.RecurrenceRule, base)
+	if err != nil {
+		return nil, err
+	}
+
+	parentID := t.ID
+	rule := *t.RecurrenceRule
+	return &Task{
+		TenantID:       t.TenantID,
+		Name:           t.Name,
+		Description:    t.Description,
+		Status:         TaskStatusPending,
+		Priority:       t.Priority,
+		AssignedTo:     t.AssignedTo,
+		DueDate:        &due,
+		RecurrenceRule: &rule,
+		ParentTaskID:   &parentID,
+		CreatedBy:      t.CreatedBy,
+	}, nil
+}
+
 // IsCompleted returns true if the task is completed
 func (t *Task) IsCompleted() bool {
 	return t.Status == TaskStatusCompleted
 }
 
+// IsTerminal returns true if the task is completed or cancelled
+func (t *Task) IsTerminal() bool {
+	return t.Status == TaskStatusCompleted || t.Status == TaskStatusCancelled
+}
+
+// IsOverdue returns true if the task is still open and its due date has passed
+func (t *Task) IsOverdue(now time.Time) bool {
+	return t.DueDate != nil && !t.IsTerminal() && t.DueDate.Before(now)
+}
+
+// AssignableStatuses are the statuses a task can be assigned in. Assigning a
+// pending task starts it.
+var AssignableStatuses = []TaskStatus{TaskStatusPending, TaskStatusInProgress}
+
 // CanBeAssigned returns true if the task can be assigned to someone
 func (t *Task) CanBeAssigned() bool {
-	return t.Status == TaskStatusPending || t.Status == TaskStatusInProgress
+	for _, status := range AssignableStatuses {
+		if t.Status == status {
+			return true
+		}
+	}
+	return false
 }
 
-// Complete marks the task as completed
-func (t *Task) Complete() error {
+// Complete marks the task as completed at now
+func (t *Task) Complete(now time.Time) error {
 	if t.IsCompleted() {
-		return fmt.Errorf("task is already completed")
+		return ErrTaskAlreadyCompleted
 	}
 	if t.Status == TaskStatusCancelled {
-		return fmt.Errorf("cannot complete a cancelled task")
+		return ErrTaskCancelled
 	}
 	t.Status = TaskStatusCompleted
-	t.UpdatedAt = time.Now()
+	t.UpdatedAt = now
 	return nil
 }
 
-// Assign assigns the task to a user
-func (t *Task) Assign(userID int64) error {
+// Assign assigns the task to a user at now
+func (t *Task) Assign(userID int64, now time.Time) error {
 	if !t.CanBeAssigned() {
-		return fmt.Errorf("task cannot be assigned in its current status: %s", t.Status)
+		return fmt.Errorf("%w: %s", ErrTaskNotAssignable, t.Status)
 	}
 	if userID <= 0 {
 		return ErrUserNotFound
@@ -93,23 +195,44 @@ func (t *Task) Assign(userID int64) error {
 	if t.Status == TaskStatusPending {
 		t.Status = TaskStatusInProgress
 	}
-	t.UpdatedAt = time.Now()
+	t.UpdatedAt = now
 	return nil
 }
 
-// Cancel marks the task as cancelled
-func (t *Task) Cancel() error {
+// Cancel marks the task as cancelled at now
+func (t *Task) Cancel(now time.Time) error {
 	if t.IsCompleted() {
-		return fmt.Errorf("cannot cancel a completed task")
+		return ErrTaskAlreadyCompleted
 	}
 	if t.Status == TaskStatusCancelled {
-		return fmt.Errorf("task is already cancelled")
+		return ErrTaskAlreadyCancelled
 	}
 	t.Status = TaskStatusCancelled
-	t.UpdatedAt = time.Now()
+	t.UpdatedAt = now
+	return nil
+}
+
+// TransitionTo moves the task to the given status at now.
+// Completed and cancelled tasks are final; any other move between valid statuses is allowed.
+func (t *Task) TransitionTo(status TaskStatus, now time.Time) error {
+	if !status.IsValid() || t.IsTerminal() {
+		return ErrInvalidTransition
+	}
+	t.Status = status
+	t.UpdatedAt = now
 	return nil
 }
 
+// TaskStatuses returns all valid task statuses
+func TaskStatuses() []TaskStatus {
+	return []TaskStatus{TaskStatusPending, TaskStatusInProgress, TaskStatusCompleted, TaskStatusCancelled}
+}
+
+// Priorities returns all valid priorities
+func Priorities() []Priority {
+	return []Priority{PriorityLow, PriorityMedium, PriorityHigh}
+}
+
 // IsValid returns true if the status is valid
 func (s TaskStatus) IsValid() bool {
 	switch s {
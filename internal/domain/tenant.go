@@ -0,0 +1,22 @@
+package domain
+
+// MaxTenantIDLength is the longest tenant ID accepted
+const MaxTenantIDLength = 64
+
+// ValidateTenantID checks that a tenant ID is 1 to MaxTenantIDLength letters,
+// digits, dots, underscores or hyphens
+func ValidateTenantID(tenantID string) error {
+	if tenantID == "" || len(tenantID) > MaxTenantIDLength {
+		return ErrInvalidTenant
+	}
+	for i := 0; i < len(tenantID); i++ {
+		c := tenantID[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '.', c == '_', c == '-':
+		default:
+			return ErrInvalidTenant
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"encoding/json"
+	"net/url"
+	"time"
+)
+
+// Webhook is a subscription that has task events POSTed to a URL
+type Webhook struct {
+	ID int64 `json:"id"`
+	// TenantID is the tenant whose events the webhook receives
+	TenantID string `json:"tenant_id"`
+	URL      string `json:"url"`
+	// EventTypes are the events delivered; empty means all of them
+	EventTypes []EventType `json:"event_types"`
+	// Secret signs deliveries. It is only shown when the webhook is created.
+	Secret    string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate validates the webhook
+func (w *Webhook) Validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrWebhookURLInvalid
+	}
+	if err := ValidateTenantID(w.TenantID); err != nil {
+		return err
+	}
+	for _, t := range w.EventTypes {
+		if !t.IsValid() {
+			return ErrWebhookEventTypeInvalid
+		}
+	}
+	return nil
+}
+
+// Accepts reports whether events of type t are delivered to the webhook
+func (w *Webhook) Accepts(t EventType) bool {
+	if len(w.EventTypes) == 0 {
+		return true
+	}
+	for _, accepted := range w.EventTypes {
+		if accepted == t {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDeadLetter is an event that could not be delivered to a webhook
+type WebhookDeadLetter struct {
+	ID        int64           `json:"id"`
+	WebhookID int64           `json:"webhook_id"`
+	TenantID  string          `json:"tenant_id"`
+	EventType EventType       `json:"event_type"`
+	TaskID    int64           `json:"task_id"`
+	Payload   json.RawMessage `json:"payload"`
+	Attempts  int             `json:"attempts"`
+	LastError string          `json:"last_error"`
+	FailedAt  time.Time       `json:"failed_at"`
+}
@@ -0,0 +1,139 @@
+package kafka
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/metrics"
+	"github.com/seldomhappy/vibe_architecture/logger"
+)
+
+// ErrCircuitOpen is returned instead of publishing while the producer's circuit breaker is open
+var ErrCircuitOpen = errors.New("kafka circuit breaker is open")
+
+// Circuit breaker states, also exported as the kafka_producer_circuit_state gauge
+const (
+	CircuitClosed   = "closed"
+	CircuitHalfOpen = "half_open"
+	CircuitOpen     = "open"
+)
+
+// circuitStateValues are the gauge values of the circuit breaker states
+var circuitStateValues = map[string]float64{
+	CircuitClosed:   0,
+	CircuitHalfOpen: 1,
+	CircuitOpen:     2,
+}
+
+// BreakerConfig holds the producer's circuit breaker settings
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive failed sends that opens the
+	// circuit; 0 disables the breaker
+	FailureThreshold int
+	// OpenTimeout is how long the circuit stays open before a send is let
+	// through to probe whether the broker has recovered
+	OpenTimeout time.Duration
+}
+
+// circuitBreaker stops the producer from sending while the broker keeps failing,
+// so requests that publish events don't each wait out the send timeout. Once
+// OpenTimeout has passed, one send is let through (half open): success closes
+// the circuit again, failure keeps it open for another OpenTimeout.
+type circuitBreaker struct {
+	cfg     BreakerConfig
+	logger  logger.ILogger
+	metrics *metrics.Metrics
+
+	mu       sync.Mutex
+	state    string
+	failures int       // consecutive failures while closed
+	openedAt time.Time // when the circuit last opened
+	probing  bool      // a half-open probe send is in flight
+}
+
+func newCircuitBreaker(cfg BreakerConfig, log logger.ILogger, m *metrics.Metrics) *circuitBreaker {
+	b := &circuitBreaker{cfg: cfg, logger: log, metrics: m, state: CircuitClosed}
+	m.SetKafkaCircuitState(circuitStateValues[CircuitClosed])
+	return b
+}
+
+// allow reports whether a send may go ahead. Every allowed send must be
+// followed by a call to done with its result.
+func (b *circuitBreaker) allow() bool {
+	if b.cfg.FailureThreshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.cfg.OpenTimeout {
+			return false
+		}
+		b.setState(CircuitHalfOpen)
+		b.probing = true
+		return true
+	case CircuitHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// done records the result of a send allowed by allow
+func (b *circuitBreaker) done(err error) {
+	if b.cfg.FailureThreshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case CircuitHalfOpen:
+		b.probing = false
+		if err != nil {
+			b.open()
+			b.logger.Warn("Kafka circuit breaker probe failed, staying open for %v: %v", b.cfg.OpenTimeout, err)
+			return
+		}
+		b.failures = 0
+		b.setState(CircuitClosed)
+		b.logger.Info("Kafka circuit breaker closed: broker recovered")
+	case CircuitClosed:
+		if err == nil {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.cfg.FailureThreshold {
+			b.open()
+			b.logger.Warn("Kafka circuit breaker opened after %d consecutive failures, dropping events for %v",
+				b.failures, b.cfg.OpenTimeout)
+		}
+	}
+}
+
+// open must be called with mu held
+func (b *circuitBreaker) open() {
+	b.openedAt = time.Now()
+	b.setState(CircuitOpen)
+}
+
+// setState must be called with mu held
+func (b *circuitBreaker) setState(state string) {
+	b.state = state
+	b.metrics.SetKafkaCircuitState(circuitStateValues[state])
+}
+
+// current returns the circuit breaker state
+func (b *circuitBreaker) current() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
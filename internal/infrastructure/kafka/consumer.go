@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/IBM/sarama"
 	"github.com/seldomhappy/vibe_architecture/logger"
@@ -29,14 +30,46 @@ type ConsumerConfig struct {
 	Workers          int
 	SessionTimeout   string
 	RebalanceTimeout string
+	// OffsetInitial is where a group with no committed offset starts: oldest or newest
+	OffsetInitial string
+	// Version is the Kafka protocol version, DefaultVersion when empty
+	Version  string
+	Security SecurityConfig
 }
 
 // NewConsumer creates a new Kafka consumer
 func NewConsumer(cfg ConsumerConfig, handler *TaskEventHandler, log logger.ILogger) (*Consumer, error) {
+	version, err := parseVersion(cfg.Version)
+	if err != nil {
+		return nil, err
+	}
 	config := sarama.NewConfig()
-	config.Version = sarama.V2_6_0_0
+	config.Version = version
 	config.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRoundRobin
-	config.Consumer.Offsets.Initial = sarama.OffsetNewest
+	initial, err := ParseOffsetInitial(cfg.OffsetInitial)
+	if err != nil {
+		return nil, err
+	}
+	config.Consumer.Offsets.Initial = initial
+	if err := cfg.Security.apply(config); err != nil {
+		return nil, err
+	}
+
+	// The rebalance timeout bounds how long Cleanup may spend finishing in-flight messages
+	if cfg.SessionTimeout != "" {
+		timeout, err := time.ParseDuration(cfg.SessionTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid consumer session timeout: %w", err)
+		}
+		config.Consumer.Group.Session.Timeout = timeout
+	}
+	if cfg.RebalanceTimeout != "" {
+		timeout, err := time.ParseDuration(cfg.RebalanceTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid consumer rebalance timeout: %w", err)
+		}
+		config.Consumer.Group.Rebalance.Timeout = timeout
+	}
 
 	consumerGroup, err := sarama.NewConsumerGroup(cfg.Brokers, cfg.GroupID, config)
 	if err != nil {
@@ -103,7 +136,7 @@ func (h consumerGroupHandler) Cleanup(sarama.ConsumerGroupSession) error {
 // ConsumeClaim must start a consumer loop of ConsumerGroupClaim's Messages()
 func (h consumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
 	for message := range claim.Messages() {
-		h.handler.HandleMessage(session.Context(), message)
+		_ = h.handler.HandleMessage(session.Context(), message)
 		session.MarkMessage(message, "")
 	}
 	return nil
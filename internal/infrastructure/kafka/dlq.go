@@ -0,0 +1,283 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	pkgcontext "github.com/seldomhappy/vibe_architecture/internal/pkg/context"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/metrics"
+	"github.com/seldomhappy/vibe_architecture/logger"
+)
+
+// Headers added to messages sent to the dead letter queue.
+// All of them start with dlqHeaderPrefix and are stripped again on replay.
+const (
+	dlqHeaderPrefix         = "dlq."
+	HeaderOriginalTopic     = "dlq.original_topic"
+	HeaderOriginalPartition = "dlq.original_partition"
+	HeaderOriginalOffset    = "dlq.original_offset"
+	HeaderError             = "dlq.error"
+	HeaderFailedAt          = "dlq.failed_at"
+)
+
+// Actions recorded in the dlq_messages_total metric
+const (
+	dlqActionSent     = "sent"
+	dlqActionReplayed = "replayed"
+	dlqActionSkipped  = "skipped"
+	dlqActionDryRun   = "dry_run"
+)
+
+const (
+	// dlqReplayFetchTimeout bounds the wait for a message known to exist
+	dlqReplayFetchTimeout = 5 * time.Second
+	// dlqReplayGroupIDSuffix names the group that tracks replay progress
+	dlqReplayGroupIDSuffix = "-dlq-replay"
+)
+
+// DLQConfig holds dead letter queue configuration
+type DLQConfig struct {
+	Brokers []string
+	Topic   string
+	// GroupID is the consumer group whose replay progress is tracked; replayed
+	// messages are committed so they aren't replayed twice
+	GroupID string
+	// Version is the Kafka protocol version, DefaultVersion when empty
+	Version  string
+	Security SecurityConfig
+}
+
+// ReplayResult reports what a replay did
+type ReplayResult struct {
+	Replayed int  `json:"replayed"`
+	Skipped  int  `json:"skipped"`
+	DryRun   bool `json:"dry_run"`
+}
+
+// DeadLetterQueue parks messages that failed processing and replays them on demand
+type DeadLetterQueue struct {
+	client   sarama.Client
+	producer sarama.SyncProducer
+	topic    string
+	groupID  string
+	metrics  *metrics.Metrics
+	logger   logger.ILogger
+
+	// replayMu serializes replays so two callers can't republish the same messages
+	replayMu sync.Mutex
+}
+
+// NewDeadLetterQueue creates a dead letter queue that writes through producer
+func NewDeadLetterQueue(cfg DLQConfig, producer *Producer, m *metrics.Metrics, log logger.ILogger) (*DeadLetterQueue, error) {
+	version, err := parseVersion(cfg.Version)
+	if err != nil {
+		return nil, err
+	}
+	config := sarama.NewConfig()
+	config.Version = version
+	config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	if err := cfg.Security.apply(config); err != nil {
+		return nil, err
+	}
+
+	client, err := sarama.NewClient(cfg.Brokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dlq client: %w", err)
+	}
+
+	return &DeadLetterQueue{
+		client:   client,
+		producer: producer.producer,
+		topic:    cfg.Topic,
+		groupID:  cfg.GroupID + dlqReplayGroupIDSuffix,
+		metrics:  m,
+		logger:   log,
+	}, nil
+}
+
+// Start implements lifecycle.Service
+func (q *DeadLetterQueue) Start(ctx context.Context) error {
+	q.logger.Info("Dead letter queue ready on topic: %s", q.topic)
+	return nil
+}
+
+// Shutdown closes the dead letter queue client
+func (q *DeadLetterQueue) Shutdown(ctx context.Context) error {
+	q.logger.Info("Shutting down dead letter queue")
+	q.replayMu.Lock()
+	defer q.replayMu.Unlock()
+	return q.client.Close()
+}
+
+// Send parks a message that failed processing, recording where it came from and why it failed
+func (q *DeadLetterQueue) Send(ctx context.Context, message *sarama.ConsumerMessage, cause error) error {
+	headers := make([]sarama.RecordHeader, 0, len(message.Headers)+5)
+	for _, h := range message.Headers {
+		headers = append(headers, *h)
+	}
+	headers = append(headers,
+		sarama.RecordHeader{Key: []byte(HeaderOriginalTopic), Value: []byte(message.Topic)},
+		sarama.RecordHeader{Key: []byte(HeaderOriginalPartition), Value: []byte(fmt.Sprint(message.Partition))},
+		sarama.RecordHeader{Key: []byte(HeaderOriginalOffset), Value: []byte(fmt.Sprint(message.Offset))},
+		sarama.RecordHeader{Key: []byte(HeaderError), Value: []byte(cause.Error())},
+		sarama.RecordHeader{Key: []byte(HeaderFailedAt), Value: []byte(time.Now().UTC().Format(time.RFC3339))},
+	)
+
+	_, _, err := q.producer.SendMessage(&sarama.ProducerMessage{
+		Topic:   q.topic,
+		Key:     keyOf(message),
+		Value:   sarama.ByteEncoder(message.Value),
+		Headers: headers,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send message to dlq: %w", err)
+	}
+
+	q.metrics.RecordDLQMessage(dlqActionSent)
+	q.logger.Warn("[trace:%s] Message %s/%d/%d sent to dlq: %v",
+		pkgcontext.GetTraceID(ctx), message.Topic, message.Partition, message.Offset, cause)
+	return nil
+}
+
+// Replay republishes up to max parked messages to the topic they came from,
+// without the dlq headers. Messages with no original topic are skipped.
+// A dry run counts what would be replayed without publishing or committing anything.
+func (q *DeadLetterQueue) Replay(ctx context.Context, max int, dryRun bool) (ReplayResult, error) {
+	q.replayMu.Lock()
+	defer q.replayMu.Unlock()
+
+	result := ReplayResult{DryRun: dryRun}
+
+	offsets, err := sarama.NewOffsetManagerFromClient(q.groupID, q.client)
+	if err != nil {
+		return result, fmt.Errorf("failed to create dlq offset manager: %w", err)
+	}
+	defer offsets.Close()
+
+	consumer, err := sarama.NewConsumerFromClient(q.client)
+	if err != nil {
+		return result, fmt.Errorf("failed to create dlq consumer: %w", err)
+	}
+	defer consumer.Close()
+
+	partitions, err := q.client.Partitions(q.topic)
+	if err != nil {
+		return result, fmt.Errorf("failed to list dlq partitions: %w", err)
+	}
+
+	for _, partition := range partitions {
+		if result.Replayed+result.Skipped >= max {
+			break
+		}
+		if err := q.replayPartition(ctx, offsets, consumer, partition, max, &result); err != nil {
+			return result, err
+		}
+	}
+
+	if !dryRun {
+		offsets.Commit()
+	}
+
+	q.logger.Info("DLQ replay finished: replayed=%d skipped=%d dry_run=%t", result.Replayed, result.Skipped, dryRun)
+	return result, nil
+}
+
+// replayPartition replays one partition from the last committed offset up to the
+// offset that was newest when it started
+func (q *DeadLetterQueue) replayPartition(ctx context.Context, offsets sarama.OffsetManager, consumer sarama.Consumer, partition int32, max int, result *ReplayResult) error {
+	pom, err := offsets.ManagePartition(q.topic, partition)
+	if err != nil {
+		return fmt.Errorf("failed to manage dlq partition %d: %w", partition, err)
+	}
+	defer pom.Close()
+
+	next, _ := pom.NextOffset()
+	if next < 0 {
+		if next, err = q.client.GetOffset(q.topic, partition, sarama.OffsetOldest); err != nil {
+			return fmt.Errorf("failed to get oldest dlq offset: %w", err)
+		}
+	}
+	end, err := q.client.GetOffset(q.topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return fmt.Errorf("failed to get newest dlq offset: %w", err)
+	}
+	if next >= end {
+		return nil
+	}
+
+	pc, err := consumer.ConsumePartition(q.topic, partition, next)
+	if err != nil {
+		return fmt.Errorf("failed to consume dlq partition %d: %w", partition, err)
+	}
+	defer pc.Close()
+
+	for next < end && result.Replayed+result.Skipped < max {
+		var message *sarama.ConsumerMessage
+		select {
+		case message = <-pc.Messages():
+		case err := <-pc.Errors():
+			return fmt.Errorf("failed to read dlq partition %d: %w", partition, err)
+		case <-time.After(dlqReplayFetchTimeout):
+			return fmt.Errorf("timed out reading dlq partition %d at offset %d", partition, next)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		if err := q.replayMessage(message, result); err != nil {
+			return err
+		}
+		next = message.Offset + 1
+		if !result.DryRun {
+			pom.MarkOffset(next, "")
+		}
+	}
+
+	return nil
+}
+
+// replayMessage republishes a single message to its original topic. Retry headers
+// are dropped too, so the message gets a fresh round of retries.
+func (q *DeadLetterQueue) replayMessage(message *sarama.ConsumerMessage, result *ReplayResult) error {
+	var topic string
+	headers := make([]sarama.RecordHeader, 0, len(message.Headers))
+	for _, h := range message.Headers {
+		key := string(h.Key)
+		if key == HeaderOriginalTopic {
+			topic = string(h.Value)
+		}
+		if !strings.HasPrefix(key, dlqHeaderPrefix) && !strings.HasPrefix(key, retryHeaderPrefix) {
+			headers = append(headers, *h)
+		}
+	}
+
+	if topic == "" {
+		q.logger.Warn("Skipping dlq message at %d/%d: no original topic", message.Partition, message.Offset)
+		result.Skipped++
+		q.metrics.RecordDLQMessage(dlqActionSkipped)
+		return nil
+	}
+
+	if result.DryRun {
+		result.Replayed++
+		q.metrics.RecordDLQMessage(dlqActionDryRun)
+		return nil
+	}
+
+	_, _, err := q.producer.SendMessage(&sarama.ProducerMessage{
+		Topic:   topic,
+		Key:     keyOf(message),
+		Value:   sarama.ByteEncoder(message.Value),
+		Headers: headers,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to replay dlq message at %d/%d: %w", message.Partition, message.Offset, err)
+	}
+
+	result.Replayed++
+	q.metrics.RecordDLQMessage(dlqActionReplayed)
+	return nil
+}
@@ -0,0 +1,87 @@
+package kafka
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/google/uuid"
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+)
+
+// ErrMalformedEvent marks a message that can never be processed, however often
+// it is retried. Such messages go straight to the dead letter queue.
+var ErrMalformedEvent = errors.New("malformed event")
+
+// Envelope is the wire format of a task event: its type and a typed payload.
+// Task is a snapshot of the whole task, present only when events include it.
+// EventID identifies the event, so a consumer can tell a redelivery from a new event.
+type Envelope[T any] struct {
+	EventID   string           `json:"event_id"`
+	EventType domain.EventType `json:"event_type"`
+	Payload   T                `json:"payload"`
+	Task      *domain.Task     `json:"task,omitempty"`
+	Timestamp time.Time        `json:"timestamp"`
+}
+
+// eventPayload is implemented by the domain events carried in an envelope
+type eventPayload interface {
+	Validate() error
+}
+
+// newEnvelope wraps payload, and the task snapshot if any, for publishing.
+// An event without an ID gets a new one.
+func newEnvelope[T any](eventID string, eventType domain.EventType, payload T, task *domain.Task) Envelope[T] {
+	if eventID == "" {
+		eventID = uuid.NewString()
+	}
+	return Envelope[T]{
+		EventID:   eventID,
+		EventType: eventType,
+		Payload:   payload,
+		Task:      task,
+		Timestamp: time.Now(),
+	}
+}
+
+// envelopeHeader is the part of an envelope read before its payload
+type envelopeHeader struct {
+	EventID   string           `json:"event_id"`
+	EventType domain.EventType `json:"event_type"`
+}
+
+// peekHeader reads just the event ID and type of a message, so the payload can
+// be decoded into the matching type. Messages from older producers have no ID.
+func peekHeader(message *sarama.ConsumerMessage) (envelopeHeader, error) {
+	var header envelopeHeader
+	if err := json.Unmarshal(message.Value, &header); err != nil {
+		return header, fmt.Errorf("%w: invalid json: %v", ErrMalformedEvent, err)
+	}
+	if header.EventType == "" {
+		return header, fmt.Errorf("%w: event_type is missing", ErrMalformedEvent)
+	}
+	return header, nil
+}
+
+// decodePayload decodes the T payload of an eventType message and validates it
+func decodePayload[T eventPayload](eventType domain.EventType, message *sarama.ConsumerMessage) (T, error) {
+	var envelope Envelope[T]
+	if err := json.Unmarshal(message.Value, &envelope); err != nil {
+		return envelope.Payload, fmt.Errorf("%w: invalid %s payload: %v", ErrMalformedEvent, eventType, err)
+	}
+	if err := envelope.Payload.Validate(); err != nil {
+		return envelope.Payload, fmt.Errorf("%w: invalid %s payload: %v", ErrMalformedEvent, eventType, err)
+	}
+	return envelope.Payload, nil
+}
+
+// keyOf returns the key to forward a consumed message with. Unkeyed messages,
+// sent under KeyStrategyNone, stay unkeyed so they keep spreading over partitions.
+func keyOf(message *sarama.ConsumerMessage) sarama.Encoder {
+	if message.Key == nil {
+		return nil
+	}
+	return sarama.ByteEncoder(message.Key)
+}
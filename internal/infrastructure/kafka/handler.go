@@ -2,8 +2,12 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/IBM/sarama"
 	"github.com/seldomhappy/vibe_architecture/internal/domain"
@@ -11,144 +15,259 @@ import (
 	"github.com/seldomhappy/vibe_architecture/internal/pkg/tracing"
 	"github.com/seldomhappy/vibe_architecture/logger"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // TaskEventHandler handles task events from Kafka
 type TaskEventHandler struct {
-	logger logger.ILogger
+	retrier   *Retrier
+	processed ProcessedEvents
+	logger    logger.ILogger
+
+	// inflight counts messages being handled, so Cleanup can wait for them
+	// before the session's partitions are handed to another member
+	inflight sync.WaitGroup
 }
 
-// NewTaskEventHandler creates a new task event handler
-func NewTaskEventHandler(log logger.ILogger) *TaskEventHandler {
+// NewTaskEventHandler creates a new task event handler.
+// Messages that can't be processed are handed to retrier; events already in
+// processed are skipped.
+func NewTaskEventHandler(retrier *Retrier, processed ProcessedEvents, log logger.ILogger) *TaskEventHandler {
 	return &TaskEventHandler{
-		logger: log,
+		retrier:   retrier,
+		processed: processed,
+		logger:    log,
 	}
 }
 
-// Setup implements sarama.ConsumerGroupHandler
-func (h *TaskEventHandler) Setup(sarama.ConsumerGroupSession) error {
+// Setup implements sarama.ConsumerGroupHandler. It runs once a rebalance has
+// assigned this member its partitions.
+func (h *TaskEventHandler) Setup(session sarama.ConsumerGroupSession) error {
+	h.logger.Info("Kafka rebalance complete: generation=%d member=%s partitions=%s",
+		session.GenerationID(), session.MemberID(), formatClaims(session.Claims()))
 	return nil
 }
 
-// Cleanup implements sarama.ConsumerGroupHandler
-func (h *TaskEventHandler) Cleanup(sarama.ConsumerGroupSession) error {
+// Cleanup implements sarama.ConsumerGroupHandler. It runs when a rebalance
+// starts or the consumer stops; it waits for in-flight messages and commits
+// their offsets so the next owner of the partitions doesn't process them again.
+func (h *TaskEventHandler) Cleanup(session sarama.ConsumerGroupSession) error {
+	h.logger.Info("Kafka rebalance started: generation=%d member=%s releasing partitions=%s",
+		session.GenerationID(), session.MemberID(), formatClaims(session.Claims()))
+
+	h.inflight.Wait()
+	session.Commit()
+
+	h.logger.Info("Kafka partitions released: generation=%d member=%s offsets committed",
+		session.GenerationID(), session.MemberID())
 	return nil
 }
 
 // ConsumeClaim implements sarama.ConsumerGroupHandler
 func (h *TaskEventHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
-	for message := range claim.Messages() {
-		h.HandleMessage(session.Context(), message)
-		session.MarkMessage(message, "")
+	for {
+		select {
+		case message, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			if !h.process(session, message) {
+				return nil
+			}
+		case <-session.Context().Done():
+			// Rebalance or shutdown; stop taking new messages
+			return nil
+		}
 	}
-	return nil
 }
 
-// HandleMessage handles a single Kafka message
-func (h *TaskEventHandler) HandleMessage(ctx context.Context, message *sarama.ConsumerMessage) {
-	// Extract trace_id from headers to continue the trace
-	var traceID string
-	for _, header := range message.Headers {
-		if string(header.Key) == "trace_id" {
-			traceID = string(header.Value)
-			break
-		}
+// process handles a message and marks it once it is fully handled. It reports
+// false if the session ended before the message was due, or if a failed message
+// couldn't be handed to a retry tier or the dlq. The message is then left
+// unmarked, and the claim ends, so it is delivered again rather than lost.
+func (h *TaskEventHandler) process(session sarama.ConsumerGroupSession, message *sarama.ConsumerMessage) bool {
+	h.inflight.Add(1)
+	defer h.inflight.Done()
+
+	// Retry topics hold messages in the order they became due, so waiting
+	// for this one never holds back a message that is already due
+	if err := WaitUntilDue(session.Context(), message); err != nil {
+		return false
 	}
 
-	// Start a new span for message processing
-	ctx, span := tracing.StartSpan(ctx, "kafka-consumer", "process_message")
-	defer span.End()
+	// A message that has started is finished even if a rebalance starts meanwhile,
+	// so it is marked here rather than handled a second time by the next owner
+	ctx := messageContext(context.WithoutCancel(session.Context()), message)
+	if err := h.HandleMessage(ctx, message); err != nil {
+		// Retry later and move on so one bad message can't block the partition.
+		// A malformed message would fail every retry, so it is parked straight away.
+		if errors.Is(err, ErrMalformedEvent) {
+			if dlqErr := h.retrier.Park(ctx, message, err); dlqErr != nil {
+				h.logger.Error("Failed to park message %s/%d/%d, it will be redelivered: %v",
+					message.Topic, message.Partition, message.Offset, dlqErr)
+				return false
+			}
+		} else if retryErr := h.retrier.Retry(ctx, message, err); retryErr != nil {
+			h.logger.Error("Failed to schedule retry for message %s/%d/%d, it will be redelivered: %v",
+				message.Topic, message.Partition, message.Offset, retryErr)
+			return false
+		}
+	}
+	session.MarkMessage(message, "")
+	return true
+}
 
-	if traceID != "" {
-		span.SetAttributes(attribute.String("trace_id", traceID))
+// formatClaims renders a session's claims as "topic[0 1 2]" for logs
+func formatClaims(claims map[string][]int32) string {
+	parts := make([]string, 0, len(claims))
+	for topic, partitions := range claims {
+		parts = append(parts, fmt.Sprintf("%s%v", topic, partitions))
 	}
+	sort.Strings(parts)
+	return strings.Join(parts, " ")
+}
+
+// HandleMessage decodes a Kafka message into its typed event and handles it.
+// An error means the message wasn't processed; ErrMalformedEvent means it never will be.
+func (h *TaskEventHandler) HandleMessage(ctx context.Context, message *sarama.ConsumerMessage) error {
+	// Continue the request that published the event, so its logs and spans correlate
+	ctx = messageContext(ctx, message)
+	ctx, span := tracing.StartSpan(ctx, "kafka-consumer", "process_message", trace.WithSpanKind(trace.SpanKindConsumer))
+	defer span.End()
 
+	requestID, traceID := pkgcontext.GetRequestID(ctx), pkgcontext.GetTraceID(ctx)
 	span.SetAttributes(
 		attribute.String("kafka.topic", message.Topic),
 		attribute.Int64("kafka.partition", int64(message.Partition)),
 		attribute.Int64("kafka.offset", message.Offset),
 	)
 
-	var event map[string]interface{}
-	if err := json.Unmarshal(message.Value, &event); err != nil {
-		h.logger.Error("[trace:%s] Failed to unmarshal message: %v", traceID, err)
-		return
+	header, err := peekHeader(message)
+	if err != nil {
+		h.logger.Error("[%s][trace:%s] Failed to decode message: %v", requestID, traceID, err)
+		return err
 	}
+	eventType := header.EventType
+	span.SetAttributes(attribute.String("event.id", header.EventID))
 
-	eventType, ok := event["event_type"].(string)
-	if !ok {
-		h.logger.Error("[trace:%s] Event type not found in message", traceID)
-		return
+	// Messages from older producers carry no event ID and can't be deduplicated
+	if header.EventID != "" {
+		claimed, err := h.processed.Claim(ctx, header.EventID)
+		if err != nil {
+			h.logger.Error("[%s][trace:%s] Failed to claim event %s: %v", requestID, traceID, header.EventID, err)
+			return fmt.Errorf("failed to claim event %s: %w", header.EventID, err)
+		}
+		if !claimed {
+			h.logger.Info("[%s][trace:%s] Skipping %s event %s, already processed", requestID, traceID, eventType, header.EventID)
+			return nil
+		}
 	}
 
-	h.logger.Info("[trace:%s] Processing event: %s", traceID, eventType)
+	h.logger.Info("[%s][trace:%s] Processing event: %s", requestID, traceID, eventType)
 
-	switch domain.EventType(eventType) {
+	switch eventType {
 	case domain.EventTypeTaskCreated:
-		h.handleTaskCreated(ctx, event)
+		err = handleTyped(ctx, eventType, message, h.HandleTaskCreated)
 	case domain.EventTypeTaskUpdated:
-		h.handleTaskUpdated(ctx, event)
+		err = handleTyped(ctx, eventType, message, h.HandleTaskUpdated)
 	case domain.EventTypeTaskCompleted:
-		h.handleTaskCompleted(ctx, event)
+		err = handleTyped(ctx, eventType, message, h.HandleTaskCompleted)
 	case domain.EventTypeTaskDeleted:
-		h.handleTaskDeleted(ctx, event)
+		err = handleTyped(ctx, eventType, message, h.HandleTaskDeleted)
+	case domain.EventTypeTaskCommented:
+		err = handleTyped(ctx, eventType, message, h.HandleTaskCommented)
+	case domain.EventTypeTaskDueSoon:
+		err = handleTyped(ctx, eventType, message, h.HandleTaskDueSoon)
 	default:
-		h.logger.Warn("[trace:%s] Unknown event type: %s", traceID, eventType)
+		h.logger.Warn("[%s][trace:%s] Unknown event type: %s", requestID, traceID, eventType)
 	}
+	if errors.Is(err, ErrMalformedEvent) {
+		h.logger.Error("[%s][trace:%s] Failed to decode message: %v", requestID, traceID, err)
+	}
+	if err != nil && header.EventID != "" {
+		// The event wasn't processed after all; its retry or replay must be
+		if releaseErr := h.processed.Release(ctx, header.EventID); releaseErr != nil {
+			h.logger.Error("[%s][trace:%s] Failed to release event %s: %v", requestID, traceID, header.EventID, releaseErr)
+		}
+	}
+	return err
 }
 
-func (h *TaskEventHandler) handleTaskCreated(ctx context.Context, event map[string]interface{}) {
-	traceID := pkgcontext.GetTraceID(ctx)
-	h.logger.Info("[trace:%s] Task created event received: %+v", traceID, event["payload"])
-	// Add business logic here (e.g., send notification, update cache, etc.)
-}
-
-func (h *TaskEventHandler) handleTaskUpdated(ctx context.Context, event map[string]interface{}) {
-	traceID := pkgcontext.GetTraceID(ctx)
-	h.logger.Info("[trace:%s] Task updated event received: %+v", traceID, event["payload"])
-	// Add business logic here
-}
+// messageContext restores the request ID, baggage and trace of the request
+// that published message. Without a span ID, as on messages from older
+// producers, the trace isn't continued.
+func messageContext(ctx context.Context, message *sarama.ConsumerMessage) context.Context {
+	if requestID := headerValue(message, HeaderRequestID); requestID != "" {
+		ctx = pkgcontext.WithRequestID(ctx, requestID)
+	}
+	ctx = tracing.DecodeBaggage(ctx, headerValue(message, HeaderBaggage))
 
-func (h *TaskEventHandler) handleTaskCompleted(ctx context.Context, event map[string]interface{}) {
-	traceID := pkgcontext.GetTraceID(ctx)
-	h.logger.Info("[trace:%s] Task completed event received: %+v", traceID, event["payload"])
-	// Add business logic here (e.g., send completion notification)
+	traceID, err := trace.TraceIDFromHex(headerValue(message, HeaderTraceID))
+	if err != nil {
+		return ctx
+	}
+	spanID, err := trace.SpanIDFromHex(headerValue(message, HeaderSpanID))
+	if err != nil {
+		return ctx
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Remote:  true,
+	}))
 }
 
-func (h *TaskEventHandler) handleTaskDeleted(ctx context.Context, event map[string]interface{}) {
-	traceID := pkgcontext.GetTraceID(ctx)
-	h.logger.Info("[trace:%s] Task deleted event received: %+v", traceID, event["payload"])
-	// Add business logic here
+// handleTyped decodes the payload of message as T and passes it to handle
+func handleTyped[T eventPayload](ctx context.Context, eventType domain.EventType, message *sarama.ConsumerMessage, handle func(context.Context, T) error) error {
+	payload, err := decodePayload[T](eventType, message)
+	if err != nil {
+		return err
+	}
+	return handle(ctx, payload)
 }
 
-// HandleTaskCreated handles a task created event (alternative method for direct calls)
+// HandleTaskCreated handles a task created event
 func (h *TaskEventHandler) HandleTaskCreated(ctx context.Context, event domain.TaskCreatedEvent) error {
-	h.logger.Info("Handling task created: %d - %s", event.TaskID, event.Name)
+	h.logger.Info("[%s][trace:%s] Handling task created: %d - %s", pkgcontext.GetRequestID(ctx), pkgcontext.GetTraceID(ctx), event.TaskID, event.Name)
 	// Add your business logic here
 	return nil
 }
 
 // HandleTaskUpdated handles a task updated event
 func (h *TaskEventHandler) HandleTaskUpdated(ctx context.Context, event domain.TaskUpdatedEvent) error {
-	h.logger.Info("Handling task updated: %d - %s", event.TaskID, event.Name)
+	h.logger.Info("[%s][trace:%s] Handling task updated: %d - %s", pkgcontext.GetRequestID(ctx), pkgcontext.GetTraceID(ctx), event.TaskID, event.Name)
 	// Add your business logic here
 	return nil
 }
 
 // HandleTaskCompleted handles a task completed event
 func (h *TaskEventHandler) HandleTaskCompleted(ctx context.Context, event domain.TaskCompletedEvent) error {
-	h.logger.Info("Handling task completed: %d", event.TaskID)
+	h.logger.Info("[%s][trace:%s] Handling task completed: %d", pkgcontext.GetRequestID(ctx), pkgcontext.GetTraceID(ctx), event.TaskID)
 	// Add your business logic here
 	return nil
 }
 
 // HandleTaskDeleted handles a task deleted event
 func (h *TaskEventHandler) HandleTaskDeleted(ctx context.Context, event domain.TaskDeletedEvent) error {
-	h.logger.Info("Handling task deleted: %d", event.TaskID)
+	h.logger.Info("[%s][trace:%s] Handling task deleted: %d", pkgcontext.GetRequestID(ctx), pkgcontext.GetTraceID(ctx), event.TaskID)
 	// Add your business logic here
 	return nil
 }
 
+// HandleTaskCommented handles a task commented event
+func (h *TaskEventHandler) HandleTaskCommented(ctx context.Context, event domain.TaskCommentedEvent) error {
+	h.logger.Info("[%s][trace:%s] Handling task commented: %d - comment %d", pkgcontext.GetRequestID(ctx), pkgcontext.GetTraceID(ctx), event.TaskID, event.CommentID)
+	// Add your business logic here (e.g., notify task watchers)
+	return nil
+}
+
+// HandleTaskDueSoon handles a task due soon event
+func (h *TaskEventHandler) HandleTaskDueSoon(ctx context.Context, event domain.TaskDueSoonEvent) error {
+	h.logger.Info("[%s][trace:%s] Handling task due soon: %d - due %s", pkgcontext.GetRequestID(ctx), pkgcontext.GetTraceID(ctx), event.TaskID, event.DueDate.Format(time.RFC3339))
+	// Add your business logic here (e.g., remind the assignee)
+	return nil
+}
+
 // LogError logs an error with trace context
 func (h *TaskEventHandler) LogError(ctx context.Context, format string, args ...interface{}) {
 	traceID := pkgcontext.GetTraceID(ctx)
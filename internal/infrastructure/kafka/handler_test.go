@@ -0,0 +1,81 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/buildinfo"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/metrics"
+	"github.com/seldomhappy/vibe_architecture/logger"
+)
+
+// testSession records the messages marked through it
+type testSession struct {
+	ctx    context.Context
+	marked []*sarama.ConsumerMessage
+}
+
+func (s *testSession) Claims() map[string][]int32                                        { return nil }
+func (s *testSession) MemberID() string                                                  { return "test" }
+func (s *testSession) GenerationID() int32                                               { return 1 }
+func (s *testSession) MarkOffset(topic string, partition int32, offset int64, _ string)  {}
+func (s *testSession) Commit()                                                           {}
+func (s *testSession) ResetOffset(topic string, partition int32, offset int64, _ string) {}
+func (s *testSession) MarkMessage(msg *sarama.ConsumerMessage, _ string) {
+	s.marked = append(s.marked, msg)
+}
+func (s *testSession) Context() context.Context { return s.ctx }
+
+// failingProcessedEvents fails every claim, so every well-formed message fails processing
+type failingProcessedEvents struct{}
+
+func (failingProcessedEvents) Claim(ctx context.Context, eventID string) (bool, error) {
+	return false, errors.New("database unavailable")
+}
+func (failingProcessedEvents) Release(ctx context.Context, eventID string) error { return nil }
+func (failingProcessedEvents) DeleteBefore(ctx context.Context, t time.Time) (int64, error) {
+	return 0, nil
+}
+
+// TestProcessKeepsMessageWhenRepublishFails checks that a failed message the
+// retrier can't hand on is left unmarked and ends the claim, so it is redelivered
+func TestProcessKeepsMessageWhenRepublishFails(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "retry tier unavailable", value: `{"event_id":"e1","event_type":"task.created","payload":{}}`},
+		{name: "dlq unavailable", value: `not json`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := logger.New("test", logger.WithOutput(io.Discard))
+			producer := mocks.NewSyncProducer(t, nil)
+			producer.ExpectSendMessageAndFail(sarama.ErrOutOfBrokers)
+			defer producer.Close()
+
+			dlq := &DeadLetterQueue{producer: producer, topic: "tasks-dlq", metrics: metrics.New(buildinfo.Info{}, 0, false), logger: log}
+			retrier := &Retrier{
+				producer: producer,
+				tiers:    []RetryTier{{Topic: "tasks-retry-1", Delay: time.Second}},
+				dlq:      dlq,
+				logger:   log,
+			}
+			handler := NewTaskEventHandler(retrier, failingProcessedEvents{}, log)
+
+			session := &testSession{ctx: context.Background()}
+			message := &sarama.ConsumerMessage{Topic: "tasks", Partition: 0, Offset: 7, Value: []byte(tt.value)}
+			if handler.process(session, message) {
+				t.Error("process reported true, want false to end the claim")
+			}
+			if len(session.marked) != 0 {
+				t.Errorf("message was marked, want it left for redelivery")
+			}
+		})
+	}
+}
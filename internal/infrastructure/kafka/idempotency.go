@@ -0,0 +1,18 @@
+package kafka
+
+import (
+	"context"
+	"time"
+)
+
+// ProcessedEvents remembers the events the consumer has processed. Kafka
+// delivers at least once, so after a rebalance or a resent produce request
+// the same event may arrive twice.
+type ProcessedEvents interface {
+	// Claim records eventID as processed and reports false if it already was
+	Claim(ctx context.Context, eventID string) (bool, error)
+	// Release forgets eventID, so the event is processed when it comes again
+	Release(ctx context.Context, eventID string) error
+	// DeleteBefore forgets the events processed before t and returns how many
+	DeleteBefore(ctx context.Context, t time.Time) (int64, error)
+}
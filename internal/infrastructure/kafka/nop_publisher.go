@@ -0,0 +1,61 @@
+package kafka
+
+import (
+	"context"
+
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	pkgcontext "github.com/seldomhappy/vibe_architecture/internal/pkg/context"
+	"github.com/seldomhappy/vibe_architecture/logger"
+)
+
+// NopPublisher stands in for the Producer when Kafka is disabled.
+// It drops every event, logging it at debug level.
+type NopPublisher struct {
+	logger logger.ILogger
+}
+
+// NewNopPublisher creates a publisher that discards events
+func NewNopPublisher(log logger.ILogger) *NopPublisher {
+	return &NopPublisher{logger: log}
+}
+
+// PublishTaskCreated discards a task created event
+func (p *NopPublisher) PublishTaskCreated(ctx context.Context, event domain.TaskCreatedEvent, task *domain.Task) error {
+	p.drop(ctx, domain.EventTypeTaskCreated, event.TaskID)
+	return nil
+}
+
+// PublishTaskUpdated discards a task updated event
+func (p *NopPublisher) PublishTaskUpdated(ctx context.Context, event domain.TaskUpdatedEvent, task *domain.Task) error {
+	p.drop(ctx, domain.EventTypeTaskUpdated, event.TaskID)
+	return nil
+}
+
+// PublishTaskCompleted discards a task completed event
+func (p *NopPublisher) PublishTaskCompleted(ctx context.Context, event domain.TaskCompletedEvent, task *domain.Task) error {
+	p.drop(ctx, domain.EventTypeTaskCompleted, event.TaskID)
+	return nil
+}
+
+// PublishTaskDeleted discards a task deleted event
+func (p *NopPublisher) PublishTaskDeleted(ctx context.Context, event domain.TaskDeletedEvent, task *domain.Task) error {
+	p.drop(ctx, domain.EventTypeTaskDeleted, event.TaskID)
+	return nil
+}
+
+// PublishTaskCommented discards a task commented event
+func (p *NopPublisher) PublishTaskCommented(ctx context.Context, event domain.TaskCommentedEvent) error {
+	p.drop(ctx, domain.EventTypeTaskCommented, event.TaskID)
+	return nil
+}
+
+// PublishTaskDueSoon discards a task due soon event
+func (p *NopPublisher) PublishTaskDueSoon(ctx context.Context, event domain.TaskDueSoonEvent, task *domain.Task) error {
+	p.drop(ctx, domain.EventTypeTaskDueSoon, event.TaskID)
+	return nil
+}
+
+func (p *NopPublisher) drop(ctx context.Context, eventType domain.EventType, taskID int64) {
+	p.logger.Debug("[trace:%s] Kafka disabled, dropping %s event for task %d",
+		pkgcontext.GetTraceID(ctx), eventType, taskID)
+}
@@ -0,0 +1,187 @@
+package kafka
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/seldomhappy/vibe_architecture/logger"
+)
+
+// Consumer offset positions, used for kafka.consumer.offset_initial and offset resets
+const (
+	OffsetOldest = "oldest"
+	OffsetNewest = "newest"
+)
+
+// ParseOffsetInitial maps an offset_initial setting to the sarama constant
+func ParseOffsetInitial(s string) (int64, error) {
+	switch strings.ToLower(s) {
+	case OffsetOldest:
+		return sarama.OffsetOldest, nil
+	case "", OffsetNewest:
+		return sarama.OffsetNewest, nil
+	default:
+		return 0, fmt.Errorf("unknown initial offset: %q", s)
+	}
+}
+
+// ResetTarget is where an offset reset moves the group: the oldest retained
+// message, or the first message at or after a timestamp
+type ResetTarget struct {
+	Oldest bool
+	At     time.Time
+}
+
+// ParseResetTarget parses "oldest" (or "earliest") or an RFC 3339 timestamp
+func ParseResetTarget(s string) (ResetTarget, error) {
+	if lower := strings.ToLower(s); lower == OffsetOldest || lower == "earliest" {
+		return ResetTarget{Oldest: true}, nil
+	}
+	at, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return ResetTarget{}, fmt.Errorf("reset target must be %q or an RFC 3339 timestamp: %q", OffsetOldest, s)
+	}
+	return ResetTarget{At: at}, nil
+}
+
+// String implements fmt.Stringer
+func (t ResetTarget) String() string {
+	if t.Oldest {
+		return OffsetOldest
+	}
+	return t.At.Format(time.RFC3339)
+}
+
+// OffsetResetConfig holds offset reset configuration
+type OffsetResetConfig struct {
+	Brokers []string
+	GroupID string
+	Topics  []string
+	// Version is the Kafka protocol version, DefaultVersion when empty
+	Version  string
+	Security SecurityConfig
+}
+
+// OffsetChange is the move of one partition's committed offset.
+// Current is -1 when the group has never committed an offset for the partition.
+type OffsetChange struct {
+	Topic     string
+	Partition int32
+	Current   int64
+	Target    int64
+}
+
+// ResetOffsets moves the group's committed offsets on every partition of the
+// topics to target, so the consumer reprocesses from there. Without apply it
+// only reports the changes. The group's consumers must be stopped: Kafka rejects
+// commits from outside the group while it has members.
+func ResetOffsets(cfg OffsetResetConfig, target ResetTarget, apply bool, log logger.ILogger) ([]OffsetChange, error) {
+	version, err := parseVersion(cfg.Version)
+	if err != nil {
+		return nil, err
+	}
+	config := sarama.NewConfig()
+	config.Version = version
+	config.Consumer.Return.Errors = true
+	// Offsets are only committed explicitly, and never on a dry run
+	config.Consumer.Offsets.AutoCommit.Enable = false
+	if err := cfg.Security.apply(config); err != nil {
+		return nil, err
+	}
+
+	client, err := sarama.NewClient(cfg.Brokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka client: %w", err)
+	}
+	defer client.Close()
+
+	offsets, err := sarama.NewOffsetManagerFromClient(cfg.GroupID, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create offset manager: %w", err)
+	}
+	defer offsets.Close()
+
+	var changes []OffsetChange
+	var managed []sarama.PartitionOffsetManager
+	defer func() {
+		for _, pom := range managed {
+			pom.AsyncClose()
+		}
+	}()
+
+	for _, topic := range cfg.Topics {
+		partitions, err := client.Partitions(topic)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list partitions of %s: %w", topic, err)
+		}
+
+		for _, partition := range partitions {
+			pom, err := offsets.ManagePartition(topic, partition)
+			if err != nil {
+				return nil, fmt.Errorf("failed to manage partition %s/%d: %w", topic, partition, err)
+			}
+			managed = append(managed, pom)
+
+			current, _ := pom.NextOffset()
+			if current < 0 {
+				current = -1
+			}
+			next, err := resetOffset(client, topic, partition, target)
+			if err != nil {
+				return nil, err
+			}
+
+			changes = append(changes, OffsetChange{Topic: topic, Partition: partition, Current: current, Target: next})
+			log.Info("Offset reset %s/%d: %d -> %d", topic, partition, current, next)
+			if apply {
+				pom.ResetOffset(next, "")
+			}
+		}
+	}
+
+	if !apply {
+		log.Info("Offset reset dry run for group %s to %s: %d partitions, nothing committed", cfg.GroupID, target, len(changes))
+		return changes, nil
+	}
+
+	// Commit is synchronous, so any failure is already waiting on the partitions' error channels
+	offsets.Commit()
+	for _, pom := range managed {
+		select {
+		case err := <-pom.Errors():
+			return changes, fmt.Errorf("failed to commit reset offsets: %w", err)
+		default:
+		}
+	}
+
+	log.Info("Offset reset for group %s to %s committed on %d partitions", cfg.GroupID, target, len(changes))
+	return changes, nil
+}
+
+// resetOffset looks up the offset target points to on one partition
+func resetOffset(client sarama.Client, topic string, partition int32, target ResetTarget) (int64, error) {
+	if target.Oldest {
+		offset, err := client.GetOffset(topic, partition, sarama.OffsetOldest)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get oldest offset of %s/%d: %w", topic, partition, err)
+		}
+		return offset, nil
+	}
+
+	offset, err := client.GetOffset(topic, partition, target.At.UnixMilli())
+	if err != nil {
+		return 0, fmt.Errorf("failed to get offset of %s/%d at %s: %w", topic, partition, target, err)
+	}
+	if offset >= 0 {
+		return offset, nil
+	}
+
+	// Nothing was written since the timestamp, so start after the last message
+	offset, err = client.GetOffset(topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get newest offset of %s/%d: %w", topic, partition, err)
+	}
+	return offset, nil
+}
@@ -9,14 +9,41 @@ import (
 	"github.com/IBM/sarama"
 	"github.com/seldomhappy/vibe_architecture/internal/domain"
 	pkgcontext "github.com/seldomhappy/vibe_architecture/internal/pkg/context"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/metrics"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/tracing"
 	"github.com/seldomhappy/vibe_architecture/logger"
 )
 
+// Headers carrying the publishing request along with each event, so the
+// consumer can correlate its logs and continue the trace
+const (
+	HeaderTraceID   = "trace_id"
+	HeaderSpanID    = "span_id"
+	HeaderRequestID = "request_id"
+	// HeaderBaggage carries the trace baggage (tenant, user) in W3C format
+	HeaderBaggage = "baggage"
+)
+
+// Message key strategies
+const (
+	// KeyStrategyTaskID keys events by task, keeping each task's events in order
+	KeyStrategyTaskID = "task_id"
+	// KeyStrategyAssignedTo keys events by assignee, keeping each assignee's
+	// events in order. Events of unassigned tasks are keyed by task.
+	KeyStrategyAssignedTo = "assigned_to"
+	// KeyStrategyNone sends events unkeyed to random partitions, without any ordering
+	KeyStrategyNone = "none"
+)
+
 // Producer represents a Kafka producer
 type Producer struct {
-	producer sarama.SyncProducer
-	topic    string
-	logger   logger.ILogger
+	client      sarama.Client
+	producer    sarama.SyncProducer
+	topic       string
+	keyStrategy string
+	breaker     *circuitBreaker
+	logger      logger.ILogger
+	metrics     *metrics.Metrics
 }
 
 // ProducerConfig holds producer configuration
@@ -28,17 +55,31 @@ type ProducerConfig struct {
 	RetryBackoff time.Duration
 	Idempotent   bool
 	Timeout      time.Duration
+	Breaker      BreakerConfig
+	// KeyStrategy is KeyStrategyTaskID, KeyStrategyAssignedTo or KeyStrategyNone
+	KeyStrategy string
+	// Version is the Kafka protocol version, DefaultVersion when empty
+	Version  string
+	Security SecurityConfig
 }
 
 // NewProducer creates a new Kafka producer
-func NewProducer(cfg ProducerConfig, log logger.ILogger) (*Producer, error) {
+func NewProducer(cfg ProducerConfig, m *metrics.Metrics, log logger.ILogger) (*Producer, error) {
+	version, err := parseVersion(cfg.Version)
+	if err != nil {
+		return nil, err
+	}
 	config := sarama.NewConfig()
+	config.Version = version
 	config.Producer.Return.Successes = true
 	config.Producer.RequiredAcks = sarama.WaitForAll
 	config.Producer.Retry.Max = cfg.RetryMax
 	config.Producer.Retry.Backoff = cfg.RetryBackoff
 	config.Producer.Idempotent = cfg.Idempotent
 	config.Producer.Timeout = cfg.Timeout
+	if err := cfg.Security.apply(config); err != nil {
+		return nil, err
+	}
 
 	switch cfg.Compression {
 	case "snappy":
@@ -51,15 +92,25 @@ func NewProducer(cfg ProducerConfig, log logger.ILogger) (*Producer, error) {
 		config.Producer.Compression = sarama.CompressionNone
 	}
 
-	producer, err := sarama.NewSyncProducer(cfg.Brokers, config)
+	client, err := sarama.NewClient(cfg.Brokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka client: %w", err)
+	}
+
+	producer, err := sarama.NewSyncProducerFromClient(client)
 	if err != nil {
+		client.Close()
 		return nil, fmt.Errorf("failed to create kafka producer: %w", err)
 	}
 
 	return &Producer{
-		producer: producer,
-		topic:    cfg.Topic,
-		logger:   log,
+		client:      client,
+		producer:    producer,
+		topic:       cfg.Topic,
+		keyStrategy: cfg.KeyStrategy,
+		breaker:     newCircuitBreaker(cfg.Breaker, log, m),
+		logger:      log,
+		metrics:     m,
 	}, nil
 }
 
@@ -72,34 +123,105 @@ func (p *Producer) Start(ctx context.Context) error {
 // Shutdown closes the producer
 func (p *Producer) Shutdown(ctx context.Context) error {
 	p.logger.Info("Shutting down Kafka producer")
-	return p.producer.Close()
+	err := p.producer.Close()
+	if closeErr := p.client.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Ping checks that the brokers are reachable by refreshing the topic's metadata
+func (p *Producer) Ping(ctx context.Context) error {
+	if err := p.client.RefreshMetadata(p.topic); err != nil {
+		return fmt.Errorf("failed to reach kafka: %w", err)
+	}
+	return nil
+}
+
+// CircuitState returns the state of the producer's circuit breaker
+func (p *Producer) CircuitState() string {
+	return p.breaker.current()
+}
+
+// CheckCircuit is a readiness check that fails while the circuit breaker is
+// open, i.e. while task events are being dropped
+func (p *Producer) CheckCircuit(ctx context.Context) error {
+	if p.CircuitState() == CircuitOpen {
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// messageKey returns the key of a task's event under the key strategy; empty
+// means unkeyed. assignedTo is nil when the task is unassigned or the event
+// doesn't tell.
+func (p *Producer) messageKey(taskID int64, assignedTo *int64) string {
+	switch p.keyStrategy {
+	case KeyStrategyNone:
+		return ""
+	case KeyStrategyAssignedTo:
+		if assignedTo != nil {
+			return fmt.Sprintf("user-%d", *assignedTo)
+		}
+	}
+	return fmt.Sprintf("task-%d", taskID)
 }
 
-// SendMessage sends a message to Kafka
+// taskAssignee returns who a task snapshot is assigned to, if there is one
+func taskAssignee(task *domain.Task) *int64 {
+	if task == nil {
+		return nil
+	}
+	return task.AssignedTo
+}
+
+// SendMessage sends a message to Kafka. An empty key sends it unkeyed, to a
+// random partition. While the circuit breaker is open the message is dropped
+// and ErrCircuitOpen is returned without contacting the broker.
 func (p *Producer) SendMessage(ctx context.Context, key string, value interface{}) error {
 	data, err := json.Marshal(value)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
+	var keyEncoder sarama.Encoder
+	if key != "" {
+		keyEncoder = sarama.StringEncoder(key)
+	}
 	msg := &sarama.ProducerMessage{
 		Topic: p.topic,
-		Key:   sarama.StringEncoder(key),
+		Key:   keyEncoder,
 		Value: sarama.ByteEncoder(data),
 		Headers: []sarama.RecordHeader{
 			{
-				Key:   []byte("trace_id"),
+				Key:   []byte(HeaderTraceID),
 				Value: []byte(pkgcontext.GetTraceID(ctx)),
 			},
 			{
-				Key:   []byte("request_id"),
+				Key:   []byte(HeaderSpanID),
+				Value: []byte(pkgcontext.GetSpanID(ctx)),
+			},
+			{
+				Key:   []byte(HeaderRequestID),
 				Value: []byte(pkgcontext.GetRequestID(ctx)),
 			},
+			{
+				Key:   []byte(HeaderBaggage),
+				Value: []byte(tracing.EncodeBaggage(ctx)),
+			},
 		},
 		Timestamp: time.Now(),
 	}
 
+	if !p.breaker.allow() {
+		p.metrics.RecordKafkaEventDropped()
+		p.logger.Debug("[trace:%s] Kafka circuit breaker is open, dropping message %s",
+			pkgcontext.GetTraceID(ctx), key)
+		return ErrCircuitOpen
+	}
+
 	partition, offset, err := p.producer.SendMessage(msg)
+	p.breaker.done(err)
 	if err != nil {
 		p.logger.Error("Failed to send message to Kafka: %v", err)
 		return fmt.Errorf("failed to send message: %w", err)
@@ -110,37 +232,31 @@ func (p *Producer) SendMessage(ctx context.Context, key string, value interface{
 }
 
 // PublishTaskCreated publishes a task created event
-func (p *Producer) PublishTaskCreated(ctx context.Context, event domain.TaskCreatedEvent) error {
-	return p.SendMessage(ctx, fmt.Sprintf("task-%d", event.TaskID), map[string]interface{}{
-		"event_type": domain.EventTypeTaskCreated,
-		"payload":    event,
-		"timestamp":  time.Now(),
-	})
+func (p *Producer) PublishTaskCreated(ctx context.Context, event domain.TaskCreatedEvent, task *domain.Task) error {
+	return p.SendMessage(ctx, p.messageKey(event.TaskID, taskAssignee(task)), newEnvelope("", domain.EventTypeTaskCreated, event, task))
 }
 
 // PublishTaskUpdated publishes a task updated event
-func (p *Producer) PublishTaskUpdated(ctx context.Context, event domain.TaskUpdatedEvent) error {
-	return p.SendMessage(ctx, fmt.Sprintf("task-%d", event.TaskID), map[string]interface{}{
-		"event_type": domain.EventTypeTaskUpdated,
-		"payload":    event,
-		"timestamp":  time.Now(),
-	})
+func (p *Producer) PublishTaskUpdated(ctx context.Context, event domain.TaskUpdatedEvent, task *domain.Task) error {
+	return p.SendMessage(ctx, p.messageKey(event.TaskID, event.AssignedTo), newEnvelope("", domain.EventTypeTaskUpdated, event, task))
 }
 
 // PublishTaskCompleted publishes a task completed event
-func (p *Producer) PublishTaskCompleted(ctx context.Context, event domain.TaskCompletedEvent) error {
-	return p.SendMessage(ctx, fmt.Sprintf("task-%d", event.TaskID), map[string]interface{}{
-		"event_type": domain.EventTypeTaskCompleted,
-		"payload":    event,
-		"timestamp":  time.Now(),
-	})
+func (p *Producer) PublishTaskCompleted(ctx context.Context, event domain.TaskCompletedEvent, task *domain.Task) error {
+	return p.SendMessage(ctx, p.messageKey(event.TaskID, taskAssignee(task)), newEnvelope("", domain.EventTypeTaskCompleted, event, task))
 }
 
 // PublishTaskDeleted publishes a task deleted event
-func (p *Producer) PublishTaskDeleted(ctx context.Context, event domain.TaskDeletedEvent) error {
-	return p.SendMessage(ctx, fmt.Sprintf("task-%d", event.TaskID), map[string]interface{}{
-		"event_type": domain.EventTypeTaskDeleted,
-		"payload":    event,
-		"timestamp":  time.Now(),
-	})
+func (p *Producer) PublishTaskDeleted(ctx context.Context, event domain.TaskDeletedEvent, task *domain.Task) error {
+	return p.SendMessage(ctx, p.messageKey(event.TaskID, taskAssignee(task)), newEnvelope("", domain.EventTypeTaskDeleted, event, task))
+}
+
+// PublishTaskCommented publishes a task commented event
+func (p *Producer) PublishTaskCommented(ctx context.Context, event domain.TaskCommentedEvent) error {
+	return p.SendMessage(ctx, p.messageKey(event.TaskID, nil), newEnvelope("", domain.EventTypeTaskCommented, event, nil))
+}
+
+// PublishTaskDueSoon publishes a task due soon event
+func (p *Producer) PublishTaskDueSoon(ctx context.Context, event domain.TaskDueSoonEvent, task *domain.Task) error {
+	return p.SendMessage(ctx, p.messageKey(event.TaskID, event.AssignedTo), newEnvelope("", domain.EventTypeTaskDueSoon, event, task))
 }
@@ -0,0 +1,159 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	pkgcontext "github.com/seldomhappy/vibe_architecture/internal/pkg/context"
+	"github.com/seldomhappy/vibe_architecture/logger"
+)
+
+// Headers added to messages sent to a retry topic.
+// All of them start with retryHeaderPrefix and are stripped again on dlq replay.
+const (
+	retryHeaderPrefix        = "retry."
+	HeaderRetryAttempt       = "retry.attempt"
+	HeaderRetryNotBefore     = "retry.not_before"
+	HeaderRetryOriginalTopic = "retry.original_topic"
+)
+
+// RetryTier is a retry topic and how long messages wait there before being retried
+type RetryTier struct {
+	Topic string
+	Delay time.Duration
+}
+
+// Retrier moves messages that failed processing through the retry tiers in order,
+// parking them in the dead letter queue once the last tier has failed too
+type Retrier struct {
+	producer sarama.SyncProducer
+	tiers    []RetryTier
+	dlq      *DeadLetterQueue
+	logger   logger.ILogger
+}
+
+// NewRetrier creates a retrier that publishes through producer.
+// With no tiers, failed messages go straight to dlq.
+func NewRetrier(tiers []RetryTier, producer *Producer, dlq *DeadLetterQueue, log logger.ILogger) *Retrier {
+	return &Retrier{
+		producer: producer.producer,
+		tiers:    tiers,
+		dlq:      dlq,
+		logger:   log,
+	}
+}
+
+// Topics returns the retry topics, which the consumer subscribes to alongside the main topic
+func (r *Retrier) Topics() []string {
+	topics := make([]string, 0, len(r.tiers))
+	for _, tier := range r.tiers {
+		topics = append(topics, tier.Topic)
+	}
+	return topics
+}
+
+// Retry republishes a failed message to the next retry tier with its attempt
+// count incremented and the time it becomes due. After the last tier it goes to the dlq.
+func (r *Retrier) Retry(ctx context.Context, message *sarama.ConsumerMessage, cause error) error {
+	attempt := retryAttempt(message)
+	originalTopic := retryOriginalTopic(message)
+
+	if attempt >= len(r.tiers) {
+		return r.Park(ctx, message, cause)
+	}
+
+	tier := r.tiers[attempt]
+	headers := make([]sarama.RecordHeader, 0, len(message.Headers)+3)
+	for _, h := range message.Headers {
+		if !strings.HasPrefix(string(h.Key), retryHeaderPrefix) {
+			headers = append(headers, *h)
+		}
+	}
+	headers = append(headers,
+		sarama.RecordHeader{Key: []byte(HeaderRetryAttempt), Value: []byte(strconv.Itoa(attempt + 1))},
+		sarama.RecordHeader{Key: []byte(HeaderRetryNotBefore), Value: []byte(time.Now().Add(tier.Delay).UTC().Format(time.RFC3339Nano))},
+		sarama.RecordHeader{Key: []byte(HeaderRetryOriginalTopic), Value: []byte(originalTopic)},
+	)
+
+	_, _, err := r.producer.SendMessage(&sarama.ProducerMessage{
+		Topic:   tier.Topic,
+		Key:     keyOf(message),
+		Value:   sarama.ByteEncoder(message.Value),
+		Headers: headers,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send message to retry topic %s: %w", tier.Topic, err)
+	}
+
+	r.logger.Warn("[trace:%s] Message %s/%d/%d scheduled for retry %d/%d on %s in %v: %v",
+		pkgcontext.GetTraceID(ctx), message.Topic, message.Partition, message.Offset,
+		attempt+1, len(r.tiers), tier.Topic, tier.Delay, cause)
+	return nil
+}
+
+// Park sends a message straight to the dead letter queue, skipping any retry tiers left
+func (r *Retrier) Park(ctx context.Context, message *sarama.ConsumerMessage, cause error) error {
+	// Record the topic the message was first consumed from, so a replay starts it over
+	parked := *message
+	parked.Topic = retryOriginalTopic(message)
+	return r.dlq.Send(ctx, &parked, cause)
+}
+
+// WaitUntilDue blocks until a retried message is due. Messages that were never
+// retried are due immediately. It returns the context error if ctx ends first,
+// in which case the message must not be marked so it is redelivered later.
+func WaitUntilDue(ctx context.Context, message *sarama.ConsumerMessage) error {
+	value := headerValue(message, HeaderRetryNotBefore)
+	if value == "" {
+		return nil
+	}
+	notBefore, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil
+	}
+
+	wait := time.Until(notBefore)
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryAttempt returns how many times message has been retried
+func retryAttempt(message *sarama.ConsumerMessage) int {
+	attempt, err := strconv.Atoi(headerValue(message, HeaderRetryAttempt))
+	if err != nil || attempt < 0 {
+		return 0
+	}
+	return attempt
+}
+
+// retryOriginalTopic returns the topic message was first consumed from
+func retryOriginalTopic(message *sarama.ConsumerMessage) string {
+	if topic := headerValue(message, HeaderRetryOriginalTopic); topic != "" {
+		return topic
+	}
+	return message.Topic
+}
+
+// headerValue returns the value of the named header, or "" if it is missing
+func headerValue(message *sarama.ConsumerMessage, key string) string {
+	for _, h := range message.Headers {
+		if string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
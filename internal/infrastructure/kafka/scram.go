@@ -0,0 +1,151 @@
+package kafka
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// SCRAM hash functions
+var (
+	scramSHA256 = sha256.New
+	scramSHA512 = sha512.New
+)
+
+// scramClient is the client side of a SCRAM exchange (RFC 5802), which sarama
+// leaves to the application. Passwords are used as is, without SASLprep.
+type scramClient struct {
+	newHash              func() hash.Hash
+	password             string
+	gs2Header            string
+	clientFirstBare      string
+	nonce                string
+	expectedServerSignal []byte
+	step                 int
+	done                 bool
+}
+
+func newSCRAMClient(newHash func() hash.Hash) *scramClient {
+	return &scramClient{newHash: newHash}
+}
+
+// Begin implements sarama.SCRAMClient
+func (c *scramClient) Begin(username, password, authzID string) error {
+	nonce := make([]byte, 24)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate scram nonce: %w", err)
+	}
+
+	c.password = password
+	c.nonce = base64.RawStdEncoding.EncodeToString(nonce)
+	c.gs2Header = "n,,"
+	if authzID != "" {
+		c.gs2Header = "n,a=" + scramEscape(authzID) + ","
+	}
+	c.clientFirstBare = "n=" + scramEscape(username) + ",r=" + c.nonce
+	c.step = 0
+	c.done = false
+	return nil
+}
+
+// Step implements sarama.SCRAMClient
+func (c *scramClient) Step(challenge string) (string, error) {
+	c.step++
+	switch c.step {
+	case 1:
+		return c.gs2Header + c.clientFirstBare, nil
+	case 2:
+		return c.clientFinal(challenge)
+	case 3:
+		c.done = true
+		return "", c.verifyServerFinal(challenge)
+	default:
+		return "", errors.New("scram exchange is already over")
+	}
+}
+
+// Done implements sarama.SCRAMClient
+func (c *scramClient) Done() bool {
+	return c.done
+}
+
+// clientFinal answers the server-first message with the client proof
+func (c *scramClient) clientFinal(serverFirst string) (string, error) {
+	attrs := scramAttributes(serverFirst)
+	nonce, salt64, iterations := attrs["r"], attrs["s"], attrs["i"]
+	if !strings.HasPrefix(nonce, c.nonce) || len(nonce) == len(c.nonce) {
+		return "", errors.New("scram server nonce doesn't extend the client nonce")
+	}
+	salt, err := base64.StdEncoding.DecodeString(salt64)
+	if err != nil {
+		return "", fmt.Errorf("invalid scram salt: %w", err)
+	}
+	iter, err := strconv.Atoi(iterations)
+	if err != nil || iter <= 0 {
+		return "", fmt.Errorf("invalid scram iteration count: %q", iterations)
+	}
+
+	salted := pbkdf2.Key([]byte(c.password), salt, iter, c.newHash().Size(), c.newHash)
+	clientKey := c.hmac(salted, []byte("Client Key"))
+	h := c.newHash()
+	h.Write(clientKey)
+	storedKey := h.Sum(nil)
+
+	clientFinalBare := "c=" + base64.StdEncoding.EncodeToString([]byte(c.gs2Header)) + ",r=" + nonce
+	authMessage := []byte(c.clientFirstBare + "," + serverFirst + "," + clientFinalBare)
+
+	proof := c.hmac(storedKey, authMessage)
+	for i := range proof {
+		proof[i] ^= clientKey[i]
+	}
+	c.expectedServerSignal = c.hmac(c.hmac(salted, []byte("Server Key")), authMessage)
+
+	return clientFinalBare + ",p=" + base64.StdEncoding.EncodeToString(proof), nil
+}
+
+// verifyServerFinal checks the server's signature, proving it knows the password too
+func (c *scramClient) verifyServerFinal(serverFinal string) error {
+	attrs := scramAttributes(serverFinal)
+	if e, ok := attrs["e"]; ok {
+		return fmt.Errorf("scram authentication failed: %s", e)
+	}
+	signature, err := base64.StdEncoding.DecodeString(attrs["v"])
+	if err != nil {
+		return fmt.Errorf("invalid scram server signature: %w", err)
+	}
+	if !hmac.Equal(signature, c.expectedServerSignal) {
+		return errors.New("scram server signature doesn't match")
+	}
+	return nil
+}
+
+func (c *scramClient) hmac(key, message []byte) []byte {
+	mac := hmac.New(c.newHash, key)
+	mac.Write(message)
+	return mac.Sum(nil)
+}
+
+// scramAttributes parses a comma separated list of SCRAM k=v attributes
+func scramAttributes(message string) map[string]string {
+	attrs := make(map[string]string)
+	for _, field := range strings.Split(message, ",") {
+		if k, v, ok := strings.Cut(field, "="); ok {
+			attrs[k] = v
+		}
+	}
+	return attrs
+}
+
+// scramEscape escapes a SCRAM user name
+func scramEscape(s string) string {
+	return strings.NewReplacer("=", "=3D", ",", "=2C").Replace(s)
+}
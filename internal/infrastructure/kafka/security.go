@@ -0,0 +1,107 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/IBM/sarama"
+)
+
+// SASL mechanisms accepted for SASLConfig.Mechanism
+const (
+	SASLMechanismPlain       = "PLAIN"
+	SASLMechanismSCRAMSHA256 = "SCRAM-SHA-256"
+	SASLMechanismSCRAMSHA512 = "SCRAM-SHA-512"
+)
+
+// SASLConfig holds SASL authentication settings. An empty Mechanism disables SASL.
+type SASLConfig struct {
+	Mechanism string
+	Username  string
+	Password  string
+}
+
+// TLSConfig holds the settings of TLS connections to the brokers
+type TLSConfig struct {
+	Enabled bool
+	// CAFile verifies the brokers' certificates; empty trusts the system roots
+	CAFile string
+	// CertFile and KeyFile authenticate the client with mutual TLS
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+}
+
+// SecurityConfig secures the connections of every Kafka client: producer,
+// consumer, dead letter queue and admin
+type SecurityConfig struct {
+	SASL SASLConfig
+	TLS  TLSConfig
+}
+
+// apply configures SASL and TLS on a sarama config
+func (c SecurityConfig) apply(config *sarama.Config) error {
+	if c.TLS.Enabled {
+		tlsConfig, err := c.TLS.build()
+		if err != nil {
+			return err
+		}
+		config.Net.TLS.Enable = true
+		config.Net.TLS.Config = tlsConfig
+	}
+
+	switch c.SASL.Mechanism {
+	case "":
+		return nil
+	case SASLMechanismPlain:
+		config.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+	case SASLMechanismSCRAMSHA256:
+		config.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+		config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return newSCRAMClient(scramSHA256) }
+	case SASLMechanismSCRAMSHA512:
+		config.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+		config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return newSCRAMClient(scramSHA512) }
+	default:
+		return fmt.Errorf("unsupported SASL mechanism: %q", c.SASL.Mechanism)
+	}
+	if c.SASL.Username == "" || c.SASL.Password == "" {
+		return fmt.Errorf("SASL mechanism %s needs a username and password", c.SASL.Mechanism)
+	}
+	config.Net.SASL.Enable = true
+	config.Net.SASL.Handshake = true
+	config.Net.SASL.User = c.SASL.Username
+	config.Net.SASL.Password = c.SASL.Password
+	return nil
+}
+
+// build loads the CA and client certificates into a tls.Config
+func (c TLSConfig) build() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kafka CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in kafka CA file %s", c.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kafka client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,19 @@
+package kafka
+
+import (
+	"context"
+
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+)
+
+// HandleEvent publishes an event from the event bus to Kafka. The event tells
+// the task's assignee, so it's keyed accordingly under KeyStrategyAssignedTo.
+func (p *Producer) HandleEvent(ctx context.Context, event domain.TaskEvent) error {
+	return p.SendMessage(ctx, p.messageKey(event.TaskID, event.AssignedTo), newEnvelope(event.ID, event.Type, event.Payload, event.Task))
+}
+
+// HandleEvent discards an event from the event bus
+func (p *NopPublisher) HandleEvent(ctx context.Context, event domain.TaskEvent) error {
+	p.drop(ctx, event.Type, event.TaskID)
+	return nil
+}
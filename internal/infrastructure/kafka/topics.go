@@ -0,0 +1,78 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/IBM/sarama"
+	"github.com/seldomhappy/vibe_architecture/logger"
+)
+
+// TopicsConfig holds topic verification configuration
+type TopicsConfig struct {
+	Brokers []string
+	Topics  []string
+	// AutoCreate creates missing topics instead of failing
+	AutoCreate        bool
+	Partitions        int32
+	ReplicationFactor int16
+	// Version is the Kafka protocol version, DefaultVersion when empty
+	Version  string
+	Security SecurityConfig
+}
+
+// EnsureTopics checks that every topic exists and logs its partitions and
+// replication factor. Missing topics are created when AutoCreate is set and
+// fail the check otherwise, since a consumer of a missing topic silently
+// receives nothing.
+func EnsureTopics(cfg TopicsConfig, log logger.ILogger) error {
+	version, err := parseVersion(cfg.Version)
+	if err != nil {
+		return err
+	}
+	config := sarama.NewConfig()
+	config.Version = version
+	if err := cfg.Security.apply(config); err != nil {
+		return err
+	}
+
+	admin, err := sarama.NewClusterAdmin(cfg.Brokers, config)
+	if err != nil {
+		return fmt.Errorf("failed to create kafka cluster admin: %w", err)
+	}
+	defer admin.Close()
+
+	existing, err := admin.ListTopics()
+	if err != nil {
+		return fmt.Errorf("failed to list kafka topics: %w", err)
+	}
+
+	var missing []string
+	for _, topic := range cfg.Topics {
+		detail, ok := existing[topic]
+		if !ok {
+			missing = append(missing, topic)
+			continue
+		}
+		log.Info("Kafka topic %s: %d partitions, replication factor %d", topic, detail.NumPartitions, detail.ReplicationFactor)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if !cfg.AutoCreate {
+		return fmt.Errorf("kafka topics %v do not exist; create them or set kafka.topics.auto_create", missing)
+	}
+
+	for _, topic := range missing {
+		detail := &sarama.TopicDetail{
+			NumPartitions:     cfg.Partitions,
+			ReplicationFactor: cfg.ReplicationFactor,
+		}
+		// Another instance starting alongside may have just created it
+		if err := admin.CreateTopic(topic, detail, false); err != nil && !errors.Is(err, sarama.ErrTopicAlreadyExists) {
+			return fmt.Errorf("failed to create kafka topic %s: %w", topic, err)
+		}
+		log.Info("Created Kafka topic %s: %d partitions, replication factor %d", topic, cfg.Partitions, cfg.ReplicationFactor)
+	}
+	return nil
+}
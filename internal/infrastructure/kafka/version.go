@@ -0,0 +1,23 @@
+package kafka
+
+import (
+	"fmt"
+
+	"github.com/IBM/sarama"
+)
+
+// DefaultVersion is the Kafka protocol version clients speak when none is configured
+const DefaultVersion = "2.6.0"
+
+// parseVersion parses the Kafka protocol version the clients speak. It must not
+// be newer than the brokers' version.
+func parseVersion(version string) (sarama.KafkaVersion, error) {
+	if version == "" {
+		version = DefaultVersion
+	}
+	v, err := sarama.ParseKafkaVersion(version)
+	if err != nil {
+		return sarama.KafkaVersion{}, fmt.Errorf("invalid kafka version: %w", err)
+	}
+	return v, nil
+}
@@ -0,0 +1,124 @@
+// Package outbox stores task events on their way to Kafka, in the transaction
+// of the change they describe, so an event is kept exactly when its change is
+// and goes out once Kafka is reachable.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	pkgcontext "github.com/seldomhappy/vibe_architecture/internal/pkg/context"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/eventbus"
+	"github.com/seldomhappy/vibe_architecture/logger"
+)
+
+// Store persists the events of the outbox
+type Store interface {
+	AddTx(ctx context.Context, tx pgx.Tx, event *domain.OutboxEvent) error
+	// PendingForUpdate returns the oldest events no other transaction holds
+	// and holds them until tx ends
+	PendingForUpdate(ctx context.Context, tx pgx.Tx, limit int) ([]*domain.OutboxEvent, error)
+	DeleteTx(ctx context.Context, tx pgx.Tx, id int64) error
+}
+
+// TxManager runs a function inside a database transaction
+type TxManager interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context, tx pgx.Tx) error) error
+}
+
+// Outbox takes the place of the Kafka sink on the event bus. Use cases store
+// their events with AddTx, and Relay publishes them to the Kafka sink in order.
+type Outbox struct {
+	store     Store
+	tx        TxManager
+	target    eventbus.Sink
+	batchSize int
+	logger    logger.ILogger
+}
+
+// New creates an outbox that relays events to target, batchSize at a time
+func New(store Store, txManager TxManager, target eventbus.Sink, batchSize int, log logger.ILogger) *Outbox {
+	return &Outbox{
+		store:     store,
+		tx:        txManager,
+		target:    target,
+		batchSize: batchSize,
+		logger:    log,
+	}
+}
+
+// AddTx stores an event inside tx until Relay publishes it
+func (o *Outbox) AddTx(ctx context.Context, tx pgx.Tx, event domain.TaskEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox event: %w", err)
+	}
+	return o.store.AddTx(ctx, tx, &domain.OutboxEvent{
+		EventType: event.Type,
+		TaskID:    event.TaskID,
+		Event:     data,
+		RequestID: pkgcontext.GetRequestID(ctx),
+	})
+}
+
+// Relay publishes the stored events, oldest first, and removes each once it is
+// published. Every replica runs it: each batch is claimed in a transaction,
+// so replicas relay different events rather than the same ones twice. It
+// stops at the first failure so events keep their order; the events published
+// before it are removed, and the next run starts over from there.
+func (o *Outbox) Relay(ctx context.Context) error {
+	for {
+		claimed := 0
+		var relayErr error
+		err := o.tx.WithTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
+			events, err := o.store.PendingForUpdate(ctx, tx, o.batchSize)
+			if err != nil {
+				return err
+			}
+			claimed = len(events)
+
+			for _, stored := range events {
+				if err := o.publish(ctx, stored); err != nil {
+					// Commit the deletes so far; rolling back would publish those events again
+					relayErr = fmt.Errorf("failed to relay %s event %d for task %d: %w", stored.EventType, stored.ID, stored.TaskID, err)
+					return nil
+				}
+				if err := o.store.DeleteTx(ctx, tx, stored.ID); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if relayErr != nil {
+			return relayErr
+		}
+		if claimed < o.batchSize {
+			return nil
+		}
+	}
+}
+
+// publish hands a stored event to the target along with its request ID
+func (o *Outbox) publish(ctx context.Context, stored *domain.OutboxEvent) error {
+	// The payload is passed on as stored rather than decoded into a map
+	var payload json.RawMessage
+	event := domain.TaskEvent{Payload: &payload}
+	if err := json.Unmarshal(stored.Event, &event); err != nil {
+		// It will never decode; keeping it would block every later event
+		o.logger.Error("[%s] Dropping undecodable outbox event %d: %v", stored.RequestID, stored.ID, err)
+		return nil
+	}
+
+	ctx = pkgcontext.WithRequestID(ctx, stored.RequestID)
+	if err := o.target.HandleEvent(ctx, event); err != nil {
+		return err
+	}
+	o.logger.Debug("[%s] Relayed %s event for task %d from the outbox", stored.RequestID, event.Type, event.TaskID)
+	return nil
+}
@@ -15,7 +15,7 @@ var migrationFiles embed.FS
 
 // RunMigrations runs database migrations using tern
 func RunMigrations(dsn string, log logger.ILogger) error {
-	log.Info("Running database migrations...")
+	log.Info("Running database migrations on %s", RedactDSN(dsn))
 
 	ctx := context.Background()
 	conn, err := pgx.Connect(ctx, dsn)
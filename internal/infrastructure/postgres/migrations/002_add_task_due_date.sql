@@ -0,0 +1,12 @@
+-- Add due date to tasks
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS due_date TIMESTAMPTZ;
+
+-- Partial index used by overdue/due-soon queries on open tasks
+CREATE INDEX IF NOT EXISTS idx_tasks_due_date ON tasks(due_date)
+    WHERE due_date IS NOT NULL AND status NOT IN ('completed', 'cancelled');
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_tasks_due_date;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS due_date;
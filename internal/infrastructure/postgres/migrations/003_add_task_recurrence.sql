@@ -0,0 +1,19 @@
+-- Add recurrence support to tasks
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS recurrence_rule VARCHAR(255);
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS parent_task_id BIGINT REFERENCES tasks(id) ON DELETE SET NULL;
+
+-- A recurring task generates at most one next occurrence
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_parent_task_id ON tasks(parent_task_id)
+    WHERE parent_task_id IS NOT NULL;
+
+-- Speeds up the scheduler scan for completed recurring tasks
+CREATE INDEX IF NOT EXISTS idx_tasks_recurring_completed ON tasks(id)
+    WHERE recurrence_rule IS NOT NULL AND status = 'completed';
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_tasks_recurring_completed;
+DROP INDEX IF EXISTS idx_tasks_parent_task_id;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS parent_task_id;
+ALTER TABLE tasks DROP COLUMN IF EXISTS recurrence_rule;
@@ -0,0 +1,17 @@
+-- Create task_dependencies table: task_id is blocked until depends_on_id is completed
+CREATE TABLE IF NOT EXISTS task_dependencies (
+    task_id BIGINT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    depends_on_id BIGINT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (task_id, depends_on_id),
+    CHECK (task_id <> depends_on_id)
+);
+
+-- Reverse lookups for cycle detection
+CREATE INDEX IF NOT EXISTS idx_task_dependencies_depends_on_id ON task_dependencies(depends_on_id);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_task_dependencies_depends_on_id;
+
+DROP TABLE IF EXISTS task_dependencies;
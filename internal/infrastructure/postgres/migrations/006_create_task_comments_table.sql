@@ -0,0 +1,17 @@
+-- Create task_comments table
+CREATE TABLE IF NOT EXISTS task_comments (
+    id BIGSERIAL PRIMARY KEY,
+    task_id BIGINT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    author BIGINT NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Comments are always listed per task in creation order
+CREATE INDEX IF NOT EXISTS idx_task_comments_task_id_created_at ON task_comments(task_id, created_at);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_task_comments_task_id_created_at;
+
+DROP TABLE IF EXISTS task_comments;
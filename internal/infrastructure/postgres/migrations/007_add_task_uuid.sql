@@ -0,0 +1,11 @@
+-- Add a UUID to every task so it can be addressed without exposing serial IDs.
+-- New rows get their UUID from the application; the default backfills existing rows.
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS uuid UUID NOT NULL DEFAULT gen_random_uuid();
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_uuid ON tasks(uuid);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_tasks_uuid;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS uuid;
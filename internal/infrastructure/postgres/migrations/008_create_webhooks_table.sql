@@ -0,0 +1,31 @@
+-- Create webhooks table: subscriptions that have task events POSTed to them
+CREATE TABLE IF NOT EXISTS webhooks (
+    id BIGSERIAL PRIMARY KEY,
+    url TEXT NOT NULL,
+    event_types TEXT[] NOT NULL DEFAULT '{}',
+    secret TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Events that could not be delivered after every retry
+CREATE TABLE IF NOT EXISTS webhook_dead_letters (
+    id BIGSERIAL PRIMARY KEY,
+    webhook_id BIGINT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_type VARCHAR(50) NOT NULL,
+    task_id BIGINT NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INT NOT NULL,
+    last_error TEXT NOT NULL,
+    failed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Dead letters are listed per webhook, newest first
+CREATE INDEX IF NOT EXISTS idx_webhook_dead_letters_webhook_id_failed_at ON webhook_dead_letters(webhook_id, failed_at);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_webhook_dead_letters_webhook_id_failed_at;
+
+DROP TABLE IF EXISTS webhook_dead_letters;
+
+DROP TABLE IF EXISTS webhooks;
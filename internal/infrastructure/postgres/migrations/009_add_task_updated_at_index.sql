@@ -0,0 +1,6 @@
+-- Index updated_at so the last modification of a task list (conditional GET) is cheap
+CREATE INDEX IF NOT EXISTS idx_tasks_updated_at ON tasks(updated_at);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_tasks_updated_at;
@@ -0,0 +1,12 @@
+-- Track when the due soon reminder of a task went out, so it goes out once
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS due_notified_at TIMESTAMPTZ;
+
+-- Partial index used by the due soon query: open tasks not yet reminded
+CREATE INDEX IF NOT EXISTS idx_tasks_due_date_unnotified ON tasks(due_date)
+    WHERE due_date IS NOT NULL AND due_notified_at IS NULL AND status NOT IN ('completed', 'cancelled');
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_tasks_due_date_unnotified;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS due_notified_at;
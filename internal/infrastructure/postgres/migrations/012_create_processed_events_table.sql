@@ -0,0 +1,12 @@
+-- Create processed events table: IDs of the Kafka events the consumer has processed
+CREATE TABLE IF NOT EXISTS processed_events (
+    event_id TEXT PRIMARY KEY,
+    processed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Old rows are deleted by processed_at
+CREATE INDEX IF NOT EXISTS idx_processed_events_processed_at ON processed_events(processed_at);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS processed_events;
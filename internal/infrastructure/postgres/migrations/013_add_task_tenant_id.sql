@@ -0,0 +1,17 @@
+-- Give every task a tenant. Tasks created before tenants belong to the default one.
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+-- Every query is scoped to a tenant, so the indexes used by lists lead with it
+CREATE INDEX IF NOT EXISTS idx_tasks_tenant_created_at ON tasks(tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_tasks_tenant_status ON tasks(tenant_id, status);
+CREATE INDEX IF NOT EXISTS idx_tasks_tenant_assigned_to ON tasks(tenant_id, assigned_to);
+CREATE INDEX IF NOT EXISTS idx_tasks_tenant_updated_at ON tasks(tenant_id, updated_at);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_tasks_tenant_updated_at;
+DROP INDEX IF EXISTS idx_tasks_tenant_assigned_to;
+DROP INDEX IF EXISTS idx_tasks_tenant_status;
+DROP INDEX IF EXISTS idx_tasks_tenant_created_at;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,14 @@
+-- Give every webhook and dead letter a tenant. Webhooks created before tenants
+-- belong to the default one and from now on only get its events.
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE webhook_dead_letters ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+-- Webhooks are listed per tenant, and loaded per tenant for every event
+CREATE INDEX IF NOT EXISTS idx_webhooks_tenant_id ON webhooks(tenant_id, id);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_webhooks_tenant_id;
+
+ALTER TABLE webhook_dead_letters DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE webhooks DROP COLUMN IF EXISTS tenant_id;
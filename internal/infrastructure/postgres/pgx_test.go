@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/buildinfo"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/metrics"
+	"github.com/seldomhappy/vibe_architecture/logger"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// monitorRunning reports whether a pool stats monitor goroutine is alive
+func monitorRunning() bool {
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+	return strings.Contains(string(buf), "(*DB).monitorPoolStats")
+}
+
+func TestShutdownStopsMonitor(t *testing.T) {
+	log := logger.New("test", logger.WithOutput(io.Discard))
+	// No connection is opened: MinConns is 0 and nothing is queried
+	db, err := New(Config{
+		DSN:          "postgres://test@127.0.0.1:1/test",
+		MaxOpenConns: 1,
+		Monitor:      PoolMonitorConfig{Interval: time.Millisecond},
+	}, log, metrics.New(buildinfo.Info{}, 0, false), noop.NewTracerProvider().Tracer("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.startMonitor()
+	deadline := time.Now().Add(time.Second)
+	for !monitorRunning() {
+		if time.Now().After(deadline) {
+			t.Fatal("monitor goroutine isn't running after startMonitor")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := db.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	select {
+	case <-db.monitorDone:
+	default:
+		t.Fatal("Shutdown returned before the monitor exited")
+	}
+	if monitorRunning() {
+		t.Error("monitor goroutine still running after Shutdown")
+	}
+}
@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RetryConfig controls retries of transient database errors
+type RetryConfig struct {
+	// MaxAttempts is the total number of tries, including the first; 1 disables retries
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry; it doubles on each retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between retries
+	MaxBackoff time.Duration
+}
+
+// transientCodes are SQLSTATEs after which the statement can safely be run again
+var transientCodes = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"57P01": true, // admin_shutdown
+	"57P02": true, // crash_shutdown
+	"57P03": true, // cannot_connect_now
+}
+
+// IsTransient reports whether err is a temporary failure worth retrying.
+// Connection failures are only retried when pgx guarantees nothing reached the
+// server, so a non-idempotent statement is never applied twice.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, pgx.ErrNoRows) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// Class 08 is connection_exception
+		return transientCodes[pgErr.Code] || strings.HasPrefix(pgErr.Code, "08")
+	}
+
+	return pgconn.SafeToRetry(err)
+}
+
+// withRetry runs fn until it succeeds, fails permanently, runs out of attempts
+// or ctx is done. The last error is returned.
+func (db *DB) withRetry(ctx context.Context, op string, fn func() error) error {
+	backoff := db.retry.InitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= db.retry.MaxAttempts || !IsTransient(err) {
+			return err
+		}
+
+		db.logger.Warn("Transient database error in %s (attempt %d/%d), retrying in %v: %v",
+			op, attempt, db.retry.MaxAttempts, backoff, err)
+		trace.SpanFromContext(ctx).AddEvent("db.retry")
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if backoff > db.retry.MaxBackoff {
+			backoff = db.retry.MaxBackoff
+		}
+	}
+}
+
+// retryRow defers QueryRow until Scan, where errors surface, so the query can be retried
+type retryRow struct {
+	db    *DB
+	ctx   context.Context
+	query string
+	args  []any
+}
+
+// Scan implements pgx.Row
+func (r *retryRow) Scan(dest ...any) error {
+	start := time.Now()
+
+	err := r.db.withRetry(r.ctx, "query_row", func() error {
+		return r.db.pool.QueryRow(r.ctx, r.query, r.args...).Scan(dest...)
+	})
+
+	status := "success"
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		status = "error"
+		trace.SpanFromContext(r.ctx).RecordError(err)
+	}
+
+	r.db.metrics.RecordDBQuery("query_row", status, time.Since(start))
+	return err
+}
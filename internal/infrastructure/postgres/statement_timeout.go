@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// deadlineTimeouts sets each connection's statement_timeout to what is left of
+// the deadline of the context it is acquired with, so PostgreSQL itself cancels a
+// query the caller has stopped waiting for. Connections acquired without a
+// deadline are put back to the server default.
+type deadlineTimeouts struct {
+	mu  sync.Mutex
+	set map[*pgx.Conn]bool // connections whose statement_timeout was changed
+}
+
+// install hooks the timeouts into the pool configuration
+func (d *deadlineTimeouts) install(cfg *pgxpool.Config) {
+	d.set = make(map[*pgx.Conn]bool)
+	cfg.BeforeAcquire = d.beforeAcquire
+	cfg.BeforeClose = d.forget
+}
+
+// beforeAcquire costs a round trip whenever the context has a deadline. A
+// connection that can't be configured is destroyed and another one is tried.
+func (d *deadlineTimeouts) beforeAcquire(ctx context.Context, conn *pgx.Conn) bool {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		if !d.isSet(conn) {
+			return true
+		}
+		if _, err := conn.Exec(ctx, "SET statement_timeout TO DEFAULT"); err != nil {
+			return false
+		}
+		d.mark(conn, false)
+		return true
+	}
+
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		// The query fails on the expired context anyway
+		return true
+	}
+	// statement_timeout is in milliseconds; round up so it never fires before the context does
+	ms := (remaining + time.Millisecond - 1) / time.Millisecond
+	if _, err := conn.Exec(ctx, fmt.Sprintf("SET statement_timeout = %d", ms)); err != nil {
+		return false
+	}
+	d.mark(conn, true)
+	return true
+}
+
+func (d *deadlineTimeouts) isSet(conn *pgx.Conn) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.set[conn]
+}
+
+func (d *deadlineTimeouts) mark(conn *pgx.Conn, set bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if set {
+		d.set[conn] = true
+	} else {
+		delete(d.set, conn)
+	}
+}
+
+func (d *deadlineTimeouts) forget(conn *pgx.Conn) {
+	d.mark(conn, false)
+}
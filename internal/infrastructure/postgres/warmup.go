@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// warmUp opens the pool's minimum number of connections before traffic arrives.
+// pgxpool creates them in the background after New, so without this the first
+// requests can still pay for connecting. Connections are acquired concurrently
+// and held until all are open, forcing the pool to create a new one for each.
+// A failed or timed out warm-up is only logged: the pool still works, just cold.
+func (db *DB) warmUp(ctx context.Context) {
+	target := int(db.pool.Config().MinConns)
+	if target == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, db.warmup)
+	defer cancel()
+
+	start := time.Now()
+	var (
+		mu       sync.Mutex
+		acquired []*pgxpool.Conn
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < target; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := db.pool.Acquire(ctx)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			acquired = append(acquired, conn)
+		}()
+	}
+	wg.Wait()
+	for _, conn := range acquired {
+		conn.Release()
+	}
+
+	duration := time.Since(start)
+	db.metrics.RecordDBPoolWarmup(len(acquired), duration)
+	if firstErr != nil {
+		db.logger.Warn("Database pool warm-up opened %d of %d connections in %v: %v", len(acquired), target, duration, firstErr)
+		return
+	}
+	db.logger.Info("Database pool warmed up with %d connections in %v", len(acquired), duration)
+}
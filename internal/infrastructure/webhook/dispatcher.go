@@ -0,0 +1,313 @@
+// Package webhook delivers task events to webhook subscribers over HTTP.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	pkgcontext "github.com/seldomhappy/vibe_architecture/internal/pkg/context"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/metrics"
+	"github.com/seldomhappy/vibe_architecture/logger"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Delivery outcomes counted in webhook_deliveries_total
+const (
+	ResultDelivered    = "delivered"
+	ResultFailed       = "failed"
+	ResultDeadLettered = "dead_lettered"
+	ResultDropped      = "dropped"
+)
+
+// Store is the part of the webhook repository the dispatcher uses
+type Store interface {
+	// GetAll returns the webhooks of the tenant of ctx
+	GetAll(ctx context.Context) ([]*domain.Webhook, error)
+	CreateDeadLetter(ctx context.Context, letter *domain.WebhookDeadLetter) error
+}
+
+// Config holds dispatcher configuration
+type Config struct {
+	// Workers is the number of deliveries made concurrently
+	Workers int
+	// QueueSize is how many deliveries may wait for a worker, and how many
+	// events for them to be made; more are dropped
+	QueueSize int
+	// Timeout bounds a single delivery attempt
+	Timeout time.Duration
+	// MaxAttempts is how often a delivery is tried before it is dead-lettered
+	MaxAttempts int
+	// InitialBackoff is the wait after the first failed attempt; it doubles
+	// after each further one, up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// delivery is one event on its way to one webhook
+type delivery struct {
+	id      string // sent in X-Webhook-Delivery, the same on every attempt
+	webhook *domain.Webhook
+	event   domain.TaskEvent
+	body    []byte
+}
+
+// Dispatcher POSTs the task events it receives from the event bus to every
+// webhook of the event's tenant subscribed to them. Each request carries an HMAC-SHA256 signature of the body
+// made with the webhook's secret. Failed deliveries are retried with exponential
+// backoff and stored as dead letters once MaxAttempts have failed.
+type Dispatcher struct {
+	cfg     Config
+	store   Store
+	client  *http.Client
+	metrics *metrics.Metrics
+	logger  logger.ILogger
+
+	events chan domain.TaskEvent
+	queue  chan delivery
+	stop   chan struct{}
+	ctx    context.Context // cancelled to abandon in-flight deliveries
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewDispatcher creates a dispatcher that delivers with client. Subscribe it
+// to the event bus.
+func NewDispatcher(cfg Config, store Store, client *http.Client, m *metrics.Metrics, log logger.ILogger) *Dispatcher {
+	return &Dispatcher{
+		cfg:     cfg,
+		store:   store,
+		client:  client,
+		metrics: m,
+		logger:  log,
+		events:  make(chan domain.TaskEvent, cfg.QueueSize),
+		queue:   make(chan delivery, cfg.QueueSize),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Start starts the delivery workers
+func (d *Dispatcher) Start(ctx context.Context) error {
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+
+	d.wg.Add(1)
+	go d.run()
+	for i := 0; i < d.cfg.Workers; i++ {
+		d.wg.Add(1)
+		go d.work()
+	}
+
+	d.logger.Info("Webhook dispatcher started with %d workers", d.cfg.Workers)
+	return nil
+}
+
+// Shutdown stops taking new events and waits for queued deliveries. Those still
+// pending when ctx ends are abandoned and dead-lettered.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.logger.Info("Shutting down webhook dispatcher")
+	close(d.stop)
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// HandleEvent takes an event from the event bus. It never blocks: the event
+// is dropped when the dispatcher is shut down or too far behind.
+func (d *Dispatcher) HandleEvent(ctx context.Context, event domain.TaskEvent) error {
+	select {
+	case <-d.stop:
+		return errors.New("webhook dispatcher is shut down")
+	default:
+	}
+
+	select {
+	case d.events <- event:
+		return nil
+	default:
+		return fmt.Errorf("webhook event buffer of %d is full", d.cfg.QueueSize)
+	}
+}
+
+// run queues a delivery per subscribed webhook for every event received
+func (d *Dispatcher) run() {
+	defer d.wg.Done()
+	defer close(d.queue)
+
+	for {
+		select {
+		case <-d.stop:
+			return
+		case event := <-d.events:
+			d.dispatch(event)
+		}
+	}
+}
+
+func (d *Dispatcher) dispatch(event domain.TaskEvent) {
+	ctx, cancel := context.WithTimeout(d.ctx, d.cfg.Timeout)
+	defer cancel()
+
+	webhooks, err := d.store.GetAll(pkgcontext.WithTenantID(ctx, event.TenantID))
+	if err != nil {
+		d.logger.Error("Failed to load webhooks, not delivering %s event for task %d: %v", event.Type, event.TaskID, err)
+		return
+	}
+
+	var body []byte
+	for _, webhook := range webhooks {
+		if webhook.TenantID != event.TenantID || !webhook.Accepts(event.Type) {
+			continue
+		}
+		if body == nil {
+			if body, err = json.Marshal(event); err != nil {
+				d.logger.Error("Failed to marshal %s event for task %d: %v", event.Type, event.TaskID, err)
+				return
+			}
+		}
+
+		select {
+		case d.queue <- delivery{id: uuid.NewString(), webhook: webhook, event: event, body: body}:
+		default:
+			d.metrics.RecordWebhookDelivery(webhook.ID, ResultDropped)
+			d.logger.Warn("Webhook queue of %d is full, dropping %s event for webhook %d",
+				d.cfg.QueueSize, event.Type, webhook.ID)
+		}
+	}
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for del := range d.queue {
+		d.deliver(del)
+	}
+}
+
+// deliver makes up to MaxAttempts attempts, then dead-letters the event
+func (d *Dispatcher) deliver(del delivery) {
+	backoff := d.cfg.InitialBackoff
+	attempts := 0
+	var lastErr error
+	for attempts < d.cfg.MaxAttempts && d.ctx.Err() == nil {
+		attempts++
+		retryable, err := d.post(del)
+		if err == nil {
+			d.metrics.RecordWebhookDelivery(del.webhook.ID, ResultDelivered)
+			return
+		}
+		lastErr = err
+		d.metrics.RecordWebhookDelivery(del.webhook.ID, ResultFailed)
+		d.logger.Warn("Webhook %d delivery attempt %d of %s event failed: %v",
+			del.webhook.ID, attempts, del.event.Type, err)
+		if !retryable || attempts == d.cfg.MaxAttempts {
+			break
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-d.ctx.Done():
+		}
+		backoff = min(backoff*2, d.cfg.MaxBackoff)
+	}
+	if lastErr == nil {
+		lastErr = errors.New("dispatcher shut down before delivery")
+	}
+
+	d.deadLetter(del, attempts, lastErr)
+}
+
+// post makes a single delivery attempt. Network errors, 408, 429 and 5xx
+// responses are worth retrying; other failures are not.
+func (d *Dispatcher) post(del delivery) (retryable bool, err error) {
+	ctx, cancel := context.WithTimeout(d.ctx, d.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, del.webhook.URL, bytes.NewReader(del.body))
+	if err != nil {
+		return false, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, string(del.event.Type))
+	req.Header.Set(HeaderDelivery, del.id)
+	req.Header.Set(HeaderSignature, Sign(del.webhook.Secret, del.body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	// Drained so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retryable = resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout ||
+		resp.StatusCode == http.StatusTooManyRequests
+	return retryable, fmt.Errorf("unexpected status %d", resp.StatusCode)
+}
+
+// deadLetter stores an undelivered event. It runs even during shutdown, as the
+// database is closed only after the dispatcher.
+func (d *Dispatcher) deadLetter(del delivery, attempts int, lastErr error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(d.ctx), d.cfg.Timeout)
+	defer cancel()
+
+	letter := &domain.WebhookDeadLetter{
+		WebhookID: del.webhook.ID,
+		EventType: del.event.Type,
+		TaskID:    del.event.TaskID,
+		Payload:   del.body,
+		Attempts:  attempts,
+		LastError: lastErr.Error(),
+	}
+	if err := d.store.CreateDeadLetter(ctx, letter); err != nil {
+		if errors.Is(err, domain.ErrWebhookNotFound) {
+			// Deleted meanwhile; nobody is left to replay it for
+			return
+		}
+		d.logger.Error("Failed to dead-letter %s event for webhook %d, it is lost: %v", del.event.Type, del.webhook.ID, err)
+		return
+	}
+
+	d.metrics.RecordWebhookDelivery(del.webhook.ID, ResultDeadLettered)
+	d.logger.Error("Gave up delivering %s event for task %d to webhook %d after %d attempts: %v",
+		del.event.Type, del.event.TaskID, del.webhook.ID, attempts, lastErr)
+}
+
+// Sign returns the X-Webhook-Signature value for body: "sha256=" followed by
+// the hex-encoded HMAC-SHA256 of body keyed with secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
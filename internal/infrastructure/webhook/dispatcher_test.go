@@ -0,0 +1,83 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/buildinfo"
+	pkgcontext "github.com/seldomhappy/vibe_architecture/internal/pkg/context"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/metrics"
+	"github.com/seldomhappy/vibe_architecture/internal/repository/memory"
+	"github.com/seldomhappy/vibe_architecture/logger"
+)
+
+func TestDispatchOnlyToWebhooksOfTheEventTenant(t *testing.T) {
+	log := logger.New("test", logger.WithOutput(io.Discard))
+
+	var (
+		mu   sync.Mutex
+		hits = map[string]int{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	store := memory.NewWebhookRepository(log)
+	for _, tenant := range []string{"acme", "globex"} {
+		ctx := pkgcontext.WithTenantID(context.Background(), tenant)
+		webhook := &domain.Webhook{TenantID: tenant, URL: server.URL + "/" + tenant, EventTypes: []domain.EventType{}}
+		if err := store.Create(ctx, webhook); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := NewDispatcher(Config{Workers: 1, QueueSize: 10, Timeout: time.Second, MaxAttempts: 1},
+		store, server.Client(), metrics.New(buildinfo.Info{}, 0, false), log)
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	event := domain.TaskEvent{Type: domain.EventTypeTaskCreated, TaskID: 1, TenantID: "acme"}
+	if err := d.HandleEvent(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		delivered := hits["/acme"]
+		mu.Unlock()
+		if delivered > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("acme webhook got no delivery")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// The event has been dispatched; Shutdown waits for whatever it queued
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := d.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if hits["/acme"] != 1 {
+		t.Errorf("acme webhook got %d deliveries, want 1", hits["/acme"])
+	}
+	if hits["/globex"] != 0 {
+		t.Errorf("globex webhook got %d deliveries of an acme event, want 0", hits["/globex"])
+	}
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/clock"
+)
+
+const testSecret = "test-secret-0123456789abcdef-0123456789"
+
+func TestVerifierRoundTrip(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	v, err := NewVerifier(testSecret, clock.NewMock(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Claims{UserID: 42, TenantID: "acme", Role: "admin", ExpiresAt: now.Add(time.Hour)}
+	got, err := v.Verify(v.Sign(want))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if got.UserID != want.UserID || got.TenantID != want.TenantID || got.Role != want.Role || !got.ExpiresAt.Equal(want.ExpiresAt) {
+		t.Errorf("Verify = %+v, want %+v", got, want)
+	}
+}
+
+func TestVerifierRejects(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	v, _ := NewVerifier(testSecret, clock.NewMock(now))
+	other, _ := NewVerifier(strings.Repeat("x", MinSecretLength), clock.NewMock(now))
+	valid := Claims{UserID: 42, TenantID: "acme", ExpiresAt: now.Add(time.Hour)}
+
+	token := v.Sign(valid)
+	parts := strings.Split(token, ".")
+	// {"alg":"none"} with the original claims and no signature
+	unsigned := "eyJhbGciOiJub25lIn0." + parts[1] + "."
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"empty", "", ErrInvalidToken},
+		{"not a jwt", "abc", ErrInvalidToken},
+		{"other secret", other.Sign(valid), ErrInvalidToken},
+		{"tampered claims", parts[0] + "." + strings.TrimRight(parts[1], "=") + "x." + parts[2], ErrInvalidToken},
+		{"alg none", unsigned, ErrInvalidToken},
+		{"no user", v.Sign(Claims{TenantID: "acme", ExpiresAt: now.Add(time.Hour)}), ErrInvalidToken},
+		{"expired", v.Sign(Claims{UserID: 42, TenantID: "acme", ExpiresAt: now}), ErrTokenExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := v.Verify(tt.token); !errors.Is(err, tt.want) {
+				t.Errorf("Verify error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewVerifierShortSecret(t *testing.T) {
+	if _, err := NewVerifier(strings.Repeat("x", MinSecretLength-1), nil); err == nil {
+		t.Error("NewVerifier accepted a secret shorter than MinSecretLength")
+	}
+}
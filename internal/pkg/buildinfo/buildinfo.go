@@ -0,0 +1,66 @@
+// Package buildinfo describes the running binary. Version, Commit and
+// BuildTime are set at link time, e.g.
+//
+//	go build -ldflags "-X github.com/seldomhappy/vibe_architecture/internal/pkg/buildinfo.Commit=$(git rev-parse HEAD)"
+//
+// Without them, the commit and time are read from the VCS stamp the go command
+// embeds when building inside a git checkout.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Set with -ldflags "-X ..."; empty means unknown
+var (
+	Version   string
+	Commit    string
+	BuildTime string
+)
+
+// Unknown is reported for metadata the build didn't provide
+const Unknown = "unknown"
+
+// Info is the build metadata of the running binary
+type Info struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build metadata of the application called name. version is
+// used when no version was set at link time.
+func Get(name, version string) Info {
+	info := Info{
+		Name:      name,
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+	if info.Version == "" {
+		info.Version = version
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = setting.Value
+			}
+		}
+	}
+
+	if info.Commit == "" {
+		info.Commit = Unknown
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = Unknown
+	}
+	return info
+}
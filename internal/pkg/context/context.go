@@ -11,6 +11,8 @@ type contextKey string
 const (
 	requestIDKey     contextKey = "request_id"
 	userIDKey        contextKey = "user_id"
+	userRoleKey      contextKey = "user_role"
+	tenantIDKey      contextKey = "tenant_id"
 	correlationIDKey contextKey = "correlation_id"
 )
 
@@ -40,6 +42,49 @@ func GetUserID(ctx context.Context) int64 {
 	return 0
 }
 
+// WithUserRole adds the authenticated user's role to the context
+func WithUserRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, userRoleKey, role)
+}
+
+// GetUserRole retrieves the user role from the context
+func GetUserRole(ctx context.Context) string {
+	if role, ok := ctx.Value(userRoleKey).(string); ok {
+		return role
+	}
+	return ""
+}
+
+// allTenants is stored as the tenant of contexts that act for every tenant
+type allTenants struct{}
+
+// WithTenantID adds the tenant the request acts for to the context
+func WithTenantID(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantIDKey, tenantID)
+}
+
+// WithAllTenants makes the context act for every tenant, as background jobs
+// do. It replaces a tenant set with WithTenantID, and vice versa.
+func WithAllTenants(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tenantIDKey, allTenants{})
+}
+
+// IsAllTenants reports whether the context acts for every tenant
+func IsAllTenants(ctx context.Context) bool {
+	_, ok := ctx.Value(tenantIDKey).(allTenants)
+	return ok
+}
+
+// GetTenantID retrieves the tenant ID from the context. It is empty outside of
+// requests and for contexts made with WithAllTenants; an empty tenant sees no
+// tenant's data.
+func GetTenantID(ctx context.Context) string {
+	if tenantID, ok := ctx.Value(tenantIDKey).(string); ok {
+		return tenantID
+	}
+	return ""
+}
+
 // WithCorrelationID adds a correlation ID to the context
 func WithCorrelationID(ctx context.Context, correlationID string) context.Context {
 	return context.WithValue(ctx, correlationIDKey, correlationID)
@@ -0,0 +1,193 @@
+// Package eventbus hands the domain events published by the use cases to every
+// registered sink (Kafka, live subscribers, webhooks), so business logic doesn't
+// depend on any transport.
+package eventbus
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	pkgcontext "github.com/seldomhappy/vibe_architecture/internal/pkg/context"
+	"github.com/seldomhappy/vibe_architecture/logger"
+)
+
+// Delivery modes
+const (
+	// ModeSync hands an event to every sink before Publish returns
+	ModeSync = "sync"
+	// ModeAsync queues events and hands them to the sinks in the background
+	ModeAsync = "async"
+)
+
+// Sink receives the events published on the bus
+type Sink interface {
+	HandleEvent(ctx context.Context, event domain.TaskEvent) error
+}
+
+// SinkFunc adapts a function to a Sink
+type SinkFunc func(ctx context.Context, event domain.TaskEvent) error
+
+// HandleEvent calls f
+func (f SinkFunc) HandleEvent(ctx context.Context, event domain.TaskEvent) error {
+	return f(ctx, event)
+}
+
+// Config holds event bus configuration
+type Config struct {
+	// Mode is ModeSync or ModeAsync
+	Mode string
+	// Timeout bounds each sink's handling of an event
+	Timeout time.Duration
+	// BufferSize is how many events ModeAsync queues; more are dropped
+	BufferSize int
+}
+
+// sink is a registered sink and the name its failures are logged with
+type sink struct {
+	name string
+	sink Sink
+}
+
+// queued is an event waiting for the async worker
+type queued struct {
+	ctx   context.Context
+	event domain.TaskEvent
+}
+
+// Bus fans the events published by the use cases out to its sinks, one sink
+// after the other in the order they subscribed. A failing sink is logged and
+// doesn't keep the event from the others.
+type Bus struct {
+	cfg    Config
+	logger logger.ILogger
+
+	mu     sync.RWMutex
+	sinks  []sink
+	queue  chan queued
+	closed bool
+	done   chan struct{}
+}
+
+// New creates a new event bus
+func New(cfg Config, log logger.ILogger) *Bus {
+	b := &Bus{
+		cfg:    cfg,
+		logger: log,
+		done:   make(chan struct{}),
+	}
+	if cfg.Mode == ModeAsync {
+		b.queue = make(chan queued, cfg.BufferSize)
+	}
+	return b
+}
+
+// Subscribe registers a sink under name. A sink subscribed twice under the same
+// name receives events once.
+func (b *Bus) Subscribe(name string, s Sink) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.removeLocked(name)
+	b.sinks = append(b.sinks, sink{name: name, sink: s})
+}
+
+// Unsubscribe removes the sink registered under name
+func (b *Bus) Unsubscribe(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.removeLocked(name)
+}
+
+func (b *Bus) removeLocked(name string) {
+	for i, s := range b.sinks {
+		if s.name == name {
+			b.sinks = append(b.sinks[:i:i], b.sinks[i+1:]...)
+			return
+		}
+	}
+}
+
+// Start implements lifecycle.Service. It starts the async worker.
+func (b *Bus) Start(ctx context.Context) error {
+	if b.queue == nil {
+		close(b.done)
+		b.logger.Info("Event bus started (sync)")
+		return nil
+	}
+
+	go b.run()
+	b.logger.Info("Event bus started (async, buffer: %d)", b.cfg.BufferSize)
+	return nil
+}
+
+// Shutdown waits for the queued events to reach the sinks. Events published
+// afterwards are delivered before Publish returns, whatever the mode.
+func (b *Bus) Shutdown(ctx context.Context) error {
+	b.logger.Info("Shutting down event bus")
+
+	b.mu.Lock()
+	if !b.closed && b.queue != nil {
+		close(b.queue)
+	}
+	b.closed = true
+	b.mu.Unlock()
+
+	select {
+	case <-b.done:
+		return nil
+	case <-ctx.Done():
+		b.logger.Error("Event bus shut down with %d events undelivered", len(b.queue))
+		return ctx.Err()
+	}
+}
+
+// Publish hands an event to the sinks. The event outlives the cancellation of
+// ctx, which only carries the request and trace IDs along.
+func (b *Bus) Publish(ctx context.Context, event domain.TaskEvent) {
+	ctx = context.WithoutCancel(ctx)
+
+	b.mu.RLock()
+	if b.queue == nil || b.closed {
+		b.mu.RUnlock()
+		b.deliver(ctx, event)
+		return
+	}
+	defer b.mu.RUnlock()
+
+	select {
+	case b.queue <- queued{ctx: ctx, event: event}:
+	default:
+		b.logger.Warn("[%s][trace:%s] Event bus buffer of %d is full, dropping %s event for task %d",
+			pkgcontext.GetRequestID(ctx), pkgcontext.GetTraceID(ctx), b.cfg.BufferSize, event.Type, event.TaskID)
+	}
+}
+
+func (b *Bus) run() {
+	defer close(b.done)
+	for q := range b.queue {
+		b.deliver(q.ctx, q.event)
+	}
+}
+
+// deliver hands an event to every sink in turn
+func (b *Bus) deliver(ctx context.Context, event domain.TaskEvent) {
+	b.mu.RLock()
+	sinks := b.sinks
+	b.mu.RUnlock()
+
+	for _, s := range sinks {
+		if err := b.handle(ctx, s, event); err != nil {
+			b.logger.Warn("[%s][trace:%s] Event sink %s failed to handle %s event for task %d: %v",
+				pkgcontext.GetRequestID(ctx), pkgcontext.GetTraceID(ctx), s.name, event.Type, event.TaskID, err)
+		}
+	}
+}
+
+func (b *Bus) handle(ctx context.Context, s sink, event domain.TaskEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, b.cfg.Timeout)
+	defer cancel()
+	return s.sink.HandleEvent(ctx, event)
+}
@@ -0,0 +1,170 @@
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Dependency states reported by a Checker
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusDisabled = "disabled"
+)
+
+// Overall readiness reported by a Checker
+const (
+	StatusReady    = "ready"
+	StatusNotReady = "not_ready"
+)
+
+// CheckFunc reports whether a dependency is usable
+type CheckFunc func(ctx context.Context) error
+
+// Result is the state of a single dependency
+type Result struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report is the state of every dependency. The service is ready when no
+// dependency is down; disabled dependencies don't count against it.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Ready reports whether every enabled dependency is up
+func (r Report) Ready() bool {
+	return r.Status == StatusReady
+}
+
+// Observer is told the result of every dependency check; changed is set when
+// the dependency's status differs from the previous check
+type Observer func(name string, result Result, changed bool)
+
+// Checker checks the dependencies the service needs to serve traffic
+type Checker struct {
+	timeout time.Duration
+
+	mu        sync.RWMutex
+	checks    map[string]CheckFunc
+	disabled  map[string]bool
+	last      map[string]string
+	observers []Observer
+}
+
+// New creates a checker that gives each check at most timeout
+func New(timeout time.Duration) *Checker {
+	return &Checker{
+		timeout:  timeout,
+		checks:   make(map[string]CheckFunc),
+		disabled: make(map[string]bool),
+		last:     make(map[string]string),
+	}
+}
+
+// Observe adds an observer of the checks' results
+func (c *Checker) Observe(observer Observer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.observers = append(c.observers, observer)
+}
+
+// Register adds a dependency check
+func (c *Checker) Register(name string, check CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check
+	delete(c.disabled, name)
+}
+
+// Disable records a dependency that is turned off on purpose. It is reported
+// as disabled and never makes the service unready.
+func (c *Checker) Disable(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.disabled[name] = true
+	delete(c.checks, name)
+	delete(c.last, name)
+}
+
+// Check runs every check concurrently and reports the results
+func (c *Checker) Check(ctx context.Context) Report {
+	c.mu.RLock()
+	names := make([]string, 0, len(c.checks))
+	for name := range c.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checks := make([]CheckFunc, len(names))
+	for i, name := range names {
+		checks[i] = c.checks[name]
+	}
+	report := Report{Status: StatusReady, Checks: make(map[string]Result, len(names)+len(c.disabled))}
+	for name := range c.disabled {
+		report.Checks[name] = Result{Status: StatusDisabled}
+	}
+	c.mu.RUnlock()
+
+	results := make([]Result, len(names))
+	var wg sync.WaitGroup
+	for i := range names {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = c.run(ctx, checks[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status != StatusUp {
+			report.Status = StatusNotReady
+		}
+	}
+	c.notify(names, results)
+	return report
+}
+
+// notify tells the observers the results and which of them changed since the
+// previous check. The first result of a dependency counts as a change.
+func (c *Checker) notify(names []string, results []Result) {
+	c.mu.Lock()
+	changed := make([]bool, len(names))
+	for i, name := range names {
+		changed[i] = c.last[name] != results[i].Status
+		c.last[name] = results[i].Status
+	}
+	observers := c.observers
+	c.mu.Unlock()
+
+	for _, observer := range observers {
+		for i, name := range names {
+			observer(name, results[i], changed[i])
+		}
+	}
+}
+
+// run runs one check, giving up once the timeout passes even if the check doesn't
+func (c *Checker) run(ctx context.Context, check CheckFunc) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- check(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		return Result{Status: StatusDown, Error: err.Error()}
+	}
+	return Result{Status: StatusUp}
+}
@@ -0,0 +1,107 @@
+// Package httpclient builds the HTTP clients of outbound calls. Unlike
+// http.DefaultClient they have timeouts, so a peer that stops answering can't
+// hang a caller, and every request is traced and timed.
+package httpclient
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/metrics"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Config holds HTTP client configuration
+type Config struct {
+	// Timeout bounds a whole request, from dialing to reading the response body
+	Timeout time.Duration
+	// ConnectTimeout bounds establishing a connection, TLS handshake included
+	ConnectTimeout time.Duration
+	// ResponseHeaderTimeout bounds waiting for the response headers once the
+	// request is sent
+	ResponseHeaderTimeout time.Duration
+	// MaxIdleConns caps the idle connections kept for reuse, MaxIdleConnsPerHost
+	// those to a single host
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout is how long an idle connection is kept
+	IdleConnTimeout time.Duration
+	// KeepAlive is the interval of TCP keep-alive probes; negative disables them
+	KeepAlive time.Duration
+}
+
+// New creates an HTTP client. Its requests are traced and recorded in
+// http_client_request_duration_seconds under name.
+func New(name string, cfg Config, m *metrics.Metrics) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   cfg.ConnectTimeout,
+		KeepAlive: cfg.KeepAlive,
+	}
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   cfg.ConnectTimeout,
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		ForceAttemptHTTP2:     true,
+	}
+
+	return &http.Client{
+		Timeout: cfg.Timeout,
+		Transport: &instrumentedTransport{
+			name:    name,
+			next:    transport,
+			metrics: m,
+		},
+	}
+}
+
+// instrumentedTransport traces and times the requests of a client
+type instrumentedTransport struct {
+	name    string
+	next    http.RoundTripper
+	metrics *metrics.Metrics
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	ctx, span := tracing.StartSpan(req.Context(), "http-client", fmt.Sprintf("%s %s", req.Method, req.URL.Host),
+		trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("http.client", t.name),
+		attribute.String("http.method", req.Method),
+		attribute.String("http.host", req.URL.Host),
+	)
+
+	// The peer continues the trace; the request is cloned since a RoundTripper
+	// must not modify it
+	req = req.Clone(ctx)
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		t.metrics.RecordHTTPClientRequest(t.name, req.URL.Host, "error", time.Since(start))
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+	if resp.StatusCode >= 500 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	t.metrics.RecordHTTPClientRequest(t.name, req.URL.Host, strconv.Itoa(resp.StatusCode), time.Since(start))
+	return resp, nil
+}
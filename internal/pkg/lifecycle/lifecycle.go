@@ -2,7 +2,14 @@ package lifecycle
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/seldomhappy/vibe_architecture/logger"
+	"golang.org/x/sync/errgroup"
 )
 
 // Service represents a service that can be started and stopped
@@ -11,43 +18,251 @@ type Service interface {
 	Shutdown(ctx context.Context) error
 }
 
+// Phase groups services that shut down together.
+// Phases shut down in ascending order; services within a phase shut down concurrently.
+type Phase int
+
+const (
+	// PhaseIngress stops new work from arriving: HTTP server, event streams
+	PhaseIngress Phase = iota
+	// PhaseServices drains in-flight work: consumers, schedulers. This is the default.
+	PhaseServices
+	// PhaseClients closes connections the earlier phases depended on: database, producers
+	PhaseClients
+	// PhaseTelemetry goes last so the rest of shutdown is still observable
+	PhaseTelemetry
+)
+
+// Option configures how a service is managed
+type Option func(*entry)
+
+// WithShutdownPhase places the service in the given shutdown phase
+func WithShutdownPhase(phase Phase) Option {
+	return func(e *entry) {
+		e.phase = phase
+	}
+}
+
+// WithDependsOn starts the service as soon as the named services have started,
+// concurrently with anything else that is ready. The dependencies must be
+// registered before the service.
+func WithDependsOn(names ...string) Option {
+	return func(e *entry) {
+		e.dependsOn = names
+		e.explicitDeps = true
+	}
+}
+
+// WithParallelStart lets the service start without waiting for any other service
+func WithParallelStart() Option {
+	return WithDependsOn()
+}
+
+// entry is a registered service and its settings
+type entry struct {
+	name    string
+	service Service
+	phase   Phase
+	// dependsOn lists the services that must start first. Without explicitDeps
+	// the service waits for every service registered before it.
+	dependsOn    []string
+	explicitDeps bool
+}
+
 // Manager manages the lifecycle of multiple services
 type Manager struct {
-	services []Service
-	names    []string
+	entries      []*entry
+	phaseTimeout time.Duration
+	// serviceTimeouts bound the shutdown of single services, by name
+	serviceTimeouts map[string]time.Duration
+	logger          logger.ILogger
 }
 
 // New creates a new lifecycle manager
-func New() *Manager {
+func New(log logger.ILogger) *Manager {
 	return &Manager{
-		services: make([]Service, 0),
-		names:    make([]string, 0),
+		entries: make([]*entry, 0),
+		logger:  log,
 	}
 }
 
+// SetPhaseTimeout bounds how long each shutdown phase may take, so one hanging
+// phase can't use up the whole shutdown budget. Zero means no per-phase limit.
+func (m *Manager) SetPhaseTimeout(timeout time.Duration) {
+	m.phaseTimeout = timeout
+}
+
+// SetServiceTimeouts bounds the shutdown of the named services, within their
+// phase's timeout. Other services get the whole phase.
+func (m *Manager) SetServiceTimeouts(timeouts map[string]time.Duration) {
+	m.serviceTimeouts = timeouts
+}
+
 // Register registers a service with the lifecycle manager
-func (m *Manager) Register(name string, service Service) {
-	m.services = append(m.services, service)
-	m.names = append(m.names, name)
+func (m *Manager) Register(name string, service Service, opts ...Option) {
+	e := &entry{name: name, service: service, phase: PhaseServices}
+	for _, opt := range opts {
+		opt(e)
+	}
+	m.entries = append(m.entries, e)
 }
 
-// StartAll starts all registered services in order
+// StartAll starts all registered services. A service starts once its
+// dependencies have started; by default that is every service registered
+// before it, so services start one at a time in registration order.
+// The first failure is returned and services still waiting to start are skipped.
 func (m *Manager) StartAll(ctx context.Context) error {
-	for i, service := range m.services {
-		if err := service.Start(ctx); err != nil {
-			return fmt.Errorf("failed to start %s: %w", m.names[i], err)
+	for name := range m.serviceTimeouts {
+		if !m.registered(name) {
+			return fmt.Errorf("shutdown timeout set for unknown service %s", name)
 		}
 	}
-	return nil
+
+	started := make(map[string]chan struct{}, len(m.entries))
+	deps := make([][]chan struct{}, len(m.entries))
+	for i, e := range m.entries {
+		if !e.explicitDeps {
+			for _, prev := range m.entries[:i] {
+				deps[i] = append(deps[i], started[prev.name])
+			}
+		}
+		for _, name := range e.dependsOn {
+			ch, ok := started[name]
+			if !ok {
+				return fmt.Errorf("failed to start %s: dependency %s is not registered before it", e.name, name)
+			}
+			deps[i] = append(deps[i], ch)
+		}
+		started[e.name] = make(chan struct{})
+	}
+
+	// Only waiting is cancelled on failure: services get ctx itself, since some
+	// keep it for background work that must outlive StartAll
+	g, gctx := errgroup.WithContext(ctx)
+	for i, e := range m.entries {
+		i, e := i, e
+		g.Go(func() error {
+			for _, dep := range deps[i] {
+				select {
+				case <-dep:
+				case <-gctx.Done():
+					return gctx.Err()
+				}
+			}
+			if err := e.service.Start(ctx); err != nil {
+				return fmt.Errorf("failed to start %s: %w", e.name, err)
+			}
+			close(started[e.name])
+			return nil
+		})
+	}
+	return g.Wait()
 }
 
-// ShutdownAll shuts down all registered services in reverse order
+// registered reports whether a service is registered under name
+func (m *Manager) registered(name string) bool {
+	for _, e := range m.entries {
+		if e.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ShutdownAll shuts down all registered services phase by phase. The failures
+// of every service that didn't shut down cleanly are joined into the error,
+// one per service; ShutdownErrors splits them up again.
 func (m *Manager) ShutdownAll(ctx context.Context) error {
-	var lastErr error
-	for i := len(m.services) - 1; i >= 0; i-- {
-		if err := m.services[i].Shutdown(ctx); err != nil {
-			lastErr = fmt.Errorf("failed to shutdown %s: %w", m.names[i], err)
+	var errs []error
+	for _, phase := range m.phases() {
+		errs = append(errs, m.shutdownPhase(ctx, phase)...)
+	}
+	return errors.Join(errs...)
+}
+
+// ShutdownErrors returns the failures of the single services joined in an
+// error from ShutdownAll
+func ShutdownErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+// phases returns the distinct phases in use, in shutdown order
+func (m *Manager) phases() []Phase {
+	seen := make(map[Phase]bool)
+	var phases []Phase
+	for _, e := range m.entries {
+		if !seen[e.phase] {
+			seen[e.phase] = true
+			phases = append(phases, e.phase)
 		}
 	}
-	return lastErr
+	sort.Slice(phases, func(i, j int) bool { return phases[i] < phases[j] })
+	return phases
+}
+
+// shutdownPhase shuts down the services of one phase concurrently and returns
+// their failures
+func (m *Manager) shutdownPhase(ctx context.Context, phase Phase) []error {
+	if m.phaseTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.phaseTimeout)
+		defer cancel()
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, e := range m.entries {
+		if e.phase != phase {
+			continue
+		}
+		wg.Add(1)
+		go func(e *entry) {
+			defer wg.Done()
+			if err := m.shutdownService(ctx, e); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(e)
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// shutdownService shuts down one service within its timeout and logs how long
+// it took, and whether it ran out of time
+func (m *Manager) shutdownService(ctx context.Context, e *entry) error {
+	if timeout := m.serviceTimeouts[e.name]; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	budget := "unlimited"
+	if deadline, ok := ctx.Deadline(); ok {
+		budget = deadline.Sub(start).Round(time.Millisecond).String()
+	}
+
+	err := e.service.Shutdown(ctx)
+	took := time.Since(start).Round(time.Millisecond)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		m.logger.Error("Service %s exceeded its shutdown budget of %s (took %s)", e.name, budget, took)
+	} else {
+		m.logger.Info("Service %s shut down in %s", e.name, took)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to shutdown %s: %w", e.name, err)
+	}
+	return nil
 }
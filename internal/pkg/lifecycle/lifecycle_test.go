@@ -0,0 +1,44 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/seldomhappy/vibe_architecture/logger"
+)
+
+// stubService starts cleanly and fails to shut down with shutdownErr, if set
+type stubService struct {
+	shutdownErr error
+}
+
+func (s stubService) Start(ctx context.Context) error    { return nil }
+func (s stubService) Shutdown(ctx context.Context) error { return s.shutdownErr }
+
+func TestShutdownAllReportsEveryFailure(t *testing.T) {
+	m := New(logger.New("test", logger.WithOutput(io.Discard)))
+	m.Register("http", stubService{shutdownErr: errors.New("listener stuck")}, WithShutdownPhase(PhaseIngress))
+	m.Register("consumer", stubService{shutdownErr: errors.New("commit failed")})
+	m.Register("scheduler", stubService{shutdownErr: errors.New("job still running")})
+	m.Register("cache", stubService{})
+	m.Register("database", stubService{shutdownErr: errors.New("pool busy")}, WithShutdownPhase(PhaseClients))
+
+	err := m.ShutdownAll(context.Background())
+	if err == nil {
+		t.Fatal("ShutdownAll returned nil, want the failures")
+	}
+	for _, name := range []string{"http", "consumer", "scheduler", "database"} {
+		if !strings.Contains(err.Error(), "failed to shutdown "+name) {
+			t.Errorf("error doesn't mention %s: %v", name, err)
+		}
+	}
+	if strings.Contains(err.Error(), "cache") {
+		t.Errorf("error mentions cache, which shut down cleanly: %v", err)
+	}
+	if got := len(ShutdownErrors(err)); got != 4 {
+		t.Errorf("ShutdownErrors returned %d errors, want 4", got)
+	}
+}
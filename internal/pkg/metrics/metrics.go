@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/buildinfo"
 )
 
 // Metrics holds all Prometheus metrics
@@ -17,6 +19,10 @@ type Metrics struct {
 	HTTPRequestsTotal      *prometheus.CounterVec
 	HTTPRequestDuration    *prometheus.HistogramVec
 	HTTPRequestsInFlight   prometheus.Gauge
+	HTTPConcurrentRequests prometheus.Gauge
+	HTTPRequestsShed       prometheus.Counter
+	PanicsTotal            *prometheus.CounterVec
+	ValidationFailures     *prometheus.CounterVec
 
 	// Business metrics
 	TasksCreatedTotal      prometheus.Counter
@@ -30,6 +36,23 @@ type Metrics struct {
 	DBConnectionsIdle      prometheus.Gauge
 	DBQueryDuration        *prometheus.HistogramVec
 	DBQueriesTotal         *prometheus.CounterVec
+	DBPoolWarmupDuration   prometheus.Gauge
+	DBPoolWarmupConns      prometheus.Gauge
+	DBPoolUtilization      prometheus.Gauge
+
+	// Kafka metrics
+	DLQMessagesTotal       *prometheus.CounterVec
+	KafkaCircuitState      prometheus.Gauge
+	KafkaEventsDropped     prometheus.Counter
+
+	// Webhook metrics
+	WebhookDeliveriesTotal *prometheus.CounterVec
+
+	// Outbound HTTP metrics
+	HTTPClientDuration     *prometheus.HistogramVec
+
+	// Dependency metrics
+	DependencyUp           *prometheus.GaugeVec
 
 	// System metrics
 	AppInfo                *prometheus.GaugeVec
@@ -41,7 +64,7 @@ type Metrics struct {
 }
 
 // New creates a new metrics instance
-func New(serviceName, version string, port int, enabled bool) *Metrics {
+func New(info buildinfo.Info, port int, enabled bool) *Metrics {
 	if !enabled {
 		return &Metrics{enabled: false}
 	}
@@ -72,6 +95,32 @@ func New(serviceName, version string, port int, enabled bool) *Metrics {
 				Help: "Number of HTTP requests currently being processed",
 			},
 		),
+		HTTPConcurrentRequests: promauto.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "http_concurrent_requests",
+				Help: "Number of HTTP requests holding a slot of the concurrency limiter",
+			},
+		),
+		HTTPRequestsShed: promauto.NewCounter(
+			prometheus.CounterOpts{
+				Name: "http_requests_shed_total",
+				Help: "Total number of HTTP requests turned away by the concurrency limiter",
+			},
+		),
+		PanicsTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "panics_total",
+				Help: "Total number of panics recovered while handling HTTP requests",
+			},
+			[]string{"method", "path"},
+		),
+		ValidationFailures: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "validation_failures_total",
+				Help: "Total number of fields rejected by task create and update validation, by field and reason (required, too_long, invalid)",
+			},
+			[]string{"field", "reason"},
+		),
 
 		// Business metrics
 		TasksCreatedTotal: promauto.NewCounter(
@@ -135,6 +184,73 @@ func New(serviceName, version string, port int, enabled bool) *Metrics {
 			},
 			[]string{"query", "status"},
 		),
+		DBPoolWarmupDuration: promauto.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "db_pool_warmup_seconds",
+				Help: "Time the last database pool warm-up took in seconds",
+			},
+		),
+		DBPoolWarmupConns: promauto.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "db_pool_warmup_connections",
+				Help: "Number of connections opened by the last database pool warm-up",
+			},
+		),
+		DBPoolUtilization: promauto.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "db_pool_utilization",
+				Help: "Share of the database pool's maximum connections in use, from 0 to 1",
+			},
+		),
+
+		// Kafka metrics
+		DLQMessagesTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "dlq_messages_total",
+				Help: "Total number of dead letter queue messages by action (sent, replayed, skipped)",
+			},
+			[]string{"action"},
+		),
+		KafkaCircuitState: promauto.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "kafka_producer_circuit_state",
+				Help: "State of the Kafka producer circuit breaker (0 closed, 1 half open, 2 open)",
+			},
+		),
+		KafkaEventsDropped: promauto.NewCounter(
+			prometheus.CounterOpts{
+				Name: "kafka_events_dropped_total",
+				Help: "Total number of events dropped because the Kafka circuit breaker was open",
+			},
+		),
+
+		// Webhook metrics
+		WebhookDeliveriesTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "webhook_deliveries_total",
+				Help: "Total number of webhook delivery attempts and outcomes by webhook and result (delivered, failed, dead_lettered, dropped)",
+			},
+			[]string{"webhook_id", "result"},
+		),
+
+		// Outbound HTTP metrics
+		HTTPClientDuration: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "http_client_request_duration_seconds",
+				Help:    "Outbound HTTP request duration in seconds by client, host and status (error when no response came)",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{"client", "host", "status"},
+		),
+
+		// Dependency metrics
+		DependencyUp: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "dependency_up",
+				Help: "Whether a dependency passed its last health check (1 up, 0 down)",
+			},
+			[]string{"name"},
+		),
 
 		// System metrics
 		AppInfo: promauto.NewGaugeVec(
@@ -142,7 +258,7 @@ func New(serviceName, version string, port int, enabled bool) *Metrics {
 				Name: "app_info",
 				Help: "Application information",
 			},
-			[]string{"service", "version"},
+			[]string{"service", "version", "commit", "build_time", "go_version"},
 		),
 		AppUptime: promauto.NewCounter(
 			prometheus.CounterOpts{
@@ -152,7 +268,7 @@ func New(serviceName, version string, port int, enabled bool) *Metrics {
 		),
 	}
 
-	m.AppInfo.WithLabelValues(serviceName, version).Set(1)
+	m.AppInfo.WithLabelValues(info.Name, info.Version, info.Commit, info.BuildTime, info.GoVersion).Set(1)
 
 	// Create HTTP server for metrics endpoint
 	mux := http.NewServeMux()
@@ -168,7 +284,7 @@ func New(serviceName, version string, port int, enabled bool) *Metrics {
 
 // Start starts the metrics HTTP server
 func (m *Metrics) Start(ctx context.Context) error {
-	if !m.enabled {
+	if m == nil || !m.enabled {
 		return nil
 	}
 
@@ -197,7 +313,7 @@ func (m *Metrics) Start(ctx context.Context) error {
 
 // Shutdown gracefully shuts down the metrics server
 func (m *Metrics) Shutdown(ctx context.Context) error {
-	if !m.enabled || m.server == nil {
+	if m == nil || !m.enabled || m.server == nil {
 		return nil
 	}
 	return m.server.Shutdown(ctx)
@@ -205,7 +321,7 @@ func (m *Metrics) Shutdown(ctx context.Context) error {
 
 // RecordHTTPRequest records an HTTP request metric
 func (m *Metrics) RecordHTTPRequest(method, path, status string, duration time.Duration) {
-	if !m.enabled {
+	if m == nil || !m.enabled {
 		return
 	}
 	m.HTTPRequestsTotal.WithLabelValues(method, path, status).Inc()
@@ -214,7 +330,7 @@ func (m *Metrics) RecordHTTPRequest(method, path, status string, duration time.D
 
 // IncHTTPRequestsInFlight increments the in-flight requests gauge
 func (m *Metrics) IncHTTPRequestsInFlight() {
-	if !m.enabled {
+	if m == nil || !m.enabled {
 		return
 	}
 	m.HTTPRequestsInFlight.Inc()
@@ -222,15 +338,48 @@ func (m *Metrics) IncHTTPRequestsInFlight() {
 
 // DecHTTPRequestsInFlight decrements the in-flight requests gauge
 func (m *Metrics) DecHTTPRequestsInFlight() {
-	if !m.enabled {
+	if m == nil || !m.enabled {
 		return
 	}
 	m.HTTPRequestsInFlight.Dec()
 }
 
+// RecordValidationFailure records a request field rejected by validation.
+// field must come from a fixed set of names, never from the request itself.
+func (m *Metrics) RecordValidationFailure(field, reason string) {
+	if m == nil || !m.enabled {
+		return
+	}
+	m.ValidationFailures.WithLabelValues(field, reason).Inc()
+}
+
+// SetHTTPConcurrentRequests sets the number of requests holding a concurrency limiter slot
+func (m *Metrics) SetHTTPConcurrentRequests(n int) {
+	if m == nil || !m.enabled {
+		return
+	}
+	m.HTTPConcurrentRequests.Set(float64(n))
+}
+
+// RecordHTTPRequestShed records a request turned away by the concurrency limiter
+func (m *Metrics) RecordHTTPRequestShed() {
+	if m == nil || !m.enabled {
+		return
+	}
+	m.HTTPRequestsShed.Inc()
+}
+
+// RecordPanic records a panic recovered while handling a request
+func (m *Metrics) RecordPanic(method, path string) {
+	if m == nil || !m.enabled {
+		return
+	}
+	m.PanicsTotal.WithLabelValues(method, path).Inc()
+}
+
 // RecordTaskCreated records a task creation
 func (m *Metrics) RecordTaskCreated() {
-	if !m.enabled {
+	if m == nil || !m.enabled {
 		return
 	}
 	m.TasksCreatedTotal.Inc()
@@ -238,7 +387,7 @@ func (m *Metrics) RecordTaskCreated() {
 
 // RecordTaskCompleted records a task completion
 func (m *Metrics) RecordTaskCompleted() {
-	if !m.enabled {
+	if m == nil || !m.enabled {
 		return
 	}
 	m.TasksCompletedTotal.Inc()
@@ -246,7 +395,7 @@ func (m *Metrics) RecordTaskCompleted() {
 
 // RecordTaskFailed records a failed task operation
 func (m *Metrics) RecordTaskFailed() {
-	if !m.enabled {
+	if m == nil || !m.enabled {
 		return
 	}
 	m.TasksFailedTotal.Inc()
@@ -254,7 +403,7 @@ func (m *Metrics) RecordTaskFailed() {
 
 // SetTasksByStatus sets the number of tasks for a given status
 func (m *Metrics) SetTasksByStatus(status string, count float64) {
-	if !m.enabled {
+	if m == nil || !m.enabled {
 		return
 	}
 	m.TasksByStatus.WithLabelValues(status).Set(count)
@@ -262,7 +411,7 @@ func (m *Metrics) SetTasksByStatus(status string, count float64) {
 
 // RecordTaskProcessingDuration records task processing duration
 func (m *Metrics) RecordTaskProcessingDuration(duration time.Duration) {
-	if !m.enabled {
+	if m == nil || !m.enabled {
 		return
 	}
 	m.TaskProcessingDuration.Observe(duration.Seconds())
@@ -270,18 +419,87 @@ func (m *Metrics) RecordTaskProcessingDuration(duration time.Duration) {
 
 // RecordDBQuery records a database query
 func (m *Metrics) RecordDBQuery(query, status string, duration time.Duration) {
-	if !m.enabled {
+	if m == nil || !m.enabled {
 		return
 	}
 	m.DBQueriesTotal.WithLabelValues(query, status).Inc()
 	m.DBQueryDuration.WithLabelValues(query).Observe(duration.Seconds())
 }
 
+// RecordDBPoolWarmup records the outcome of a connection pool warm-up
+func (m *Metrics) RecordDBPoolWarmup(conns int, duration time.Duration) {
+	if m == nil || !m.enabled {
+		return
+	}
+	m.DBPoolWarmupConns.Set(float64(conns))
+	m.DBPoolWarmupDuration.Set(duration.Seconds())
+}
+
 // SetDBConnections sets database connection metrics
 func (m *Metrics) SetDBConnections(open, idle int32) {
-	if !m.enabled {
+	if m == nil || !m.enabled {
 		return
 	}
 	m.DBConnectionsOpen.Set(float64(open))
 	m.DBConnectionsIdle.Set(float64(idle))
 }
+
+// SetDBPoolUtilization sets the share of the pool's maximum connections in use
+func (m *Metrics) SetDBPoolUtilization(ratio float64) {
+	if m == nil || !m.enabled {
+		return
+	}
+	m.DBPoolUtilization.Set(ratio)
+}
+
+// RecordDLQMessage records a dead letter queue message being sent, replayed or skipped
+func (m *Metrics) RecordDLQMessage(action string) {
+	if m == nil || !m.enabled {
+		return
+	}
+	m.DLQMessagesTotal.WithLabelValues(action).Inc()
+}
+
+// SetKafkaCircuitState sets the Kafka producer circuit breaker state
+func (m *Metrics) SetKafkaCircuitState(state float64) {
+	if m == nil || !m.enabled {
+		return
+	}
+	m.KafkaCircuitState.Set(state)
+}
+
+// RecordWebhookDelivery records the outcome of a webhook delivery attempt
+func (m *Metrics) RecordWebhookDelivery(webhookID int64, result string) {
+	if m == nil || !m.enabled {
+		return
+	}
+	m.WebhookDeliveriesTotal.WithLabelValues(strconv.FormatInt(webhookID, 10), result).Inc()
+}
+
+// SetDependencyUp sets whether a dependency passed its last health check
+func (m *Metrics) SetDependencyUp(name string, up bool) {
+	if m == nil || !m.enabled {
+		return
+	}
+	value := 0.0
+	if up {
+		value = 1
+	}
+	m.DependencyUp.WithLabelValues(name).Set(value)
+}
+
+// RecordHTTPClientRequest records an outbound HTTP request
+func (m *Metrics) RecordHTTPClientRequest(client, host, status string, duration time.Duration) {
+	if m == nil || !m.enabled {
+		return
+	}
+	m.HTTPClientDuration.WithLabelValues(client, host, status).Observe(duration.Seconds())
+}
+
+// RecordKafkaEventDropped records an event dropped by the open Kafka circuit breaker
+func (m *Metrics) RecordKafkaEventDropped() {
+	if m == nil || !m.enabled {
+		return
+	}
+	m.KafkaEventsDropped.Inc()
+}
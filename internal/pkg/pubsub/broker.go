@@ -0,0 +1,129 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	"github.com/seldomhappy/vibe_architecture/logger"
+)
+
+// Filter decides whether a subscriber receives an event. A nil filter accepts everything.
+type Filter func(event domain.TaskEvent) bool
+
+// Subscription is a single subscriber's buffered event stream
+type Subscription struct {
+	events chan domain.TaskEvent
+	filter Filter
+}
+
+// Events returns the channel events are delivered on. It is closed when the
+// subscription is removed, the subscriber falls behind, or the broker shuts down.
+func (s *Subscription) Events() <-chan domain.TaskEvent {
+	return s.events
+}
+
+// Broker fans task events out to in-process subscribers.
+// Publishing never blocks: a subscriber whose buffer is full is dropped.
+type Broker struct {
+	bufferSize int
+	logger     logger.ILogger
+
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// New creates a new broker with the given per-subscriber buffer size
+func New(bufferSize int, log logger.ILogger) *Broker {
+	return &Broker{
+		bufferSize: bufferSize,
+		logger:     log,
+		subs:       make(map[*Subscription]struct{}),
+	}
+}
+
+// Start implements lifecycle.Service
+func (b *Broker) Start(ctx context.Context) error {
+	b.logger.Info("Event broker started")
+	return nil
+}
+
+// Shutdown closes every subscription so open streams terminate
+func (b *Broker) Shutdown(ctx context.Context) error {
+	b.logger.Info("Shutting down event broker")
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subs {
+		delete(b.subs, sub)
+		close(sub.events)
+	}
+	return nil
+}
+
+// Closed reports whether the broker has shut down, which tells a subscriber whose
+// stream ended whether it was dropped for falling behind or should stop
+func (b *Broker) Closed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.closed
+}
+
+// Subscribe registers a new subscriber. After shutdown the returned
+// subscription is already closed.
+func (b *Broker) Subscribe(filter Filter) *Subscription {
+	sub := &Subscription{
+		events: make(chan domain.TaskEvent, b.bufferSize),
+		filter: filter,
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(sub.events)
+		return sub
+	}
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe removes a subscriber. It is safe to call more than once.
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.events)
+	}
+}
+
+// HandleEvent publishes an event from the event bus to the subscribers
+func (b *Broker) HandleEvent(ctx context.Context, event domain.TaskEvent) error {
+	b.Publish(event)
+	return nil
+}
+
+// Publish delivers an event to every matching subscriber
+func (b *Broker) Publish(event domain.TaskEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		if sub.filter != nil && !sub.filter(event) {
+			continue
+		}
+
+		select {
+		case sub.events <- event:
+		default:
+			b.logger.Warn("Dropping slow event subscriber (buffer of %d full)", b.bufferSize)
+			delete(b.subs, sub)
+			close(sub.events)
+		}
+	}
+}
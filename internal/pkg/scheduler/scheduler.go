@@ -0,0 +1,80 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/seldomhappy/vibe_architecture/logger"
+)
+
+// JobFunc is the unit of work executed on every tick
+type JobFunc func(ctx context.Context) error
+
+// Job runs a function periodically until it is shut down
+type Job struct {
+	name     string
+	interval time.Duration
+	fn       JobFunc
+	logger   logger.ILogger
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// New creates a new periodic job
+func New(name string, interval time.Duration, fn JobFunc, log logger.ILogger) *Job {
+	return &Job{
+		name:     name,
+		interval: interval,
+		fn:       fn,
+		logger:   log,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start starts running the job in the background.
+// The job is bound to its own context so it keeps running after ctx returns
+// and only stops on Shutdown.
+func (j *Job) Start(ctx context.Context) error {
+	runCtx, cancel := context.WithCancel(context.Background())
+	j.cancel = cancel
+
+	j.logger.Info("Starting job %s (interval: %v)", j.name, j.interval)
+
+	go j.run(runCtx)
+
+	return nil
+}
+
+// Shutdown stops the job and waits for the current run to finish
+func (j *Job) Shutdown(ctx context.Context) error {
+	j.logger.Info("Stopping job %s", j.name)
+	if j.cancel == nil {
+		return nil
+	}
+	j.cancel()
+
+	select {
+	case <-j.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (j *Job) run(ctx context.Context) {
+	defer close(j.done)
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := j.fn(ctx); err != nil && ctx.Err() == nil {
+				j.logger.Error("Job %s failed: %v", j.name, err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Baggage keys set for every request. They ride along the whole trace, into
+// Kafka messages too, and are added to every span as attributes.
+const (
+	BaggageTenantID = "tenant.id"
+	BaggageUserID   = "user.id"
+)
+
+// SetBaggage returns a copy of ctx whose baggage holds value under key. Values
+// are limited to printable ASCII without spaces, quotes, commas, semicolons or
+// backslashes, which is what survives a trip through Kafka headers unchanged.
+func SetBaggage(ctx context.Context, key, value string) (context.Context, error) {
+	if !validBaggageValue(value) {
+		return ctx, fmt.Errorf("invalid baggage value for %s: %q", key, value)
+	}
+	// NewMember expects the value URL encoded and decodes it
+	member, err := baggage.NewMember(key, url.PathEscape(value))
+	if err != nil {
+		return ctx, err
+	}
+	b, err := baggage.FromContext(ctx).SetMember(member)
+	if err != nil {
+		return ctx, err
+	}
+	return baggage.ContextWithBaggage(ctx, b), nil
+}
+
+// validBaggageValue reports whether every byte of value is a W3C baggage-octet
+func validBaggageValue(value string) bool {
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c < 0x21 || c > 0x7e || c == '"' || c == ',' || c == ';' || c == '\\' {
+			return false
+		}
+	}
+	return true
+}
+
+// GetBaggage returns the baggage value under key, or "" if there is none
+func GetBaggage(ctx context.Context, key string) string {
+	return baggage.FromContext(ctx).Member(key).Value()
+}
+
+// EncodeBaggage returns the baggage of ctx in the W3C baggage header format,
+// or "" if there is none
+func EncodeBaggage(ctx context.Context) string {
+	return baggage.FromContext(ctx).String()
+}
+
+// DecodeBaggage returns a copy of ctx with the baggage encoded by EncodeBaggage.
+// Malformed baggage is ignored.
+func DecodeBaggage(ctx context.Context, encoded string) context.Context {
+	if encoded == "" {
+		return ctx
+	}
+	b, err := baggage.Parse(encoded)
+	if err != nil {
+		return ctx
+	}
+	return baggage.ContextWithBaggage(ctx, b)
+}
+
+// baggageProcessor copies the baggage a span starts with to its attributes,
+// so traces can be searched by tenant or user in Jaeger
+type baggageProcessor struct{}
+
+// OnStart implements sdktrace.SpanProcessor
+func (baggageProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	for _, member := range baggage.FromContext(parent).Members() {
+		s.SetAttributes(attribute.String(member.Key(), member.Value()))
+	}
+}
+
+// OnEnd implements sdktrace.SpanProcessor
+func (baggageProcessor) OnEnd(sdktrace.ReadOnlySpan) {}
+
+// Shutdown implements sdktrace.SpanProcessor
+func (baggageProcessor) Shutdown(context.Context) error { return nil }
+
+// ForceFlush implements sdktrace.SpanProcessor
+func (baggageProcessor) ForceFlush(context.Context) error { return nil }
@@ -0,0 +1,144 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Sampling strategies accepted for SamplingConfig.Sampler
+const (
+	// SamplerRatio samples a ratio of all spans, ignoring the parent's decision
+	SamplerRatio = "ratio"
+	// SamplerParentRatio follows the parent's decision and samples a ratio of
+	// the traces that start here
+	SamplerParentRatio = "parent_ratio"
+)
+
+// SamplingConfig chooses which spans are exported
+type SamplingConfig struct {
+	// Sampler is SamplerRatio or SamplerParentRatio, SamplerRatio when empty
+	Sampler string
+	// Rate is the ratio of traces sampled, between 0 and 1
+	Rate float64
+	// KeepErrors exports spans that end with an error even when their trace
+	// wasn't sampled
+	KeepErrors bool
+	// KeepSlowerThan exports spans that take longer, even when their trace
+	// wasn't sampled; 0 disables
+	KeepSlowerThan time.Duration
+}
+
+// keeps reports whether spans that weren't sampled are recorded so slow or
+// failed ones can still be exported
+func (c SamplingConfig) keeps() bool {
+	return c.KeepErrors || c.KeepSlowerThan > 0
+}
+
+// sampler builds the head sampler around the adjustable ratio
+func (c SamplingConfig) sampler(ratio *ratioSampler) (sdktrace.Sampler, error) {
+	var sampler sdktrace.Sampler
+	switch c.Sampler {
+	case "", SamplerRatio:
+		sampler = ratio
+	case SamplerParentRatio:
+		sampler = sdktrace.ParentBased(ratio)
+	default:
+		return nil, fmt.Errorf("unsupported sampler: %q", c.Sampler)
+	}
+	if c.keeps() {
+		sampler = recordingSampler{next: sampler}
+	}
+	return sampler, nil
+}
+
+// recordingSampler records the spans its sampler drops instead of discarding
+// them, so keepProcessor can look at how they ended. Recording every span costs
+// what sampling them all would, short of exporting them.
+type recordingSampler struct {
+	next sdktrace.Sampler
+}
+
+// ShouldSample implements sdktrace.Sampler
+func (s recordingSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	result := s.next.ShouldSample(p)
+	if result.Decision == sdktrace.Drop {
+		result.Decision = sdktrace.RecordOnly
+	}
+	return result
+}
+
+// Description implements sdktrace.Sampler
+func (s recordingSampler) Description() string {
+	return "Recording{" + s.next.Description() + "}"
+}
+
+// keepProcessor passes sampled spans on to the exporting processor, along with
+// the spans that weren't sampled but ended with an error or took too long.
+// Only those spans are kept, not the rest of their trace.
+type keepProcessor struct {
+	next           sdktrace.SpanProcessor
+	keepErrors     bool
+	keepSlowerThan time.Duration
+}
+
+// OnStart implements sdktrace.SpanProcessor
+func (p *keepProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	p.next.OnStart(parent, s)
+}
+
+// OnEnd implements sdktrace.SpanProcessor
+func (p *keepProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if s.SpanContext().IsSampled() {
+		p.next.OnEnd(s)
+		return
+	}
+	if p.keep(s) {
+		p.next.OnEnd(keptSpan{s})
+	}
+}
+
+// Shutdown implements sdktrace.SpanProcessor
+func (p *keepProcessor) Shutdown(ctx context.Context) error {
+	return p.next.Shutdown(ctx)
+}
+
+// ForceFlush implements sdktrace.SpanProcessor
+func (p *keepProcessor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
+
+func (p *keepProcessor) keep(s sdktrace.ReadOnlySpan) bool {
+	if p.keepSlowerThan > 0 && s.EndTime().Sub(s.StartTime()) > p.keepSlowerThan {
+		return true
+	}
+	if !p.keepErrors {
+		return false
+	}
+	if s.Status().Code == codes.Error {
+		return true
+	}
+	// span.RecordError adds an exception event without setting the status
+	for _, event := range s.Events() {
+		if event.Name == "exception" {
+			return true
+		}
+	}
+	return false
+}
+
+// keptSpan marks a span that wasn't sampled as sampled, since exporting
+// processors skip the others
+type keptSpan struct {
+	sdktrace.ReadOnlySpan
+}
+
+// SpanContext implements sdktrace.ReadOnlySpan
+func (s keptSpan) SpanContext() trace.SpanContext {
+	sc := s.ReadOnlySpan.SpanContext()
+	return sc.WithTraceFlags(sc.TraceFlags().WithSampled(true))
+}
@@ -3,24 +3,60 @@ package tracing
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/exporters/jaeger"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
 	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
+// active is true while an enabled Tracer's provider is installed. Until then
+// StartSpan returns noopSpan, so disabled tracing costs no allocations.
+var active atomic.Bool
+
+// noopSpan is boxed once so returning it doesn't allocate
+var noopSpan trace.Span = noop.Span{}
+
 // Tracer holds the OpenTelemetry tracer provider
 type Tracer struct {
 	provider *sdktrace.TracerProvider
+	sampler  *ratioSampler
 	enabled  bool
 }
 
+// ratioSampler samples a ratio of traces that can be changed at runtime
+type ratioSampler struct {
+	current atomic.Value // sdktrace.Sampler
+}
+
+func newRatioSampler(rate float64) *ratioSampler {
+	s := &ratioSampler{}
+	s.set(rate)
+	return s
+}
+
+func (s *ratioSampler) set(rate float64) {
+	s.current.Store(sdktrace.TraceIDRatioBased(rate))
+}
+
+// ShouldSample implements sdktrace.Sampler
+func (s *ratioSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	return s.current.Load().(sdktrace.Sampler).ShouldSample(p)
+}
+
+// Description implements sdktrace.Sampler
+func (s *ratioSampler) Description() string {
+	return s.current.Load().(sdktrace.Sampler).Description()
+}
+
 // New creates a new tracer with Jaeger exporter
-func New(serviceName, jaegerEndpoint string, samplingRate float64, enabled bool) (*Tracer, error) {
+func New(serviceName, jaegerEndpoint string, sampling SamplingConfig, enabled bool) (*Tracer, error) {
 	if !enabled {
 		return &Tracer{enabled: false}, nil
 	}
@@ -32,9 +68,31 @@ func New(serviceName, jaegerEndpoint string, samplingRate float64, enabled bool)
 		return nil, fmt.Errorf("failed to create jaeger exporter: %w", err)
 	}
 
+	return NewWithExporter(serviceName, exporter, sampling)
+}
+
+// NewWithExporter creates an enabled tracer that exports its spans to exporter
+// and installs it as the global provider, as New does for Jaeger
+func NewWithExporter(serviceName string, exporter sdktrace.SpanExporter, sampling SamplingConfig) (*Tracer, error) {
+	ratio := newRatioSampler(sampling.Rate)
+	sampler, err := sampling.sampler(ratio)
+	if err != nil {
+		return nil, err
+	}
+
+	var processor sdktrace.SpanProcessor = sdktrace.NewBatchSpanProcessor(exporter)
+	if sampling.keeps() {
+		processor = &keepProcessor{
+			next:           processor,
+			keepErrors:     sampling.KeepErrors,
+			keepSlowerThan: sampling.KeepSlowerThan,
+		}
+	}
+
 	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(exporter),
-		sdktrace.WithSampler(sdktrace.TraceIDRatioBased(samplingRate)),
+		sdktrace.WithSpanProcessor(baggageProcessor{}),
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithSampler(sampler),
 		sdktrace.WithResource(resource.NewWithAttributes(
 			semconv.SchemaURL,
 			semconv.ServiceName(serviceName),
@@ -42,16 +100,30 @@ func New(serviceName, jaegerEndpoint string, samplingRate float64, enabled bool)
 	)
 
 	otel.SetTracerProvider(tp)
+	active.Store(true)
 
 	return &Tracer{
 		provider: tp,
+		sampler:  ratio,
 		enabled:  true,
 	}, nil
 }
 
+// SetSamplingRate changes the ratio of traces that are sampled
+func (t *Tracer) SetSamplingRate(rate float64) error {
+	if rate < 0 || rate > 1 {
+		return fmt.Errorf("sampling rate must be between 0 and 1, got %v", rate)
+	}
+	if t == nil || !t.enabled {
+		return nil
+	}
+	t.sampler.set(rate)
+	return nil
+}
+
 // Start initializes the tracer
 func (t *Tracer) Start(ctx context.Context) error {
-	if !t.enabled {
+	if t == nil || !t.enabled {
 		return nil
 	}
 	return nil
@@ -59,19 +131,27 @@ func (t *Tracer) Start(ctx context.Context) error {
 
 // Shutdown shuts down the tracer
 func (t *Tracer) Shutdown(ctx context.Context) error {
-	if !t.enabled || t.provider == nil {
+	if t == nil || !t.enabled || t.provider == nil {
 		return nil
 	}
+	active.Store(false)
 	return t.provider.Shutdown(ctx)
 }
 
-// GetTracer returns a named tracer
+// GetTracer returns a named tracer, or a no-op tracer while tracing is disabled
 func GetTracer(name string) trace.Tracer {
+	if !active.Load() {
+		return noop.NewTracerProvider().Tracer(name)
+	}
 	return otel.Tracer(name)
 }
 
-// StartSpan starts a new span
+// StartSpan starts a new span. While tracing is disabled no span is created:
+// ctx is returned unchanged along with a span that ignores every call.
 func StartSpan(ctx context.Context, tracerName, spanName string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if !active.Load() {
+		return ctx, noopSpan
+	}
 	tracer := GetTracer(tracerName)
 	return tracer.Start(ctx, spanName, opts...)
 }
@@ -82,8 +162,23 @@ func AddSpanAttributes(ctx context.Context, attrs ...attribute.KeyValue) {
 	span.SetAttributes(attrs...)
 }
 
-// RecordError records an error in the current span
+// RecordError records an error in the current span and marks the span failed
 func RecordError(ctx context.Context, err error) {
 	span := trace.SpanFromContext(ctx)
 	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// AddEvent records a named event, such as validation_failed, in the current span
+func AddEvent(ctx context.Context, name string, attrs ...attribute.KeyValue) {
+	span := trace.SpanFromContext(ctx)
+	span.AddEvent(name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan ends a span, marking it successful unless an error was recorded in it
+func EndSpan(span trace.Span) {
+	if s, ok := span.(sdktrace.ReadOnlySpan); ok && s.Status().Code == codes.Unset {
+		span.SetStatus(codes.Ok, "")
+	}
+	span.End()
 }
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// CreateComment stores a new comment. The insert only succeeds while the task
+// exists, so comments can't be attached to a task deleted concurrently.
+func (r *TaskRepository) CreateComment(ctx context.Context, comment *domain.Comment) error {
+	ctx, span := tracing.StartSpan(ctx, "repository", "create_comment")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("task.id", comment.TaskID))
+
+	return r.createComment(ctx, r.db, comment)
+}
+
+// CreateCommentTx is CreateComment inside the given transaction
+func (r *TaskRepository) CreateCommentTx(ctx context.Context, tx pgx.Tx, comment *domain.Comment) error {
+	ctx, span := tracing.StartSpan(ctx, "repository", "create_comment_tx")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("task.id", comment.TaskID))
+
+	return r.createComment(ctx, tx, comment)
+}
+
+func (r *TaskRepository) createComment(ctx context.Context, q queryRower, comment *domain.Comment) error {
+	query := `
+		INSERT INTO task_comments (task_id, author, body)
+		SELECT id, $2, $3 FROM tasks WHERE id = $1 AND ($4 = '*' OR tenant_id = $4)
+		RETURNING id, created_at
+	`
+
+	err := q.QueryRow(ctx, query, comment.TaskID, comment.Author, comment.Body, TenantOf(ctx)).
+		Scan(&comment.ID, &comment.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrTaskNotFound
+		}
+		r.logger.Error("Failed to create comment: %v", err)
+		tracing.RecordError(ctx, err)
+		return fmt.Errorf("failed to create comment: %w", err)
+	}
+
+	return nil
+}
+
+// GetComments returns all comments of a task, oldest first
+func (r *TaskRepository) GetComments(ctx context.Context, taskID int64) ([]*domain.Comment, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "get_comments")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("task.id", taskID))
+
+	query := `
+		SELECT c.id, c.task_id, c.author, c.body, c.created_at
+		FROM task_comments c
+		JOIN tasks t ON t.id = c.task_id
+		WHERE c.task_id = $1 AND ($2 = '*' OR t.tenant_id = $2)
+		ORDER BY c.created_at, c.id
+	`
+
+	rows, err := r.db.Query(ctx, query, taskID, TenantOf(ctx))
+	if err != nil {
+		r.logger.Error("Failed to get comments: %v", err)
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to get comments: %w", err)
+	}
+
+	comments, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*domain.Comment, error) {
+		comment := &domain.Comment{}
+		err := row.Scan(&comment.ID, &comment.TaskID, &comment.Author, &comment.Body, &comment.CreatedAt)
+		return comment, err
+	})
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to scan comments: %w", err)
+	}
+
+	return comments, nil
+}
+
+// DeleteComment deletes a comment of the given task
+func (r *TaskRepository) DeleteComment(ctx context.Context, taskID, commentID int64) error {
+	ctx, span := tracing.StartSpan(ctx, "repository", "delete_comment")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.Int64("task.id", taskID),
+		attribute.Int64("comment.id", commentID),
+	)
+
+	query := `
+		DELETE FROM task_comments c
+		USING tasks t
+		WHERE c.id = $1 AND c.task_id = $2 AND t.id = c.task_id AND ($3 = '*' OR t.tenant_id = $3)
+	`
+
+	result, err := r.db.Exec(ctx, query, commentID, taskID, TenantOf(ctx))
+	if err != nil {
+		r.logger.Error("Failed to delete comment: %v", err)
+		tracing.RecordError(ctx, err)
+		return fmt.Errorf("failed to delete comment: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrCommentNotFound
+	}
+
+	return nil
+}
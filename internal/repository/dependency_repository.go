@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// AddDependency records that taskID depends on dependsOnID.
+// The cycle check and insert run in one transaction holding a table lock so
+// concurrent inserts can't race each other into a cycle. Adding an existing
+// dependency is a no-op.
+func (r *TaskRepository) AddDependency(ctx context.Context, taskID, dependsOnID int64) error {
+	ctx, span := tracing.StartSpan(ctx, "repository", "add_task_dependency")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.Int64("task.id", taskID),
+		attribute.Int64("task.depends_on_id", dependsOnID),
+	)
+
+	if taskID == dependsOnID {
+		return domain.ErrDependencyCycle
+	}
+
+	tx, err := r.db.BeginTx(ctx)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `LOCK TABLE task_dependencies IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		tracing.RecordError(ctx, err)
+		return fmt.Errorf("failed to lock dependencies: %w", err)
+	}
+
+	// Adding taskID -> dependsOnID closes a cycle if taskID is already reachable from dependsOnID
+	cycleQuery := `
+		WITH RECURSIVE reachable(id) AS (
+			SELECT depends_on_id FROM task_dependencies WHERE task_id = $1
+			UNION
+			SELECT d.depends_on_id FROM task_dependencies d JOIN reachable r ON d.task_id = r.id
+		)
+		SELECT EXISTS (SELECT 1 FROM reachable WHERE id = $2)
+	`
+
+	var cycle bool
+	if err := tx.QueryRow(ctx, cycleQuery, dependsOnID, taskID).Scan(&cycle); err != nil {
+		r.logger.Error("Failed to check dependency cycle: %v", err)
+		tracing.RecordError(ctx, err)
+		return fmt.Errorf("failed to check dependency cycle: %w", err)
+	}
+	if cycle {
+		return domain.ErrDependencyCycle
+	}
+
+	insertQuery := `
+		INSERT INTO task_dependencies (task_id, depends_on_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`
+	if _, err := tx.Exec(ctx, insertQuery, taskID, dependsOnID); err != nil {
+		r.logger.Error("Failed to add dependency: %v", err)
+		tracing.RecordError(ctx, err)
+		return fmt.Errorf("failed to add dependency: %w", err)
+	}
+
+	// Bump updated_at so the task's ETag reflects the new dependency
+	if err := touchTask(ctx, tx, taskID); err != nil {
+		tracing.RecordError(ctx, err)
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		tracing.RecordError(ctx, err)
+		return fmt.Errorf("failed to commit dependency: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveDependency deletes the dependency of taskID on dependsOnID
+func (r *TaskRepository) RemoveDependency(ctx context.Context, taskID, dependsOnID int64) error {
+	ctx, span := tracing.StartSpan(ctx, "repository", "remove_task_dependency")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.Int64("task.id", taskID),
+		attribute.Int64("task.depends_on_id", dependsOnID),
+	)
+
+	tx, err := r.db.BeginTx(ctx)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	query := `
+		DELETE FROM task_dependencies d
+		USING tasks t
+		WHERE d.task_id = $1 AND d.depends_on_id = $2 AND t.id = d.task_id AND ($3 = '*' OR t.tenant_id = $3)
+	`
+
+	result, err := tx.Exec(ctx, query, taskID, dependsOnID, TenantOf(ctx))
+	if err != nil {
+		r.logger.Error("Failed to remove dependency: %v", err)
+		tracing.RecordError(ctx, err)
+		return fmt.Errorf("failed to remove dependency: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrDependencyNotFound
+	}
+
+	if err := touchTask(ctx, tx, taskID); err != nil {
+		tracing.RecordError(ctx, err)
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		tracing.RecordError(ctx, err)
+		return fmt.Errorf("failed to commit dependency removal: %w", err)
+	}
+
+	return nil
+}
+
+// GetDependencies returns the IDs of the tasks taskID depends on
+func (r *TaskRepository) GetDependencies(ctx context.Context, taskID int64) ([]int64, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "get_task_dependencies")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("task.id", taskID))
+
+	query := `
+		SELECT d.depends_on_id
+		FROM task_dependencies d
+		JOIN tasks t ON t.id = d.task_id
+		WHERE d.task_id = $1 AND ($2 = '*' OR t.tenant_id = $2)
+		ORDER BY d.depends_on_id
+	`
+
+	rows, err := r.db.Query(ctx, query, taskID, TenantOf(ctx))
+	if err != nil {
+		r.logger.Error("Failed to get dependencies: %v", err)
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to get dependencies: %w", err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to scan dependencies: %w", err)
+	}
+
+	return ids, nil
+}
+
+// CountIncompleteDependenciesTx returns how many of taskID's dependencies are
+// not completed, as seen by tx
+func (r *TaskRepository) CountIncompleteDependenciesTx(ctx context.Context, tx pgx.Tx, taskID int64) (int, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "count_incomplete_dependencies_tx")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("task.id", taskID))
+
+	query := `
+		SELECT COUNT(*)
+		FROM task_dependencies d
+		JOIN tasks t ON t.id = d.depends_on_id
+		WHERE d.task_id = $1 AND t.status <> $2 AND ($3 = '*' OR t.tenant_id = $3)
+	`
+
+	var count int
+	if err := tx.QueryRow(ctx, query, taskID, domain.TaskStatusCompleted, TenantOf(ctx)).Scan(&count); err != nil {
+		r.logger.Error("Failed to count incomplete dependencies: %v", err)
+		tracing.RecordError(ctx, err)
+		return 0, fmt.Errorf("failed to count incomplete dependencies: %w", err)
+	}
+
+	return count, nil
+}
+
+// touchTask bumps a task's updated_at inside a transaction
+func touchTask(ctx context.Context, tx pgx.Tx, taskID int64) error {
+	if _, err := tx.Exec(ctx, `UPDATE tasks SET updated_at = NOW() WHERE id = $1`, taskID); err != nil {
+		return fmt.Errorf("failed to touch task: %w", err)
+	}
+	return nil
+}
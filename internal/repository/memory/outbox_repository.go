@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	"github.com/seldomhappy/vibe_architecture/logger"
+)
+
+// OutboxRepository implements event outbox data access in memory. The events
+// live in the store, so a rolled back transaction takes its events with it.
+type OutboxRepository struct {
+	store  *Store
+	logger logger.ILogger
+}
+
+// NewOutboxRepository creates a new in-memory outbox repository over store
+func NewOutboxRepository(store *Store, log logger.ILogger) *OutboxRepository {
+	return &OutboxRepository{
+		store:  store,
+		logger: log,
+	}
+}
+
+// Add stores an event in the outbox
+func (r *OutboxRepository) Add(ctx context.Context, event *domain.OutboxEvent) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event.ID = s.nextOutboxID.Add(1)
+	event.CreatedAt = time.Now()
+	c := *event
+	s.outbox[event.ID] = &c
+	return nil
+}
+
+// AddTx stores an event in the outbox inside the current transaction
+func (r *OutboxRepository) AddTx(ctx context.Context, tx pgx.Tx, event *domain.OutboxEvent) error {
+	return r.Add(ctx, event)
+}
+
+// PendingForUpdate returns up to limit events of the outbox, oldest first.
+// Transactions already run one at a time, so there is nothing to lock.
+func (r *OutboxRepository) PendingForUpdate(ctx context.Context, tx pgx.Tx, limit int) ([]*domain.OutboxEvent, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	events := make([]*domain.OutboxEvent, 0, len(s.outbox))
+	for _, event := range s.outbox {
+		c := *event
+		events = append(events, &c)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+// DeleteTx removes a published event from the outbox inside the current transaction
+func (r *OutboxRepository) DeleteTx(ctx context.Context, tx pgx.Tx, id int64) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.outbox, id)
+	return nil
+}
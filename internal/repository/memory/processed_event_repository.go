@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/seldomhappy/vibe_architecture/logger"
+)
+
+// ProcessedEventRepository records the Kafka events the consumer has processed, in memory
+type ProcessedEventRepository struct {
+	logger logger.ILogger
+
+	mu     sync.Mutex
+	events map[string]time.Time // event ID -> when it was processed
+}
+
+// NewProcessedEventRepository creates a new in-memory processed event repository
+func NewProcessedEventRepository(log logger.ILogger) *ProcessedEventRepository {
+	return &ProcessedEventRepository{
+		logger: log,
+		events: make(map[string]time.Time),
+	}
+}
+
+// Claim records an event as processed and reports false if it already was
+func (r *ProcessedEventRepository) Claim(ctx context.Context, eventID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.events[eventID]; ok {
+		return false, nil
+	}
+	r.events[eventID] = time.Now()
+	return true, nil
+}
+
+// Release forgets an event, so it is processed when it comes again
+func (r *ProcessedEventRepository) Release(ctx context.Context, eventID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.events, eventID)
+	return nil
+}
+
+// DeleteBefore forgets the events processed before t and returns how many
+func (r *ProcessedEventRepository) DeleteBefore(ctx context.Context, t time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, at := range r.events {
+		if at.Before(t) {
+			delete(r.events, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,214 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+)
+
+// AddDependency records that taskID depends on dependsOnID, rejecting cycles
+func (r *TaskRepository) AddDependency(ctx context.Context, taskID, dependsOnID int64) error {
+	if taskID == dependsOnID {
+		return domain.ErrDependencyCycle
+	}
+
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tasks[taskID]; !ok {
+		return domain.ErrReferenceNotFound
+	}
+	if _, ok := s.tasks[dependsOnID]; !ok {
+		return domain.ErrReferenceNotFound
+	}
+	if r.reachable(dependsOnID, taskID) {
+		return domain.ErrDependencyCycle
+	}
+
+	if s.deps[taskID] == nil {
+		s.deps[taskID] = make(map[int64]struct{})
+	}
+	s.deps[taskID][dependsOnID] = struct{}{}
+	s.tasks[taskID].UpdatedAt = time.Now()
+	return nil
+}
+
+// reachable reports whether to can be reached from from by following dependencies
+func (r *TaskRepository) reachable(from, to int64) bool {
+	seen := map[int64]bool{from: true}
+	queue := []int64{from}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for dep := range r.store.deps[id] {
+			if dep == to {
+				return true
+			}
+			if !seen[dep] {
+				seen[dep] = true
+				queue = append(queue, dep)
+			}
+		}
+	}
+	return false
+}
+
+// RemoveDependency removes a dependency between two tasks
+func (r *TaskRepository) RemoveDependency(ctx context.Context, taskID, dependsOnID int64) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := r.lookup(ctx, taskID); !ok {
+		return domain.ErrDependencyNotFound
+	}
+	if _, ok := s.deps[taskID][dependsOnID]; !ok {
+		return domain.ErrDependencyNotFound
+	}
+	delete(s.deps[taskID], dependsOnID)
+	if task, ok := s.tasks[taskID]; ok {
+		task.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+// GetDependencies returns the IDs of the tasks taskID depends on, in ascending order
+func (r *TaskRepository) GetDependencies(ctx context.Context, taskID int64) ([]int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	ids := make([]int64, 0, len(r.store.deps[taskID]))
+	if _, ok := r.lookup(ctx, taskID); !ok {
+		return ids, nil
+	}
+	for id := range r.store.deps[taskID] {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+// CountIncompleteDependenciesTx returns how many of the tasks taskID depends on aren't completed
+func (r *TaskRepository) CountIncompleteDependenciesTx(ctx context.Context, tx pgx.Tx, taskID int64) (int, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	count := 0
+	for id := range r.store.deps[taskID] {
+		if task, ok := r.lookup(ctx, id); ok && task.Status != domain.TaskStatusCompleted {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetTreeForUpdate returns a task and all of its subtasks (recursively), parents
+// before their subtasks. The memory store has no row locks; the TxManager
+// already serializes transactions.
+func (r *TaskRepository) GetTreeForUpdate(ctx context.Context, tx pgx.Tx, id int64) ([]*domain.Task, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	root, ok := r.lookup(ctx, id)
+	if !ok {
+		return nil, domain.ErrTaskNotFound
+	}
+
+	tree := []*domain.Task{cloneTask(root)}
+	for i := 0; i < len(tree); i++ {
+		level := []*domain.Task{}
+		for _, task := range r.store.tasks {
+			if task.ParentID != nil && *task.ParentID == tree[i].ID {
+				level = append(level, cloneTask(task))
+			}
+		}
+		sort.Slice(level, func(a, b int) bool { return level[a].ID < level[b].ID })
+		tree = append(tree, level...)
+	}
+	return tree, nil
+}
+
+// CountSubtasks returns the number of direct subtasks of parentID
+func (r *TaskRepository) CountSubtasks(ctx context.Context, parentID int64) (int, error) {
+	return r.countSubtasks(ctx, parentID, func(*domain.Task) bool { return true }), nil
+}
+
+// CountIncompleteSubtasksTx returns the number of direct subtasks of parentID that are still open
+func (r *TaskRepository) CountIncompleteSubtasksTx(ctx context.Context, tx pgx.Tx, parentID int64) (int, error) {
+	return r.countSubtasks(ctx, parentID, func(t *domain.Task) bool { return isOpen(t.Status) }), nil
+}
+
+func (r *TaskRepository) countSubtasks(ctx context.Context, parentID int64, include func(*domain.Task) bool) int {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	count := 0
+	for _, task := range r.store.tasks {
+		if task.ParentID != nil && *task.ParentID == parentID && visible(ctx, task) && include(task) {
+			count++
+		}
+	}
+	return count
+}
+
+// CreateCommentTx adds a comment to a task inside the current transaction
+func (r *TaskRepository) CreateCommentTx(ctx context.Context, tx pgx.Tx, comment *domain.Comment) error {
+	return r.CreateComment(ctx, comment)
+}
+
+// CreateComment adds a comment to a task
+func (r *TaskRepository) CreateComment(ctx context.Context, comment *domain.Comment) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := r.lookup(ctx, comment.TaskID); !ok {
+		return domain.ErrTaskNotFound
+	}
+
+	comment.ID = s.nextCommentID.Add(1)
+	comment.CreatedAt = time.Now()
+	stored := *comment
+	s.comments[comment.ID] = &stored
+	return nil
+}
+
+// GetComments returns the comments on a task, oldest first
+func (r *TaskRepository) GetComments(ctx context.Context, taskID int64) ([]*domain.Comment, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	comments := make([]*domain.Comment, 0)
+	if _, ok := r.lookup(ctx, taskID); !ok {
+		return comments, nil
+	}
+	for _, comment := range r.store.comments {
+		if comment.TaskID == taskID {
+			c := *comment
+			comments = append(comments, &c)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool { return comments[i].ID < comments[j].ID })
+	return comments, nil
+}
+
+// DeleteComment deletes a comment from a task
+func (r *TaskRepository) DeleteComment(ctx context.Context, taskID, commentID int64) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := r.lookup(ctx, taskID); !ok {
+		return domain.ErrCommentNotFound
+	}
+	comment, ok := s.comments[commentID]
+	if !ok || comment.TaskID != taskID {
+		return domain.ErrCommentNotFound
+	}
+	delete(s.comments, commentID)
+	return nil
+}
@@ -0,0 +1,156 @@
+// Package memory is an in-memory stand-in for the PostgreSQL repositories, for
+// demos and tests that run without a database. Data is lost on restart.
+package memory
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	"github.com/seldomhappy/vibe_architecture/logger"
+)
+
+// Store holds the tasks, dependencies, comments and outbox events shared by the
+// repositories and transaction manager
+type Store struct {
+	mu       sync.RWMutex
+	tasks    map[int64]*domain.Task
+	deps     map[int64]map[int64]struct{} // task ID -> IDs it depends on
+	comments map[int64]*domain.Comment
+	// dueNotified holds when the due soon reminder of a task went out
+	dueNotified map[int64]time.Time
+	outbox      map[int64]*domain.OutboxEvent
+
+	// IDs are never reused, not even after a rollback, like PostgreSQL sequences
+	nextTaskID    atomic.Int64
+	nextCommentID atomic.Int64
+	nextOutboxID  atomic.Int64
+
+	// txMu runs transactions one at a time, standing in for row locks
+	txMu sync.Mutex
+}
+
+// NewStore creates an empty store
+func NewStore() *Store {
+	return &Store{
+		tasks:       make(map[int64]*domain.Task),
+		deps:        make(map[int64]map[int64]struct{}),
+		comments:    make(map[int64]*domain.Comment),
+		dueNotified: make(map[int64]time.Time),
+		outbox:      make(map[int64]*domain.OutboxEvent),
+	}
+}
+
+// snapshot is a copy of the store's data, restored when a transaction fails
+type snapshot struct {
+	tasks       map[int64]*domain.Task
+	deps        map[int64]map[int64]struct{}
+	comments    map[int64]*domain.Comment
+	dueNotified map[int64]time.Time
+	outbox      map[int64]*domain.OutboxEvent
+}
+
+func (s *Store) snapshot() snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snap := snapshot{
+		tasks:       make(map[int64]*domain.Task, len(s.tasks)),
+		deps:        make(map[int64]map[int64]struct{}, len(s.deps)),
+		comments:    make(map[int64]*domain.Comment, len(s.comments)),
+		dueNotified: make(map[int64]time.Time, len(s.dueNotified)),
+		outbox:      make(map[int64]*domain.OutboxEvent, len(s.outbox)),
+	}
+	for id, task := range s.tasks {
+		snap.tasks[id] = cloneTask(task)
+	}
+	for id, set := range s.deps {
+		snap.deps[id] = make(map[int64]struct{}, len(set))
+		for dep := range set {
+			snap.deps[id][dep] = struct{}{}
+		}
+	}
+	for id, comment := range s.comments {
+		c := *comment
+		snap.comments[id] = &c
+	}
+	for id, at := range s.dueNotified {
+		snap.dueNotified[id] = at
+	}
+	for id, event := range s.outbox {
+		c := *event
+		snap.outbox[id] = &c
+	}
+	return snap
+}
+
+func (s *Store) restore(snap snapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tasks = snap.tasks
+	s.deps = snap.deps
+	s.comments = snap.comments
+	s.dueNotified = snap.dueNotified
+	s.outbox = snap.outbox
+}
+
+// TxManager runs functions one at a time, undoing their changes if they fail
+type TxManager struct {
+	store  *Store
+	logger logger.ILogger
+}
+
+// NewTxManager creates a transaction manager over store
+func NewTxManager(store *Store, log logger.ILogger) *TxManager {
+	return &TxManager{
+		store:  store,
+		logger: log,
+	}
+}
+
+// WithTransaction runs fn with a nil pgx.Tx; the repository ignores it.
+// If fn fails or panics, the store is put back the way it was. There is no
+// isolation: a write made outside the transaction meanwhile is undone too.
+func (tm *TxManager) WithTransaction(ctx context.Context, fn func(ctx context.Context, tx pgx.Tx) error) (err error) {
+	tm.store.txMu.Lock()
+	defer tm.store.txMu.Unlock()
+
+	snap := tm.store.snapshot()
+	defer func() {
+		if p := recover(); p != nil {
+			tm.store.restore(snap)
+			panic(p)
+		} else if err != nil {
+			tm.store.restore(snap)
+		}
+	}()
+
+	err = fn(ctx, nil)
+	return err
+}
+
+// cloneTask copies a task, including the values behind its pointer fields, so
+// callers and the store never share memory
+func cloneTask(t *domain.Task) *domain.Task {
+	c := *t
+	c.AssignedTo = clonePtr(t.AssignedTo)
+	c.DueDate = clonePtr(t.DueDate)
+	c.RecurrenceRule = clonePtr(t.RecurrenceRule)
+	c.ParentTaskID = clonePtr(t.ParentTaskID)
+	c.ParentID = clonePtr(t.ParentID)
+	c.SubtaskCount = nil
+	c.DependsOn = nil
+	return &c
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
@@ -0,0 +1,568 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	"github.com/seldomhappy/vibe_architecture/internal/repository"
+	"github.com/seldomhappy/vibe_architecture/logger"
+)
+
+// TaskRepository implements task data access on a Store. It keeps the
+// behavior of the PostgreSQL repository, including its constraint errors.
+type TaskRepository struct {
+	store  *Store
+	logger logger.ILogger
+}
+
+// NewTaskRepository creates a new in-memory task repository
+func NewTaskRepository(store *Store, log logger.ILogger) *TaskRepository {
+	return &TaskRepository{
+		store:  store,
+		logger: log,
+	}
+}
+
+// CreateTx creates a new task inside the current transaction
+func (r *TaskRepository) CreateTx(ctx context.Context, tx pgx.Tx, task *domain.Task) error {
+	return r.Create(ctx, task)
+}
+
+// Create creates a new task
+func (r *TaskRepository) Create(ctx context.Context, task *domain.Task) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if task.UUID == uuid.Nil {
+		task.UUID = uuid.New()
+	}
+	if err := r.checkReferences(task); err != nil {
+		return err
+	}
+	for _, existing := range s.tasks {
+		if existing.UUID == task.UUID {
+			return domain.ErrConflict
+		}
+		if task.ParentTaskID != nil && existing.ParentTaskID != nil && *existing.ParentTaskID == *task.ParentTaskID {
+			return domain.ErrConflict
+		}
+	}
+
+	now := time.Now()
+	task.ID = s.nextTaskID.Add(1)
+	task.CreatedAt = now
+	task.UpdatedAt = now
+	s.tasks[task.ID] = cloneTask(task)
+
+	r.logger.Debug("Task created with ID: %d", task.ID)
+	return nil
+}
+
+// checkReferences stands in for the foreign keys on parent_task_id and parent_id
+func (r *TaskRepository) checkReferences(task *domain.Task) error {
+	for _, ref := range []*int64{task.ParentTaskID, task.ParentID} {
+		if ref == nil {
+			continue
+		}
+		if _, ok := r.store.tasks[*ref]; !ok {
+			return domain.ErrReferenceNotFound
+		}
+	}
+	return nil
+}
+
+// visible reports whether task belongs to the tenant of ctx, like the tenant
+// condition of the PostgreSQL queries: every task is to a context made with
+// pkgcontext.WithAllTenants, none is to a context without a tenant.
+func visible(ctx context.Context, task *domain.Task) bool {
+	tenantID := repository.TenantOf(ctx)
+	return tenantID == repository.AllTenants || task.TenantID == tenantID
+}
+
+// lookup returns the task with the ID if it is visible to the tenant of ctx.
+// The store lock must be held.
+func (r *TaskRepository) lookup(ctx context.Context, id int64) (*domain.Task, bool) {
+	task, ok := r.store.tasks[id]
+	if !ok || !visible(ctx, task) {
+		return nil, false
+	}
+	return task, true
+}
+
+// GetByID retrieves a task by ID
+func (r *TaskRepository) GetByID(ctx context.Context, id int64) (*domain.Task, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	task, ok := r.lookup(ctx, id)
+	if !ok {
+		return nil, domain.ErrTaskNotFound
+	}
+	return cloneTask(task), nil
+}
+
+// Exists reports whether a task exists
+func (r *TaskRepository) Exists(ctx context.Context, id int64) (bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	_, ok := r.lookup(ctx, id)
+	return ok, nil
+}
+
+// AssignTx is Assign inside the current transaction
+func (r *TaskRepository) AssignTx(ctx context.Context, tx pgx.Tx, id, userID int64) (*domain.Task, error) {
+	return r.Assign(ctx, id, userID)
+}
+
+// Assign assigns a task to a user following the rules of domain.Task.Assign.
+// It returns the assigned task, or nil if no assignable task has the ID.
+func (r *TaskRepository) Assign(ctx context.Context, id, userID int64) (*domain.Task, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.lookup(ctx, id)
+	if !ok {
+		return nil, nil
+	}
+	if err := stored.Assign(userID, time.Now()); err != nil {
+		return nil, nil
+	}
+	return cloneTask(stored), nil
+}
+
+// CompleteTx completes a task following the rules of domain.Task.Complete,
+// unless it has incomplete dependencies or, with requireSubtasks, open
+// subtasks. It returns the completed task, or nil if no completable task has the ID.
+func (r *TaskRepository) CompleteTx(ctx context.Context, tx pgx.Tx, id int64, requireSubtasks bool) (*domain.Task, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.lookup(ctx, id)
+	if !ok || r.blocksCompletion(ctx, id, requireSubtasks) {
+		return nil, nil
+	}
+	if err := stored.Complete(time.Now()); err != nil {
+		return nil, nil
+	}
+	return cloneTask(stored), nil
+}
+
+// blocksCompletion reports whether an incomplete dependency or, with
+// requireSubtasks, an open subtask keeps the task from completing.
+// The store lock must be held.
+func (r *TaskRepository) blocksCompletion(ctx context.Context, id int64, requireSubtasks bool) bool {
+	for depID := range r.store.deps[id] {
+		if dep, ok := r.lookup(ctx, depID); ok && dep.Status != domain.TaskStatusCompleted {
+			return true
+		}
+	}
+	if !requireSubtasks {
+		return false
+	}
+	for _, task := range r.store.tasks {
+		if task.ParentID != nil && *task.ParentID == id && visible(ctx, task) && isOpen(task.Status) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetByIDs retrieves many tasks, keyed by ID; IDs with no task are absent
+func (r *TaskRepository) GetByIDs(ctx context.Context, ids []int64) (map[int64]*domain.Task, error) {
+	ids = repository.UniqueIDs(ids)
+	if len(ids) > repository.MaxGetByIDs {
+		return nil, domain.ErrBatchTooLarge
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	tasks := make(map[int64]*domain.Task, len(ids))
+	for _, id := range ids {
+		if task, ok := r.lookup(ctx, id); ok {
+			tasks[id] = cloneTask(task)
+		}
+	}
+	return tasks, nil
+}
+
+// GetByIDForUpdate retrieves a task by ID. Transactions already run one at a
+// time, so there is no row to lock.
+func (r *TaskRepository) GetByIDForUpdate(ctx context.Context, tx pgx.Tx, id int64) (*domain.Task, error) {
+	return r.GetByID(ctx, id)
+}
+
+// GetIDByUUID returns the ID of the task with the given UUID
+func (r *TaskRepository) GetIDByUUID(ctx context.Context, id uuid.UUID) (int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, task := range r.store.tasks {
+		if task.UUID == id && visible(ctx, task) {
+			return task.ID, nil
+		}
+	}
+	return 0, domain.ErrTaskNotFound
+}
+
+// GetAll retrieves all tasks matching filter, newest first
+func (r *TaskRepository) GetAll(ctx context.Context, filter repository.TaskFilter) ([]*domain.Task, error) {
+	tasks := make([]*domain.Task, 0)
+	err := r.IterateAll(ctx, filter, func(task *domain.Task) error {
+		tasks = append(tasks, task)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// IterateAll calls fn with each task matching filter, newest first. The store
+// lock isn't held while fn runs. It stops at the first error from fn, or once
+// ctx is done, and returns it.
+func (r *TaskRepository) IterateAll(ctx context.Context, filter repository.TaskFilter, fn func(*domain.Task) error) error {
+	r.store.mu.RLock()
+	tasks := make([]*domain.Task, 0)
+	for _, task := range r.store.tasks {
+		if matches(task, filter) {
+			tasks = append(tasks, cloneTask(task))
+		}
+	}
+	r.store.mu.RUnlock()
+
+	// Sorting needs every match, so unlike PostgreSQL the page is copied first
+	sortNewestFirst(tasks)
+	for _, task := range paginate(tasks, filter.Limit, filter.Offset) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetListVersion returns the version of the tasks matching filter, ignoring its
+// limit and offset
+func (r *TaskRepository) GetListVersion(ctx context.Context, filter repository.TaskFilter) (repository.ListVersion, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var version repository.ListVersion
+	for _, task := range r.store.tasks {
+		if !matches(task, filter) {
+			continue
+		}
+		version.Count++
+		if task.UpdatedAt.After(version.LastModified) {
+			version.LastModified = task.UpdatedAt
+		}
+	}
+	return version, nil
+}
+
+// matches reports whether task passes every filter that is set
+func matches(task *domain.Task, filter repository.TaskFilter) bool {
+	if filter.TenantID != repository.AllTenants && task.TenantID != filter.TenantID {
+		return false
+	}
+	if filter.Status != nil && task.Status != *filter.Status {
+		return false
+	}
+	if filter.Priority != nil && task.Priority != *filter.Priority {
+		return false
+	}
+	if filter.AssignedTo != nil && (task.AssignedTo == nil || *task.AssignedTo != *filter.AssignedTo) {
+		return false
+	}
+	if filter.ParentID != nil && (task.ParentID == nil || *task.ParentID != *filter.ParentID) {
+		return false
+	}
+	if filter.CreatedBy != nil && task.CreatedBy != *filter.CreatedBy {
+		return false
+	}
+	if filter.CreatedAfter != nil && task.CreatedAt.Before(*filter.CreatedAfter) {
+		return false
+	}
+	if filter.CreatedBefore != nil && task.CreatedAt.After(*filter.CreatedBefore) {
+		return false
+	}
+	if filter.DueBefore != nil && (task.DueDate == nil || !task.DueDate.Before(*filter.DueBefore)) {
+		return false
+	}
+	for _, status := range filter.ExcludeStatuses {
+		if task.Status == status {
+			return false
+		}
+	}
+	return true
+}
+
+// sortNewestFirst orders tasks by created_at descending. Tasks created in the
+// same instant are ordered by ID so pages are stable.
+func sortNewestFirst(tasks []*domain.Task) {
+	sort.Slice(tasks, func(i, j int) bool {
+		if !tasks[i].CreatedAt.Equal(tasks[j].CreatedAt) {
+			return tasks[i].CreatedAt.After(tasks[j].CreatedAt)
+		}
+		return tasks[i].ID > tasks[j].ID
+	})
+}
+
+// paginate applies OFFSET and LIMIT; a limit of 0 means no limit
+func paginate(tasks []*domain.Task, limit, offset int) []*domain.Task {
+	if offset > 0 {
+		if offset >= len(tasks) {
+			return tasks[:0]
+		}
+		tasks = tasks[offset:]
+	}
+	if limit > 0 && limit < len(tasks) {
+		tasks = tasks[:limit]
+	}
+	return tasks
+}
+
+// Update updates an existing task
+func (r *TaskRepository) Update(ctx context.Context, task *domain.Task) error {
+	return r.update(ctx, task, nil)
+}
+
+// UpdateTxIfUnchanged updates a task only if its updated_at still equals
+// updatedAt, and returns domain.ErrTaskModified otherwise
+func (r *TaskRepository) UpdateTxIfUnchanged(ctx context.Context, tx pgx.Tx, task *domain.Task, updatedAt time.Time) error {
+	return r.update(ctx, task, &updatedAt)
+}
+
+func (r *TaskRepository) update(ctx context.Context, task *domain.Task, expected *time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.lookup(ctx, task.ID)
+	if !ok {
+		return domain.ErrTaskNotFound
+	}
+	if expected != nil && !stored.UpdatedAt.Equal(*expected) {
+		return domain.ErrTaskModified
+	}
+
+	// Only the columns the SQL UPDATE sets
+	stored.Name = task.Name
+	stored.Description = task.Description
+	stored.Status = task.Status
+	stored.Priority = task.Priority
+	stored.AssignedTo = clonePtr(task.AssignedTo)
+	if !equalTimes(stored.DueDate, task.DueDate) {
+		// A new due date gets a due soon reminder of its own
+		delete(r.store.dueNotified, task.ID)
+	}
+	stored.DueDate = clonePtr(task.DueDate)
+	stored.RecurrenceRule = clonePtr(task.RecurrenceRule)
+	stored.UpdatedAt = time.Now()
+
+	task.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+// UpdateTx updates an existing task inside the current transaction
+func (r *TaskRepository) UpdateTx(ctx context.Context, tx pgx.Tx, task *domain.Task) error {
+	return r.Update(ctx, task)
+}
+
+// Delete deletes a task, along with its subtasks, dependencies and comments
+func (r *TaskRepository) Delete(ctx context.Context, id int64) error {
+	_, err := r.DeleteTree(ctx, id)
+	return err
+}
+
+// DeleteTx deletes a task inside the current transaction
+func (r *TaskRepository) DeleteTx(ctx context.Context, tx pgx.Tx, id int64) error {
+	return r.Delete(ctx, id)
+}
+
+// DeleteTreeTx is DeleteTree inside the current transaction
+func (r *TaskRepository) DeleteTreeTx(ctx context.Context, tx pgx.Tx, id int64) ([]*domain.Task, error) {
+	return r.DeleteTree(ctx, id)
+}
+
+// DeleteTree deletes a task and all of its subtasks, returning the deleted tasks
+func (r *TaskRepository) DeleteTree(ctx context.Context, id int64) ([]*domain.Task, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := r.lookup(ctx, id); !ok {
+		return nil, domain.ErrTaskNotFound
+	}
+
+	deleted := []int64{id}
+	for i := 0; i < len(deleted); i++ {
+		for _, task := range s.tasks {
+			if task.ParentID != nil && *task.ParentID == deleted[i] {
+				deleted = append(deleted, task.ID)
+			}
+		}
+	}
+
+	tasks := make([]*domain.Task, len(deleted))
+	for i, taskID := range deleted {
+		tasks[i] = s.tasks[taskID]
+		delete(s.tasks, taskID)
+		delete(s.deps, taskID)
+		delete(s.dueNotified, taskID)
+	}
+	for _, taskID := range deleted {
+		for _, set := range s.deps {
+			delete(set, taskID)
+		}
+		for commentID, comment := range s.comments {
+			if comment.TaskID == taskID {
+				delete(s.comments, commentID)
+			}
+		}
+		for _, task := range s.tasks {
+			if task.ParentTaskID != nil && *task.ParentTaskID == taskID {
+				task.ParentTaskID = nil
+			}
+		}
+	}
+
+	return tasks, nil
+}
+
+// GetRecurringWithoutNext returns completed recurring tasks whose next occurrence
+// has not been generated yet, least recently updated first
+func (r *TaskRepository) GetRecurringWithoutNext(ctx context.Context, limit int) ([]*domain.Task, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	hasNext := make(map[int64]bool)
+	for _, task := range r.store.tasks {
+		if task.ParentTaskID != nil {
+			hasNext[*task.ParentTaskID] = true
+		}
+	}
+
+	tasks := make([]*domain.Task, 0)
+	for _, task := range r.store.tasks {
+		if task.RecurrenceRule != nil && task.Status == domain.TaskStatusCompleted && !hasNext[task.ID] && visible(ctx, task) {
+			tasks = append(tasks, cloneTask(task))
+		}
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].UpdatedAt.Before(tasks[j].UpdatedAt)
+	})
+	return paginate(tasks, limit, 0), nil
+}
+
+// GetDueWithin returns open tasks due between now and now+d whose due soon
+// reminder hasn't gone out yet, soonest first
+func (r *TaskRepository) GetDueWithin(ctx context.Context, d time.Duration) ([]*domain.Task, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	now := time.Now()
+	end := now.Add(d)
+	tasks := make([]*domain.Task, 0)
+	for _, task := range r.store.tasks {
+		if task.DueDate == nil || task.DueDate.Before(now) || task.DueDate.After(end) || !isOpen(task.Status) || !visible(ctx, task) {
+			continue
+		}
+		if _, notified := r.store.dueNotified[task.ID]; notified {
+			continue
+		}
+		tasks = append(tasks, cloneTask(task))
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].DueDate.Before(*tasks[j].DueDate)
+	})
+	return tasks, nil
+}
+
+// MarkDueNotifiedTx is MarkDueNotified inside the current transaction
+func (r *TaskRepository) MarkDueNotifiedTx(ctx context.Context, tx pgx.Tx, id int64, at time.Time) (bool, error) {
+	return r.MarkDueNotified(ctx, id, at)
+}
+
+// MarkDueNotified records that the due soon reminder of a task went out. It
+// returns false if it already had.
+func (r *TaskRepository) MarkDueNotified(ctx context.Context, id int64, at time.Time) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.lookup(ctx, id); !ok {
+		return false, nil
+	}
+	if _, notified := r.store.dueNotified[id]; notified {
+		return false, nil
+	}
+	r.store.dueNotified[id] = at
+	return true, nil
+}
+
+// CountByStatus returns the number of tasks per status
+func (r *TaskRepository) CountByStatus(ctx context.Context) (map[domain.TaskStatus]int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	result := make(map[domain.TaskStatus]int64)
+	for _, task := range r.store.tasks {
+		if !visible(ctx, task) {
+			continue
+		}
+		result[task.Status]++
+	}
+	return result, nil
+}
+
+// CountByPriority returns the number of tasks per priority
+func (r *TaskRepository) CountByPriority(ctx context.Context) (map[domain.Priority]int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	result := make(map[domain.Priority]int64)
+	for _, task := range r.store.tasks {
+		if !visible(ctx, task) {
+			continue
+		}
+		result[task.Priority]++
+	}
+	return result, nil
+}
+
+// CountOverdue returns the number of open tasks whose due date is before now
+func (r *TaskRepository) CountOverdue(ctx context.Context, now time.Time) (int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var count int64
+	for _, task := range r.store.tasks {
+		if task.DueDate != nil && task.DueDate.Before(now) && isOpen(task.Status) && visible(ctx, task) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// isOpen reports whether a task in status still needs work
+func isOpen(status domain.TaskStatus) bool {
+	return status != domain.TaskStatusCompleted && status != domain.TaskStatusCancelled
+}
+
+// equalTimes compares two optional times like IS NOT DISTINCT FROM
+func equalTimes(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,153 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	"github.com/seldomhappy/vibe_architecture/internal/repository"
+	"github.com/seldomhappy/vibe_architecture/logger"
+)
+
+// WebhookRepository implements webhook data access in memory. Webhooks are
+// never written in a transaction, so they are kept apart from the Store.
+type WebhookRepository struct {
+	logger logger.ILogger
+
+	mu          sync.RWMutex
+	webhooks    map[int64]*domain.Webhook
+	deadLetters map[int64]*domain.WebhookDeadLetter
+
+	nextWebhookID    atomic.Int64
+	nextDeadLetterID atomic.Int64
+}
+
+// NewWebhookRepository creates a new in-memory webhook repository
+func NewWebhookRepository(log logger.ILogger) *WebhookRepository {
+	return &WebhookRepository{
+		logger:      log,
+		webhooks:    make(map[int64]*domain.Webhook),
+		deadLetters: make(map[int64]*domain.WebhookDeadLetter),
+	}
+}
+
+// cloneWebhook copies a webhook so callers and the repository never share memory
+func cloneWebhook(w *domain.Webhook) *domain.Webhook {
+	c := *w
+	c.EventTypes = append([]domain.EventType{}, w.EventTypes...)
+	return &c
+}
+
+// ownedBy reports whether something of tenantID is visible to the tenant of ctx
+func ownedBy(ctx context.Context, tenantID string) bool {
+	current := repository.TenantOf(ctx)
+	return current == repository.AllTenants || tenantID == current
+}
+
+// lookup returns the webhook with the ID if it is visible to the tenant of
+// ctx. The lock must be held.
+func (r *WebhookRepository) lookup(ctx context.Context, id int64) (*domain.Webhook, bool) {
+	webhook, ok := r.webhooks[id]
+	if !ok || !ownedBy(ctx, webhook.TenantID) {
+		return nil, false
+	}
+	return webhook, true
+}
+
+// Create stores a new webhook
+func (r *WebhookRepository) Create(ctx context.Context, webhook *domain.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	webhook.ID = r.nextWebhookID.Add(1)
+	webhook.CreatedAt = time.Now()
+	r.webhooks[webhook.ID] = cloneWebhook(webhook)
+
+	r.logger.Debug("Webhook created with ID: %d", webhook.ID)
+	return nil
+}
+
+// GetByID retrieves a webhook by ID
+func (r *WebhookRepository) GetByID(ctx context.Context, id int64) (*domain.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	webhook, ok := r.lookup(ctx, id)
+	if !ok {
+		return nil, domain.ErrWebhookNotFound
+	}
+	return cloneWebhook(webhook), nil
+}
+
+// GetAll returns every webhook of the tenant, oldest first
+func (r *WebhookRepository) GetAll(ctx context.Context) ([]*domain.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	webhooks := make([]*domain.Webhook, 0, len(r.webhooks))
+	for _, webhook := range r.webhooks {
+		if ownedBy(ctx, webhook.TenantID) {
+			webhooks = append(webhooks, cloneWebhook(webhook))
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].ID < webhooks[j].ID })
+	return webhooks, nil
+}
+
+// Delete deletes a webhook along with its dead letters
+func (r *WebhookRepository) Delete(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.lookup(ctx, id); !ok {
+		return domain.ErrWebhookNotFound
+	}
+	delete(r.webhooks, id)
+	for letterID, letter := range r.deadLetters {
+		if letter.WebhookID == id {
+			delete(r.deadLetters, letterID)
+		}
+	}
+	return nil
+}
+
+// CreateDeadLetter stores an event that could not be delivered, for the
+// tenant of its webhook
+func (r *WebhookRepository) CreateDeadLetter(ctx context.Context, letter *domain.WebhookDeadLetter) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	webhook, ok := r.webhooks[letter.WebhookID]
+	if !ok {
+		return domain.ErrWebhookNotFound
+	}
+	letter.ID = r.nextDeadLetterID.Add(1)
+	letter.TenantID = webhook.TenantID
+	letter.FailedAt = time.Now()
+	c := *letter
+	r.deadLetters[letter.ID] = &c
+	return nil
+}
+
+// GetDeadLetters returns up to limit dead letters of a webhook, newest first
+func (r *WebhookRepository) GetDeadLetters(ctx context.Context, webhookID int64, limit int) ([]*domain.WebhookDeadLetter, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	letters := []*domain.WebhookDeadLetter{}
+	for _, letter := range r.deadLetters {
+		if letter.WebhookID == webhookID && ownedBy(ctx, letter.TenantID) {
+			c := *letter
+			letters = append(letters, &c)
+		}
+	}
+	// IDs increase with failure time
+	sort.Slice(letters, func(i, j int) bool { return letters[i].ID > letters[j].ID })
+	if limit > 0 && limit < len(letters) {
+		letters = letters[:limit]
+	}
+	return letters, nil
+}
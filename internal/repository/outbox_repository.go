@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	"github.com/seldomhappy/vibe_architecture/internal/infrastructure/postgres"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/tracing"
+	"github.com/seldomhappy/vibe_architecture/logger"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// OutboxRepository implements event outbox data access
+type OutboxRepository struct {
+	db     *postgres.DB
+	logger logger.ILogger
+}
+
+// NewOutboxRepository creates a new outbox repository
+func NewOutboxRepository(db *postgres.DB, log logger.ILogger) *OutboxRepository {
+	return &OutboxRepository{
+		db:     db,
+		logger: log,
+	}
+}
+
+// Add stores an event in the outbox
+func (r *OutboxRepository) Add(ctx context.Context, event *domain.OutboxEvent) error {
+	ctx, span := tracing.StartSpan(ctx, "repository", "add_outbox_event")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("task.id", event.TaskID))
+
+	return r.add(ctx, r.db, event)
+}
+
+// AddTx stores an event in the outbox inside the given transaction, so it is
+// only kept if the change it describes commits
+func (r *OutboxRepository) AddTx(ctx context.Context, tx pgx.Tx, event *domain.OutboxEvent) error {
+	ctx, span := tracing.StartSpan(ctx, "repository", "add_outbox_event_tx")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("task.id", event.TaskID))
+
+	return r.add(ctx, tx, event)
+}
+
+func (r *OutboxRepository) add(ctx context.Context, q queryRower, event *domain.OutboxEvent) error {
+	query := `
+		INSERT INTO event_outbox (event_type, task_id, event, request_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	err := q.QueryRow(ctx, query, event.EventType, event.TaskID, event.Event, event.RequestID).
+		Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		r.logger.Error("Failed to add outbox event: %v", err)
+		tracing.RecordError(ctx, err)
+		return fmt.Errorf("failed to add outbox event: %w", err)
+	}
+
+	return nil
+}
+
+// PendingForUpdate returns up to limit events of the outbox, oldest first, and
+// locks them until tx ends. Events another replica has locked are skipped, so
+// no two relays publish the same event.
+func (r *OutboxRepository) PendingForUpdate(ctx context.Context, tx pgx.Tx, limit int) ([]*domain.OutboxEvent, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "get_pending_outbox_events_for_update")
+	defer span.End()
+
+	query := `
+		SELECT id, event_type, task_id, event, request_id, created_at
+		FROM event_outbox
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := tx.Query(ctx, query, limit)
+	if err != nil {
+		r.logger.Error("Failed to get outbox events: %v", err)
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to get outbox events: %w", err)
+	}
+
+	events, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*domain.OutboxEvent, error) {
+		event := &domain.OutboxEvent{}
+		err := row.Scan(&event.ID, &event.EventType, &event.TaskID, &event.Event, &event.RequestID, &event.CreatedAt)
+		return event, err
+	})
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to scan outbox events: %w", err)
+	}
+
+	return events, nil
+}
+
+// DeleteTx removes a published event from the outbox inside the given transaction
+func (r *OutboxRepository) DeleteTx(ctx context.Context, tx pgx.Tx, id int64) error {
+	ctx, span := tracing.StartSpan(ctx, "repository", "delete_outbox_event_tx")
+	defer span.End()
+
+	query := `DELETE FROM event_outbox WHERE id = $1`
+
+	if _, err := tx.Exec(ctx, query, id); err != nil {
+		r.logger.Error("Failed to delete outbox event: %v", err)
+		tracing.RecordError(ctx, err)
+		return fmt.Errorf("failed to delete outbox event: %w", err)
+	}
+
+	return nil
+}
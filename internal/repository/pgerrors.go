@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+)
+
+// SQLSTATE codes of integrity constraint violations
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgCheckViolation      = "23514"
+)
+
+// constraintError maps an integrity constraint violation to the matching domain
+// error, or returns nil if err is not one
+func constraintError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return nil
+	}
+
+	switch pgErr.Code {
+	case pgUniqueViolation:
+		return domain.ErrConflict
+	case pgForeignKeyViolation:
+		return domain.ErrReferenceNotFound
+	case pgCheckViolation:
+		return domain.ErrInvalidInput
+	}
+	return nil
+}
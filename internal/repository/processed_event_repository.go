@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/seldomhappy/vibe_architecture/internal/infrastructure/postgres"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/tracing"
+	"github.com/seldomhappy/vibe_architecture/logger"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ProcessedEventRepository records the Kafka events the consumer has processed
+type ProcessedEventRepository struct {
+	db     *postgres.DB
+	logger logger.ILogger
+}
+
+// NewProcessedEventRepository creates a new processed event repository
+func NewProcessedEventRepository(db *postgres.DB, log logger.ILogger) *ProcessedEventRepository {
+	return &ProcessedEventRepository{
+		db:     db,
+		logger: log,
+	}
+}
+
+// Claim records an event as processed and reports false if it already was.
+// Concurrent claims of the same event are settled by the primary key: one wins.
+func (r *ProcessedEventRepository) Claim(ctx context.Context, eventID string) (bool, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "claim_processed_event")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("event.id", eventID))
+
+	query := `
+		INSERT INTO processed_events (event_id)
+		VALUES ($1)
+		ON CONFLICT (event_id) DO NOTHING
+	`
+
+	result, err := r.db.Exec(ctx, query, eventID)
+	if err != nil {
+		r.logger.Error("Failed to claim processed event: %v", err)
+		tracing.RecordError(ctx, err)
+		return false, fmt.Errorf("failed to claim processed event: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// Release forgets an event, so it is processed when it comes again
+func (r *ProcessedEventRepository) Release(ctx context.Context, eventID string) error {
+	ctx, span := tracing.StartSpan(ctx, "repository", "release_processed_event")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("event.id", eventID))
+
+	query := `DELETE FROM processed_events WHERE event_id = $1`
+
+	if _, err := r.db.Exec(ctx, query, eventID); err != nil {
+		r.logger.Error("Failed to release processed event: %v", err)
+		tracing.RecordError(ctx, err)
+		return fmt.Errorf("failed to release processed event: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteBefore forgets the events processed before t and returns how many
+func (r *ProcessedEventRepository) DeleteBefore(ctx context.Context, t time.Time) (int64, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "delete_processed_events")
+	defer span.End()
+
+	query := `DELETE FROM processed_events WHERE processed_at < $1`
+
+	result, err := r.db.Exec(ctx, query, t)
+	if err != nil {
+		r.logger.Error("Failed to delete processed events: %v", err)
+		tracing.RecordError(ctx, err)
+		return 0, fmt.Errorf("failed to delete processed events: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CountSubtasks returns the number of direct subtasks of a task
+func (r *TaskRepository) CountSubtasks(ctx context.Context, parentID int64) (int, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "count_subtasks")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("task.id", parentID))
+
+	query := `SELECT COUNT(*) FROM tasks WHERE parent_id = $1 AND ($2 = '*' OR tenant_id = $2)`
+
+	var count int
+	if err := r.db.QueryRow(ctx, query, parentID, TenantOf(ctx)).Scan(&count); err != nil {
+		r.logger.Error("Failed to count subtasks: %v", err)
+		tracing.RecordError(ctx, err)
+		return 0, fmt.Errorf("failed to count subtasks: %w", err)
+	}
+
+	return count, nil
+}
+
+// CountIncompleteSubtasksTx returns the number of direct subtasks that are still
+// open, as seen by tx. Cancelled subtasks don't block their parent.
+func (r *TaskRepository) CountIncompleteSubtasksTx(ctx context.Context, tx pgx.Tx, parentID int64) (int, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "count_incomplete_subtasks_tx")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("task.id", parentID))
+
+	query := `SELECT COUNT(*) FROM tasks WHERE parent_id = $1 AND status NOT IN ($2, $3) AND ($4 = '*' OR tenant_id = $4)`
+
+	var count int
+	err := tx.QueryRow(ctx, query, parentID, domain.TaskStatusCompleted, domain.TaskStatusCancelled, TenantOf(ctx)).Scan(&count)
+	if err != nil {
+		r.logger.Error("Failed to count incomplete subtasks: %v", err)
+		tracing.RecordError(ctx, err)
+		return 0, fmt.Errorf("failed to count incomplete subtasks: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetTreeForUpdate returns a task and all of its subtasks (recursively), locked
+// for update inside tx. Parents come before their subtasks; the task itself is first.
+func (r *TaskRepository) GetTreeForUpdate(ctx context.Context, tx pgx.Tx, id int64) ([]*domain.Task, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "get_task_tree_for_update")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("task.id", id))
+
+	query := `
+		WITH RECURSIVE tree(id, depth) AS (
+			SELECT id, 0 FROM tasks WHERE id = $1 AND ($2 = '*' OR tenant_id = $2)
+			UNION
+			SELECT t.id, tree.depth + 1 FROM tasks t JOIN tree ON t.parent_id = tree.id
+		)
+		SELECT ` + taskColumns + `
+		FROM tasks JOIN tree USING (id)
+		ORDER BY tree.depth, id
+		FOR UPDATE OF tasks
+	`
+
+	rows, err := tx.Query(ctx, query, id, TenantOf(ctx))
+	if err != nil {
+		r.logger.Error("Failed to lock task tree: %v", err)
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to get task tree: %w", err)
+	}
+
+	tasks, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*domain.Task, error) {
+		return scanTask(row)
+	})
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to scan task tree: %w", err)
+	}
+
+	if len(tasks) == 0 {
+		return nil, domain.ErrTaskNotFound
+	}
+
+	span.SetAttributes(attribute.Int("tasks.count", len(tasks)))
+	return tasks, nil
+}
+
+// DeleteTree deletes a task together with all of its subtasks (recursively)
+// and returns every deleted task as it was before the deletion
+func (r *TaskRepository) DeleteTree(ctx context.Context, id int64) ([]*domain.Task, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "delete_task_tree")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("task.id", id))
+
+	return r.deleteTree(ctx, r.db, id)
+}
+
+// DeleteTreeTx is DeleteTree inside the given transaction
+func (r *TaskRepository) DeleteTreeTx(ctx context.Context, tx pgx.Tx, id int64) ([]*domain.Task, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "delete_task_tree_tx")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("task.id", id))
+
+	return r.deleteTree(ctx, tx, id)
+}
+
+func (r *TaskRepository) deleteTree(ctx context.Context, q querier, id int64) ([]*domain.Task, error) {
+	query := `
+		WITH RECURSIVE tree(id) AS (
+			SELECT id FROM tasks WHERE id = $1 AND ($2 = '*' OR tenant_id = $2)
+			UNION
+			SELECT t.id FROM tasks t JOIN tree ON t.parent_id = tree.id
+		)
+		DELETE FROM tasks WHERE id IN (SELECT id FROM tree)
+		RETURNING ` + taskColumns + `
+	`
+
+	rows, err := q.Query(ctx, query, id, TenantOf(ctx))
+	if err != nil {
+		r.logger.Error("Failed to delete task tree: %v", err)
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to delete task: %w", err)
+	}
+
+	tasks, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*domain.Task, error) {
+		return scanTask(row)
+	})
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to delete task: %w", err)
+	}
+
+	if len(tasks) == 0 {
+		return nil, domain.ErrTaskNotFound
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("tasks.deleted", len(tasks)))
+	return tasks, nil
+}
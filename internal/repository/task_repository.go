@@ -6,9 +6,12 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/seldomhappy/vibe_architecture/internal/domain"
 	"github.com/seldomhappy/vibe_architecture/internal/infrastructure/postgres"
+	pkgcontext "github.com/seldomhappy/vibe_architecture/internal/pkg/context"
 	"github.com/seldomhappy/vibe_architecture/internal/pkg/tracing"
 	"github.com/seldomhappy/vibe_architecture/logger"
 	"go.opentelemetry.io/otel/attribute"
@@ -22,11 +25,77 @@ type TaskRepository struct {
 
 // TaskFilter represents filters for listing tasks
 type TaskFilter struct {
+	// TenantID only matches the tasks of one tenant, or of every tenant when
+	// it is AllTenants; empty matches none. Set it with TenantOf.
+	TenantID   string
 	Status     *domain.TaskStatus
 	Priority   *domain.Priority
 	AssignedTo *int64
-	Limit      int
-	Offset     int
+	ParentID   *int64
+	CreatedBy  *int64
+	// CreatedAfter and CreatedBefore bound created_at, inclusive
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	// DueBefore only matches tasks with a due date before it
+	DueBefore *time.Time
+	// ExcludeStatuses leaves out tasks in any of these statuses
+	ExcludeStatuses []domain.TaskStatus
+	Limit           int
+	Offset          int
+}
+
+// queryRower is implemented by both *postgres.DB and pgx.Tx
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// querier is implemented by both *postgres.DB and pgx.Tx
+type querier interface {
+	queryRower
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// AllTenants is the tenant TenantOf returns for contexts made with
+// pkgcontext.WithAllTenants. Queries match it as '*', which is never a valid
+// tenant ID (see domain.ValidateTenantID).
+const AllTenants = "*"
+
+// TenantOf returns the tenant queries made with ctx are scoped to. Queries
+// match tenant_id against it unless it is AllTenants, so a context without a
+// tenant sees nothing; background jobs that work across tenants mark their
+// context with pkgcontext.WithAllTenants.
+func TenantOf(ctx context.Context) string {
+	if pkgcontext.IsAllTenants(ctx) {
+		return AllTenants
+	}
+	return pkgcontext.GetTenantID(ctx)
+}
+
+// taskColumns lists the columns scanned by scanTask, in order
+const taskColumns = `id, uuid, tenant_id, name, description, status, priority, assigned_to, due_date, recurrence_rule, parent_task_id, parent_id, created_by, created_at, updated_at`
+
+// scanTask scans a single task row selected with taskColumns
+func scanTask(row pgx.Row) (*domain.Task, error) {
+	task := &domain.Task{}
+	err := row.Scan(
+		&task.ID,
+		&task.UUID,
+		&task.TenantID,
+		&task.Name,
+		&task.Description,
+		&task.Status,
+		&task.Priority,
+		&task.AssignedTo,
+		&task.DueDate,
+		&task.RecurrenceRule,
+		&task.ParentTaskID,
+		&task.ParentID,
+		&task.CreatedBy,
+		&task.CreatedAt,
+		&task.UpdatedAt,
+	)
+	return task, err
 }
 
 // NewTaskRepository creates a new task repository
@@ -47,27 +116,58 @@ func (r *TaskRepository) Create(ctx context.Context, task *domain.Task) error {
 		attribute.String("task.priority", string(task.Priority)),
 	)
 
+	return r.create(ctx, r.db, task)
+}
+
+// CreateTx creates a new task inside the given transaction
+func (r *TaskRepository) CreateTx(ctx context.Context, tx pgx.Tx, task *domain.Task) error {
+	ctx, span := tracing.StartSpan(ctx, "repository", "create_task_tx")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("task.name", task.Name),
+		attribute.String("task.priority", string(task.Priority)),
+	)
+
+	return r.create(ctx, tx, task)
+}
+
+func (r *TaskRepository) create(ctx context.Context, q queryRower, task *domain.Task) error {
+	// created_at and updated_at come from the database clock, shared by every
+	// replica, and are read back into the task
 	query := `
-		INSERT INTO tasks (name, description, status, priority, assigned_to, created_by, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO tasks (uuid, tenant_id, name, description, status, priority, assigned_to, due_date, recurrence_rule, parent_task_id,
+			parent_id, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id, created_at, updated_at
 	`
 
-	now := time.Now()
-	err := r.db.QueryRow(ctx, query,
+	if task.UUID == uuid.Nil {
+		task.UUID = uuid.New()
+	}
+
+	err := q.QueryRow(ctx, query,
+		task.UUID,
+		task.TenantID,
 		task.Name,
 		task.Description,
 		task.Status,
 		task.Priority,
 		task.AssignedTo,
+		task.DueDate,
+		task.RecurrenceRule,
+		task.ParentTaskID,
+		task.ParentID,
 		task.CreatedBy,
-		now,
-		now,
 	).Scan(&task.ID, &task.CreatedAt, &task.UpdatedAt)
 
 	if err != nil {
-		r.logger.Error("Failed to create task: %v", err)
 		tracing.RecordError(ctx, err)
+		if mapped := constraintError(err); mapped != nil {
+			r.logger.Warn("Task rejected by constraint: %v", err)
+			return mapped
+		}
+		r.logger.Error("Failed to create task: %v", err)
 		return fmt.Errorf("failed to create task: %w", err)
 	}
 
@@ -82,24 +182,9 @@ func (r *TaskRepository) GetByID(ctx context.Context, id int64) (*domain.Task, e
 
 	span.SetAttributes(attribute.Int64("task.id", id))
 
-	query := `
-		SELECT id, name, description, status, priority, assigned_to, created_by, created_at, updated_at
-		FROM tasks
-		WHERE id = $1
-	`
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE id = $1 AND ($2 = '*' OR tenant_id = $2)`
 
-	task := &domain.Task{}
-	err := r.db.QueryRow(ctx, query, id).Scan(
-		&task.ID,
-		&task.Name,
-		&task.Description,
-		&task.Status,
-		&task.Priority,
-		&task.AssignedTo,
-		&task.CreatedBy,
-		&task.CreatedAt,
-		&task.UpdatedAt,
-	)
+	task, err := scanTask(r.db.QueryRow(ctx, query, id, TenantOf(ctx)))
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -113,81 +198,408 @@ func (r *TaskRepository) GetByID(ctx context.Context, id int64) (*domain.Task, e
 	return task, nil
 }
 
-// GetAll retrieves all tasks with optional filters
-func (r *TaskRepository) GetAll(ctx context.Context, filter TaskFilter) ([]*domain.Task, error) {
-	ctx, span := tracing.StartSpan(ctx, "repository", "get_all_tasks")
+// Exists reports whether a task exists, without loading it
+func (r *TaskRepository) Exists(ctx context.Context, id int64) (bool, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "task_exists")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("task.id", id))
+
+	query := `SELECT EXISTS(SELECT 1 FROM tasks WHERE id = $1 AND ($2 = '*' OR tenant_id = $2))`
+
+	var exists bool
+	if err := r.db.QueryRow(ctx, query, id, TenantOf(ctx)).Scan(&exists); err != nil {
+		r.logger.Error("Failed to check task exists: %v", err)
+		tracing.RecordError(ctx, err)
+		return false, fmt.Errorf("failed to check task exists: %w", err)
+	}
+
+	return exists, nil
+}
+
+// Assign assigns a task to a user in a single conditional UPDATE, following
+// the rules of domain.Task.Assign: only domain.AssignableStatuses can be
+// assigned, and a pending task starts. It returns the assigned task, or nil
+// if no assignable task has the ID.
+func (r *TaskRepository) Assign(ctx context.Context, id, userID int64) (*domain.Task, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "assign_task")
 	defer span.End()
 
+	span.SetAttributes(
+		attribute.Int64("task.id", id),
+		attribute.Int64("user.id", userID),
+	)
+
+	return r.assign(ctx, r.db, id, userID)
+}
+
+// AssignTx is Assign inside the given transaction
+func (r *TaskRepository) AssignTx(ctx context.Context, tx pgx.Tx, id, userID int64) (*domain.Task, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "assign_task_tx")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.Int64("task.id", id),
+		attribute.Int64("user.id", userID),
+	)
+
+	return r.assign(ctx, tx, id, userID)
+}
+
+func (r *TaskRepository) assign(ctx context.Context, q queryRower, id, userID int64) (*domain.Task, error) {
 	query := `
-		SELECT id, name, description, status, priority, assigned_to, created_by, created_at, updated_at
-		FROM tasks
-		WHERE 1=1
+		UPDATE tasks
+		SET assigned_to = $2,
+			status = CASE WHEN status = $4 THEN $5 ELSE status END,
+			updated_at = NOW()
+		WHERE id = $1 AND status = ANY($3) AND ($6 = '*' OR tenant_id = $6)
+		RETURNING ` + taskColumns + `
+	`
+
+	statuses := make([]string, len(domain.AssignableStatuses))
+	for i, status := range domain.AssignableStatuses {
+		statuses[i] = string(status)
+	}
+
+	task, err := scanTask(q.QueryRow(ctx, query, id, userID, statuses, domain.TaskStatusPending, domain.TaskStatusInProgress,
+		TenantOf(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		r.logger.Error("Failed to assign task: %v", err)
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to assign task: %w", err)
+	}
+
+	return task, nil
+}
+
+// CompleteTx completes a task inside tx in a single conditional UPDATE,
+// following the rules of domain.Task.Complete: completed and cancelled tasks
+// stay as they are. A task with incomplete dependencies isn't completed, nor,
+// with requireSubtasks, one with open subtasks. It returns the completed task,
+// or nil if no completable task has the ID.
+func (r *TaskRepository) CompleteTx(ctx context.Context, tx pgx.Tx, id int64, requireSubtasks bool) (*domain.Task, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "complete_task_tx")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("task.id", id))
+
+	query := `
+		UPDATE tasks t
+		SET status = $2, updated_at = NOW()
+		WHERE t.id = $1 AND t.status NOT IN ($2, $3) AND ($5 = '*' OR t.tenant_id = $5)
+			AND NOT EXISTS (
+				SELECT 1 FROM task_dependencies d
+				JOIN tasks dep ON dep.id = d.depends_on_id
+				WHERE d.task_id = t.id AND dep.status <> $2 AND ($5 = '*' OR dep.tenant_id = $5)
+			)
+			AND (NOT $4::boolean OR NOT EXISTS (
+				SELECT 1 FROM tasks sub
+				WHERE sub.parent_id = t.id AND sub.status NOT IN ($2, $3) AND ($5 = '*' OR sub.tenant_id = $5)
+			))
+		RETURNING ` + taskColumns + `
 	`
+
+	task, err := scanTask(tx.QueryRow(ctx, query, id, domain.TaskStatusCompleted, domain.TaskStatusCancelled,
+		requireSubtasks, TenantOf(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		r.logger.Error("Failed to complete task: %v", err)
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to complete task: %w", err)
+	}
+
+	return task, nil
+}
+
+// MaxGetByIDs caps how many tasks one GetByIDs call may fetch
+const MaxGetByIDs = 1000
+
+// GetByIDs retrieves many tasks in one query, keyed by ID. Duplicate IDs are
+// fetched once; IDs with no task are simply absent from the result.
+func (r *TaskRepository) GetByIDs(ctx context.Context, ids []int64) (map[int64]*domain.Task, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "get_tasks_by_ids")
+	defer span.End()
+
+	ids = UniqueIDs(ids)
+	span.SetAttributes(attribute.Int("tasks.count", len(ids)))
+
+	if len(ids) > MaxGetByIDs {
+		return nil, domain.ErrBatchTooLarge
+	}
+	tasks := make(map[int64]*domain.Task, len(ids))
+	if len(ids) == 0 {
+		return tasks, nil
+	}
+
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE id = ANY($1) AND ($2 = '*' OR tenant_id = $2)`
+
+	rows, err := r.db.Query(ctx, query, ids, TenantOf(ctx))
+	if err != nil {
+		r.logger.Error("Failed to get tasks by IDs: %v", err)
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to get tasks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			tracing.RecordError(ctx, err)
+			return nil, fmt.Errorf("failed to scan task: %w", err)
+		}
+		tasks[task.ID] = task
+	}
+	if err := rows.Err(); err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to get tasks: %w", err)
+	}
+
+	return tasks, nil
+}
+
+// UniqueIDs returns ids without duplicates, keeping the first occurrence of each
+func UniqueIDs(ids []int64) []int64 {
+	seen := make(map[int64]bool, len(ids))
+	out := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// GetIDByUUID returns the ID of the task with the given UUID
+func (r *TaskRepository) GetIDByUUID(ctx context.Context, id uuid.UUID) (int64, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "get_task_id_by_uuid")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("task.uuid", id.String()))
+
+	query := `SELECT id FROM tasks WHERE uuid = $1 AND ($2 = '*' OR tenant_id = $2)`
+
+	var taskID int64
+	if err := r.db.QueryRow(ctx, query, id, TenantOf(ctx)).Scan(&taskID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, domain.ErrTaskNotFound
+		}
+		r.logger.Error("Failed to get task by UUID: %v", err)
+		tracing.RecordError(ctx, err)
+		return 0, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	return taskID, nil
+}
+
+// GetByIDForUpdate retrieves a task by ID and locks its row until tx ends,
+// so read-modify-write flows can't interleave
+func (r *TaskRepository) GetByIDForUpdate(ctx context.Context, tx pgx.Tx, id int64) (*domain.Task, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "get_task_by_id_for_update")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("task.id", id))
+
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE id = $1 AND ($2 = '*' OR tenant_id = $2) FOR UPDATE`
+
+	task, err := scanTask(tx.QueryRow(ctx, query, id, TenantOf(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrTaskNotFound
+		}
+		r.logger.Error("Failed to lock task: %v", err)
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	return task, nil
+}
+
+// GetAll retrieves all tasks with optional filters. It collects what
+// IterateAll reads; prefer IterateAll for lists that needn't be held at once.
+func (r *TaskRepository) GetAll(ctx context.Context, filter TaskFilter) ([]*domain.Task, error) {
+	tasks := make([]*domain.Task, 0)
+	err := r.IterateAll(ctx, filter, func(task *domain.Task) error {
+		tasks = append(tasks, task)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// IterateAll calls fn with each task matching filter, newest first, as it is
+// read from the database, so the list is never held in memory as a whole. It
+// stops at the first error from fn, or once ctx is done, and returns it.
+func (r *TaskRepository) IterateAll(ctx context.Context, filter TaskFilter, fn func(*domain.Task) error) error {
+	ctx, span := tracing.StartSpan(ctx, "repository", "iterate_all_tasks")
+	defer span.End()
+
+	query, args := listQuery(filter)
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		r.logger.Error("Failed to get all tasks: %v", err)
+		tracing.RecordError(ctx, err)
+		return fmt.Errorf("failed to get tasks: %w", err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		task, err := scanTask(rows)
+		if err != nil {
+			r.logger.Error("Failed to scan task: %v", err)
+			tracing.RecordError(ctx, err)
+			return fmt.Errorf("failed to scan task: %w", err)
+		}
+		if err := fn(task); err != nil {
+			return err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		tracing.RecordError(ctx, err)
+		return fmt.Errorf("failed to get tasks: %w", err)
+	}
+
+	span.SetAttributes(attribute.Int("tasks.count", count))
+	return nil
+}
+
+// listQuery builds the query listing the tasks matching filter, newest first,
+// with its limit and offset
+func listQuery(filter TaskFilter) (string, []any) {
+	where, args := filterConditions(filter)
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE ` + where
+	argCount := len(args) + 1
+
+	query += " ORDER BY created_at DESC"
+
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", argCount)
+		args = append(args, filter.Limit)
+		argCount++
+	}
+
+	if filter.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", argCount)
+		args = append(args, filter.Offset)
+	}
+	return query, args
+}
+
+// filterConditions returns the WHERE conditions matching every filter that is
+// set, and their arguments. Limit and offset are left to the caller.
+func filterConditions(filter TaskFilter) (string, []any) {
+	where := "1=1"
 	args := make([]any, 0)
 	argCount := 1
 
+	if filter.TenantID != AllTenants {
+		where += fmt.Sprintf(" AND tenant_id = $%d", argCount)
+		args = append(args, filter.TenantID)
+		argCount++
+	}
+
 	if filter.Status != nil {
-		query += fmt.Sprintf(" AND status = $%d", argCount)
+		where += fmt.Sprintf(" AND status = $%d", argCount)
 		args = append(args, *filter.Status)
 		argCount++
 	}
 
 	if filter.Priority != nil {
-		query += fmt.Sprintf(" AND priority = $%d", argCount)
+		where += fmt.Sprintf(" AND priority = $%d", argCount)
 		args = append(args, *filter.Priority)
 		argCount++
 	}
 
 	if filter.AssignedTo != nil {
-		query += fmt.Sprintf(" AND assigned_to = $%d", argCount)
+		where += fmt.Sprintf(" AND assigned_to = $%d", argCount)
 		args = append(args, *filter.AssignedTo)
 		argCount++
 	}
 
-	query += " ORDER BY created_at DESC"
+	if filter.ParentID != nil {
+		where += fmt.Sprintf(" AND parent_id = $%d", argCount)
+		args = append(args, *filter.ParentID)
+		argCount++
+	}
 
-	if filter.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT $%d", argCount)
-		args = append(args, filter.Limit)
+	if filter.CreatedBy != nil {
+		where += fmt.Sprintf(" AND created_by = $%d", argCount)
+		args = append(args, *filter.CreatedBy)
 		argCount++
 	}
 
-	if filter.Offset > 0 {
-		query += fmt.Sprintf(" OFFSET $%d", argCount)
-		args = append(args, filter.Offset)
+	if filter.CreatedAfter != nil {
+		where += fmt.Sprintf(" AND created_at >= $%d", argCount)
+		args = append(args, *filter.CreatedAfter)
+		argCount++
 	}
 
-	rows, err := r.db.Query(ctx, query, args...)
-	if err != nil {
-		r.logger.Error("Failed to get all tasks: %v", err)
-		tracing.RecordError(ctx, err)
-		return nil, fmt.Errorf("failed to get tasks: %w", err)
+	if filter.CreatedBefore != nil {
+		where += fmt.Sprintf(" AND created_at <= $%d", argCount)
+		args = append(args, *filter.CreatedBefore)
+		argCount++
 	}
-	defer rows.Close()
 
-	tasks := make([]*domain.Task, 0)
-	for rows.Next() {
-		task := &domain.Task{}
-		err := rows.Scan(
-			&task.ID,
-			&task.Name,
-			&task.Description,
-			&task.Status,
-			&task.Priority,
-			&task.AssignedTo,
-			&task.CreatedBy,
-			&task.CreatedAt,
-			&task.UpdatedAt,
-		)
-		if err != nil {
-			r.logger.Error("Failed to scan task: %v", err)
-			continue
+	if filter.DueBefore != nil {
+		where += fmt.Sprintf(" AND due_date < $%d", argCount)
+		args = append(args, *filter.DueBefore)
+		argCount++
+	}
+
+	if len(filter.ExcludeStatuses) > 0 {
+		excluded := make([]string, len(filter.ExcludeStatuses))
+		for i, status := range filter.ExcludeStatuses {
+			excluded[i] = string(status)
 		}
-		tasks = append(tasks, task)
+		where += fmt.Sprintf(" AND status <> ALL($%d)", argCount)
+		args = append(args, excluded)
+		argCount++
 	}
 
-	span.SetAttributes(attribute.Int("tasks.count", len(tasks)))
-	return tasks, nil
+	return where, args
+}
+
+// ListVersion identifies the state of a filtered task list. It changes whenever a
+// task joins, leaves or changes within the list.
+type ListVersion struct {
+	Count int64
+	// LastModified is the latest updated_at of the tasks; zero for an empty list
+	LastModified time.Time
+}
+
+// GetListVersion returns the version of the tasks matching filter, ignoring its
+// limit and offset
+func (r *TaskRepository) GetListVersion(ctx context.Context, filter TaskFilter) (ListVersion, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "get_task_list_version")
+	defer span.End()
+
+	where, args := filterConditions(filter)
+	query := `SELECT COUNT(*), MAX(updated_at) FROM tasks WHERE ` + where
+
+	var version ListVersion
+	var lastModified *time.Time
+	if err := r.db.QueryRow(ctx, query, args...).Scan(&version.Count, &lastModified); err != nil {
+		r.logger.Error("Failed to get task list version: %v", err)
+		tracing.RecordError(ctx, err)
+		return ListVersion{}, fmt.Errorf("failed to get task list version: %w", err)
+	}
+	if lastModified != nil {
+		version.LastModified = *lastModified
+	}
+
+	return version, nil
 }
 
 // Update updates an existing task
@@ -197,32 +609,74 @@ func (r *TaskRepository) Update(ctx context.Context, task *domain.Task) error {
 
 	span.SetAttributes(attribute.Int64("task.id", task.ID))
 
+	return r.update(ctx, r.db, task, nil)
+}
+
+// UpdateTx updates an existing task inside the given transaction
+func (r *TaskRepository) UpdateTx(ctx context.Context, tx pgx.Tx, task *domain.Task) error {
+	ctx, span := tracing.StartSpan(ctx, "repository", "update_task_tx")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("task.id", task.ID))
+
+	return r.update(ctx, tx, task, nil)
+}
+
+// UpdateTxIfUnchanged updates a task inside tx only if its updated_at still
+// equals updatedAt, and returns domain.ErrTaskModified otherwise
+func (r *TaskRepository) UpdateTxIfUnchanged(ctx context.Context, tx pgx.Tx, task *domain.Task, updatedAt time.Time) error {
+	ctx, span := tracing.StartSpan(ctx, "repository", "update_task_if_unchanged")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("task.id", task.ID))
+
+	return r.update(ctx, tx, task, &updatedAt)
+}
+
+// update writes task. With expected set, the row is only written while its
+// updated_at still equals it.
+func (r *TaskRepository) update(ctx context.Context, q queryRower, task *domain.Task, expected *time.Time) error {
+	// A new due date gets a due soon reminder of its own
 	query := `
 		UPDATE tasks
-		SET name = $1, description = $2, status = $3, priority = $4, assigned_to = $5, updated_at = $6
-		WHERE id = $7
+		SET name = $1, description = $2, status = $3, priority = $4, assigned_to = $5, due_date = $6,
+			recurrence_rule = $7, updated_at = NOW(),
+			due_notified_at = CASE WHEN due_date IS DISTINCT FROM $6 THEN NULL ELSE due_notified_at END
+		WHERE id = $8 AND ($9 = '*' OR tenant_id = $9) AND ($10::timestamptz IS NULL OR updated_at = $10)
+		RETURNING updated_at
 	`
 
-	result, err := r.db.Pool().Exec(ctx, query,
+	// updated_at is set by the database; read it back so callers see the
+	// stored value (used for ETags)
+	err := q.QueryRow(ctx, query,
 		task.Name,
 		task.Description,
 		task.Status,
 		task.Priority,
 		task.AssignedTo,
-		time.Now(),
+		task.DueDate,
+		task.RecurrenceRule,
 		task.ID,
-	)
+		TenantOf(ctx),
+		expected,
+	).Scan(&task.UpdatedAt)
 
 	if err != nil {
-		r.logger.Error("Failed to update task: %v", err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			if expected != nil {
+				return domain.ErrTaskModified
+			}
+			return domain.ErrTaskNotFound
+		}
 		tracing.RecordError(ctx, err)
+		if mapped := constraintError(err); mapped != nil {
+			r.logger.Warn("Task update rejected by constraint: %v", err)
+			return mapped
+		}
+		r.logger.Error("Failed to update task: %v", err)
 		return fmt.Errorf("failed to update task: %w", err)
 	}
 
-	if result.RowsAffected() == 0 {
-		return domain.ErrTaskNotFound
-	}
-
 	return nil
 }
 
@@ -233,9 +687,23 @@ func (r *TaskRepository) Delete(ctx context.Context, id int64) error {
 
 	span.SetAttributes(attribute.Int64("task.id", id))
 
-	query := `DELETE FROM tasks WHERE id = $1`
+	return r.delete(ctx, r.db, id)
+}
+
+// DeleteTx deletes a task inside the given transaction
+func (r *TaskRepository) DeleteTx(ctx context.Context, tx pgx.Tx, id int64) error {
+	ctx, span := tracing.StartSpan(ctx, "repository", "delete_task_tx")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("task.id", id))
+
+	return r.delete(ctx, tx, id)
+}
+
+func (r *TaskRepository) delete(ctx context.Context, q querier, id int64) error {
+	query := `DELETE FROM tasks WHERE id = $1 AND ($2 = '*' OR tenant_id = $2)`
 
-	result, err := r.db.Pool().Exec(ctx, query, id)
+	result, err := q.Exec(ctx, query, id, TenantOf(ctx))
 	if err != nil {
 		r.logger.Error("Failed to delete task: %v", err)
 		tracing.RecordError(ctx, err)
@@ -248,3 +716,214 @@ func (r *TaskRepository) Delete(ctx context.Context, id int64) error {
 
 	return nil
 }
+
+// GetRecurringWithoutNext returns completed recurring tasks whose next occurrence
+// has not been generated yet
+func (r *TaskRepository) GetRecurringWithoutNext(ctx context.Context, limit int) ([]*domain.Task, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "get_recurring_without_next")
+	defer span.End()
+
+	query := `
+		SELECT ` + taskColumns + `
+		FROM tasks t
+		WHERE t.recurrence_rule IS NOT NULL
+			AND t.status = $1
+			AND NOT EXISTS (SELECT 1 FROM tasks n WHERE n.parent_task_id = t.id)
+			AND ($3 = '*' OR t.tenant_id = $3)
+		ORDER BY t.updated_at
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, domain.TaskStatusCompleted, limit, TenantOf(ctx))
+	if err != nil {
+		r.logger.Error("Failed to get recurring tasks: %v", err)
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to get recurring tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := make([]*domain.Task, 0)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			r.logger.Error("Failed to scan task: %v", err)
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+
+	span.SetAttributes(attribute.Int("tasks.count", len(tasks)))
+	return tasks, nil
+}
+
+// GetDueWithin returns open tasks due between now and now+d whose due soon
+// reminder hasn't gone out yet, soonest first
+func (r *TaskRepository) GetDueWithin(ctx context.Context, d time.Duration) ([]*domain.Task, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "get_tasks_due_within")
+	defer span.End()
+
+	// The window starts at the database clock, like created_at and updated_at
+	query := `
+		SELECT ` + taskColumns + `
+		FROM tasks
+		WHERE due_date IS NOT NULL AND due_date >= NOW() AND due_date <= NOW() + make_interval(secs => $1)
+			AND due_notified_at IS NULL
+			AND status NOT IN ($2, $3)
+			AND ($4 = '*' OR tenant_id = $4)
+		ORDER BY due_date
+	`
+
+	rows, err := r.db.Query(ctx, query, d.Seconds(), domain.TaskStatusCompleted, domain.TaskStatusCancelled, TenantOf(ctx))
+	if err != nil {
+		r.logger.Error("Failed to get tasks due soon: %v", err)
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to get tasks due soon: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := make([]*domain.Task, 0)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			r.logger.Error("Failed to scan task: %v", err)
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+
+	span.SetAttributes(attribute.Int("tasks.count", len(tasks)))
+	return tasks, nil
+}
+
+// MarkDueNotified records that the due soon reminder of a task went out. It
+// returns false if it already had, so concurrent schedulers remind only once.
+func (r *TaskRepository) MarkDueNotified(ctx context.Context, id int64, at time.Time) (bool, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "mark_task_due_notified")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("task.id", id))
+
+	return r.markDueNotified(ctx, r.db, id, at)
+}
+
+// MarkDueNotifiedTx is MarkDueNotified inside the given transaction
+func (r *TaskRepository) MarkDueNotifiedTx(ctx context.Context, tx pgx.Tx, id int64, at time.Time) (bool, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "mark_task_due_notified_tx")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("task.id", id))
+
+	return r.markDueNotified(ctx, tx, id, at)
+}
+
+func (r *TaskRepository) markDueNotified(ctx context.Context, q querier, id int64, at time.Time) (bool, error) {
+	query := `
+		UPDATE tasks SET due_notified_at = $2
+		WHERE id = $1 AND due_notified_at IS NULL AND ($3 = '*' OR tenant_id = $3)
+	`
+
+	result, err := q.Exec(ctx, query, id, at, TenantOf(ctx))
+	if err != nil {
+		r.logger.Error("Failed to mark task due notified: %v", err)
+		tracing.RecordError(ctx, err)
+		return false, fmt.Errorf("failed to mark task due notified: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// CountByStatus returns the number of tasks per status.
+// This is the query that feeds the tasks_by_status gauge.
+func (r *TaskRepository) CountByStatus(ctx context.Context) (map[domain.TaskStatus]int64, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "count_tasks_by_status")
+	defer span.End()
+
+	query := `SELECT status, COUNT(*) FROM tasks WHERE $1 = '*' OR tenant_id = $1 GROUP BY status`
+
+	rows, err := r.db.Query(ctx, query, TenantOf(ctx))
+	if err != nil {
+		r.logger.Error("Failed to count tasks by status: %v", err)
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to count tasks by status: %w", err)
+	}
+
+	counts, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (statusCount, error) {
+		var c statusCount
+		err := row.Scan(&c.status, &c.count)
+		return c, err
+	})
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to scan task counts: %w", err)
+	}
+
+	result := make(map[domain.TaskStatus]int64, len(counts))
+	for _, c := range counts {
+		result[c.status] = c.count
+	}
+	return result, nil
+}
+
+// CountByPriority returns the number of tasks per priority
+func (r *TaskRepository) CountByPriority(ctx context.Context) (map[domain.Priority]int64, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "count_tasks_by_priority")
+	defer span.End()
+
+	query := `SELECT priority, COUNT(*) FROM tasks WHERE $1 = '*' OR tenant_id = $1 GROUP BY priority`
+
+	rows, err := r.db.Query(ctx, query, TenantOf(ctx))
+	if err != nil {
+		r.logger.Error("Failed to count tasks by priority: %v", err)
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to count tasks by priority: %w", err)
+	}
+
+	counts, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (priorityCount, error) {
+		var c priorityCount
+		err := row.Scan(&c.priority, &c.count)
+		return c, err
+	})
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to scan task counts: %w", err)
+	}
+
+	result := make(map[domain.Priority]int64, len(counts))
+	for _, c := range counts {
+		result[c.priority] = c.count
+	}
+	return result, nil
+}
+
+// CountOverdue returns the number of open tasks whose due date is before now
+func (r *TaskRepository) CountOverdue(ctx context.Context, now time.Time) (int64, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "count_overdue_tasks")
+	defer span.End()
+
+	query := `
+		SELECT COUNT(*)
+		FROM tasks
+		WHERE due_date IS NOT NULL AND due_date < $1 AND status NOT IN ($2, $3)
+			AND ($4 = '*' OR tenant_id = $4)
+	`
+
+	var count int64
+	err := r.db.QueryRow(ctx, query, now, domain.TaskStatusCompleted, domain.TaskStatusCancelled, TenantOf(ctx)).Scan(&count)
+	if err != nil {
+		r.logger.Error("Failed to count overdue tasks: %v", err)
+		tracing.RecordError(ctx, err)
+		return 0, fmt.Errorf("failed to count overdue tasks: %w", err)
+	}
+
+	return count, nil
+}
+
+type statusCount struct {
+	status domain.TaskStatus
+	count  int64
+}
+
+type priorityCount struct {
+	priority domain.Priority
+	count    int64
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	"github.com/seldomhappy/vibe_architecture/internal/infrastructure/postgres"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/buildinfo"
+	pkgcontext "github.com/seldomhappy/vibe_architecture/internal/pkg/context"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/metrics"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/tracing"
+	"github.com/seldomhappy/vibe_architecture/logger"
+)
+
+// testDSNEnv names the variable holding the DSN of a PostgreSQL database the
+// tests may write to. Tests that need a database are skipped without it.
+const testDSNEnv = "TEST_DATABASE_DSN"
+
+const testTenant = "repository-test"
+
+// openTestDB migrates the test database and opens it with the given query exec mode
+func openTestDB(tb testing.TB, execMode string) (*postgres.DB, logger.ILogger) {
+	tb.Helper()
+
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		tb.Skipf("%s is not set", testDSNEnv)
+	}
+
+	log := logger.New("test", logger.WithOutput(io.Discard))
+	if err := postgres.RunMigrations(dsn, log); err != nil {
+		tb.Fatalf("migrate: %v", err)
+	}
+
+	db, err := postgres.New(postgres.Config{
+		DSN:           dsn,
+		MaxOpenConns:  4,
+		QueryExecMode: execMode,
+	}, log, metrics.New(buildinfo.Info{}, 0, false), tracing.GetTracer("postgres"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := db.Start(context.Background()); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() {
+		_ = db.Shutdown(context.Background())
+	})
+	return db, log
+}
+
+// createTestTask stores a task of the test tenant and deletes it when the test ends
+func createTestTask(tb testing.TB, repo *TaskRepository) (context.Context, *domain.Task) {
+	tb.Helper()
+
+	ctx := pkgcontext.WithTenantID(context.Background(), testTenant)
+	task := &domain.Task{
+		TenantID:  testTenant,
+		Name:      "repository test",
+		Status:    domain.TaskStatusPending,
+		Priority:  domain.PriorityMedium,
+		CreatedBy: 1,
+	}
+	if err := repo.Create(ctx, task); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() {
+		_ = repo.Delete(ctx, task.ID)
+	})
+	return ctx, task
+}
+
+// BenchmarkGetByID compares parsing GetByID on every call with preparing it
+// once per connection
+func BenchmarkGetByID(b *testing.B) {
+	for _, mode := range []string{"exec", "cache_statement"} {
+		b.Run(mode, func(b *testing.B) {
+			db, log := openTestDB(b, mode)
+			repo := NewTaskRepository(db, log)
+			ctx, task := createTestTask(b, repo)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := repo.GetByID(ctx, task.ID); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestQueryCancelledWithContext blocks a query on a row lock and cancels its
+// context, as a client disconnect does
+func TestQueryCancelledWithContext(t *testing.T) {
+	db, log := openTestDB(t, "")
+	repo := NewTaskRepository(db, log)
+	ctx, task := createTestTask(t, repo)
+
+	holder, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer holder.Rollback(context.Background())
+	if _, err := repo.GetByIDForUpdate(ctx, holder, task.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback(context.Background())
+
+	queryCtx, cancel := context.WithCancel(ctx)
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err = repo.GetByIDForUpdate(queryCtx, tx, task.ID)
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("GetByIDForUpdate error = %v, want context.Canceled", err)
+	}
+	if elapsed > 2*time.Second {
+		t.Errorf("GetByIDForUpdate returned %v after the cancel, want it to abort the query", elapsed)
+	}
+}
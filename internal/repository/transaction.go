@@ -23,8 +23,9 @@ func NewTxManager(db *postgres.DB, log logger.ILogger) *TxManager {
 	}
 }
 
-// WithTransaction executes a function within a transaction
-func (tm *TxManager) WithTransaction(ctx context.Context, fn func(ctx context.Context, tx pgx.Tx) error) error {
+// WithTransaction executes a function within a transaction. The transaction is
+// committed if fn returns nil and rolled back otherwise; fn's error is returned as is.
+func (tm *TxManager) WithTransaction(ctx context.Context, fn func(ctx context.Context, tx pgx.Tx) error) (err error) {
 	tx, err := tm.db.BeginTx(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	"github.com/seldomhappy/vibe_architecture/internal/infrastructure/postgres"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/tracing"
+	"github.com/seldomhappy/vibe_architecture/logger"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// WebhookRepository implements webhook data access
+type WebhookRepository struct {
+	db     *postgres.DB
+	logger logger.ILogger
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(db *postgres.DB, log logger.ILogger) *WebhookRepository {
+	return &WebhookRepository{
+		db:     db,
+		logger: log,
+	}
+}
+
+// webhookColumns is the column list scanned by scanWebhook
+const webhookColumns = `id, tenant_id, url, event_types, secret, created_at`
+
+func scanWebhook(row pgx.Row) (*domain.Webhook, error) {
+	webhook := &domain.Webhook{}
+	var eventTypes []string
+	if err := row.Scan(&webhook.ID, &webhook.TenantID, &webhook.URL, &eventTypes, &webhook.Secret, &webhook.CreatedAt); err != nil {
+		return nil, err
+	}
+	webhook.EventTypes = make([]domain.EventType, len(eventTypes))
+	for i, t := range eventTypes {
+		webhook.EventTypes[i] = domain.EventType(t)
+	}
+	return webhook, nil
+}
+
+// Create stores a new webhook
+func (r *WebhookRepository) Create(ctx context.Context, webhook *domain.Webhook) error {
+	ctx, span := tracing.StartSpan(ctx, "repository", "create_webhook")
+	defer span.End()
+
+	eventTypes := make([]string, len(webhook.EventTypes))
+	for i, t := range webhook.EventTypes {
+		eventTypes[i] = string(t)
+	}
+
+	query := `
+		INSERT INTO webhooks (tenant_id, url, event_types, secret)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query, webhook.TenantID, webhook.URL, eventTypes, webhook.Secret).
+		Scan(&webhook.ID, &webhook.CreatedAt)
+	if err != nil {
+		r.logger.Error("Failed to create webhook: %v", err)
+		tracing.RecordError(ctx, err)
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	r.logger.Debug("Webhook created with ID: %d", webhook.ID)
+	return nil
+}
+
+// GetByID retrieves a webhook by ID
+func (r *WebhookRepository) GetByID(ctx context.Context, id int64) (*domain.Webhook, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "get_webhook_by_id")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("webhook.id", id))
+
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = $1 AND ($2 = '*' OR tenant_id = $2)`
+
+	webhook, err := scanWebhook(r.db.QueryRow(ctx, query, id, TenantOf(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrWebhookNotFound
+		}
+		r.logger.Error("Failed to get webhook by ID: %v", err)
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+// GetAll returns every webhook of the tenant, oldest first
+func (r *WebhookRepository) GetAll(ctx context.Context) ([]*domain.Webhook, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "get_all_webhooks")
+	defer span.End()
+
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE ($1 = '*' OR tenant_id = $1) ORDER BY id`
+
+	rows, err := r.db.Query(ctx, query, TenantOf(ctx))
+	if err != nil {
+		r.logger.Error("Failed to get webhooks: %v", err)
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to get webhooks: %w", err)
+	}
+
+	webhooks, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*domain.Webhook, error) {
+		return scanWebhook(row)
+	})
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to scan webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// Delete deletes a webhook along with its dead letters
+func (r *WebhookRepository) Delete(ctx context.Context, id int64) error {
+	ctx, span := tracing.StartSpan(ctx, "repository", "delete_webhook")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("webhook.id", id))
+
+	query := `DELETE FROM webhooks WHERE id = $1 AND ($2 = '*' OR tenant_id = $2)`
+
+	result, err := r.db.Exec(ctx, query, id, TenantOf(ctx))
+	if err != nil {
+		r.logger.Error("Failed to delete webhook: %v", err)
+		tracing.RecordError(ctx, err)
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// CreateDeadLetter stores an event that could not be delivered, for the
+// tenant of its webhook. It fails with domain.ErrWebhookNotFound if the
+// webhook was deleted meanwhile.
+func (r *WebhookRepository) CreateDeadLetter(ctx context.Context, letter *domain.WebhookDeadLetter) error {
+	ctx, span := tracing.StartSpan(ctx, "repository", "create_webhook_dead_letter")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("webhook.id", letter.WebhookID))
+
+	query := `
+		INSERT INTO webhook_dead_letters (webhook_id, tenant_id, event_type, task_id, payload, attempts, last_error)
+		SELECT id, tenant_id, $2, $3, $4, $5, $6 FROM webhooks WHERE id = $1
+		RETURNING id, tenant_id, failed_at
+	`
+
+	err := r.db.QueryRow(ctx, query, letter.WebhookID, letter.EventType, letter.TaskID,
+		letter.Payload, letter.Attempts, letter.LastError).
+		Scan(&letter.ID, &letter.TenantID, &letter.FailedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrWebhookNotFound
+		}
+		r.logger.Error("Failed to create webhook dead letter: %v", err)
+		tracing.RecordError(ctx, err)
+		return fmt.Errorf("failed to create webhook dead letter: %w", err)
+	}
+
+	return nil
+}
+
+// GetDeadLetters returns up to limit dead letters of a webhook, newest first
+func (r *WebhookRepository) GetDeadLetters(ctx context.Context, webhookID int64, limit int) ([]*domain.WebhookDeadLetter, error) {
+	ctx, span := tracing.StartSpan(ctx, "repository", "get_webhook_dead_letters")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("webhook.id", webhookID))
+
+	query := `
+		SELECT id, webhook_id, tenant_id, event_type, task_id, payload, attempts, last_error, failed_at
+		FROM webhook_dead_letters
+		WHERE webhook_id = $1 AND ($3 = '*' OR tenant_id = $3)
+		ORDER BY failed_at DESC, id DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, webhookID, limit, TenantOf(ctx))
+	if err != nil {
+		r.logger.Error("Failed to get webhook dead letters: %v", err)
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to get webhook dead letters: %w", err)
+	}
+
+	letters, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*domain.WebhookDeadLetter, error) {
+		letter := &domain.WebhookDeadLetter{}
+		err := row.Scan(&letter.ID, &letter.WebhookID, &letter.TenantID, &letter.EventType, &letter.TaskID,
+			&letter.Payload, &letter.Attempts, &letter.LastError, &letter.FailedAt)
+		return letter, err
+	})
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to scan webhook dead letters: %w", err)
+	}
+
+	return letters, nil
+}
@@ -0,0 +1,179 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	pkgcontext "github.com/seldomhappy/vibe_architecture/internal/pkg/context"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// BulkUpdateStatus moves many tasks to one status inside a single transaction.
+// Each task is checked on its own: tasks that can't make the transition or don't
+// exist are reported and skipped, the rest are updated. Tasks already in the
+// target status are reported as unchanged, so retrying a batch is safe.
+func (uc *TaskUseCase) BulkUpdateStatus(ctx context.Context, ids []int64, status domain.TaskStatus) ([]BulkStatusResult, error) {
+	ctx, span := tracing.StartSpan(ctx, "usecase", "bulk_update_status")
+	defer tracing.EndSpan(span)
+
+	requestID := pkgcontext.GetRequestID(ctx)
+	traceID := pkgcontext.GetTraceID(ctx)
+
+	span.SetAttributes(
+		attribute.Int("tasks.count", len(ids)),
+		attribute.String("task.status", string(status)),
+	)
+
+	if !status.IsValid() {
+		tracing.AddEvent(ctx, "validation_failed", attribute.String("error", "invalid status"))
+		tracing.RecordError(ctx, domain.ErrInvalidInput)
+		return nil, domain.ErrInvalidInput
+	}
+
+	// Duplicates collapse into one result; sorting fixes the lock order so
+	// concurrent batches can't deadlock on each other
+	ids = uniqueSorted(ids)
+	if len(ids) == 0 {
+		tracing.AddEvent(ctx, "validation_failed", attribute.String("error", "no task ids"))
+		tracing.RecordError(ctx, domain.ErrInvalidInput)
+		return nil, domain.ErrInvalidInput
+	}
+	if uc.cfg.BulkMaxIDs > 0 && len(ids) > uc.cfg.BulkMaxIDs {
+		uc.logger.Warn("[%s][trace:%s] Bulk status update of %d tasks exceeds limit %d", requestID, traceID, len(ids), uc.cfg.BulkMaxIDs)
+		tracing.RecordError(ctx, domain.ErrBatchTooLarge)
+		return nil, domain.ErrBatchTooLarge
+	}
+
+	uc.logger.Info("[%s][trace:%s] Bulk updating %d tasks to status %s", requestID, traceID, len(ids), status)
+
+	results := make([]BulkStatusResult, 0, len(ids))
+	var updated []*domain.Task
+	err := uc.commit(ctx, func(ctx context.Context, tx pgx.Tx) ([]domain.TaskEvent, error) {
+		for _, id := range ids {
+			task, err := uc.repo.GetByIDForUpdate(ctx, tx, id)
+			if errors.Is(err, domain.ErrTaskNotFound) {
+				results = append(results, BulkStatusResult{ID: id, Result: BulkResultNotFound})
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			if task.Status == status {
+				results = append(results, BulkStatusResult{ID: id, Result: BulkResultUnchanged})
+				continue
+			}
+
+			if reason, err := uc.checkTransition(ctx, tx, task, status); err != nil {
+				return nil, err
+			} else if reason != nil {
+				results = append(results, BulkStatusResult{ID: id, Result: BulkResultInvalidTransition, Reason: reason.Error()})
+				continue
+			}
+
+			if err := uc.repo.UpdateTx(ctx, tx, task); err != nil {
+				return nil, fmt.Errorf("failed to save task %d: %w", id, err)
+			}
+			results = append(results, BulkStatusResult{ID: id, Result: BulkResultUpdated})
+			updated = append(updated, task)
+		}
+		return uc.statusChangedEvents(updated), nil
+	})
+	if err != nil {
+		uc.logger.Error("[%s][trace:%s] Bulk status update failed: %v", requestID, traceID, err)
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+	uc.recordStatusChanged(updated)
+
+	uc.logger.Info("[%s][trace:%s] Bulk status update done: %d of %d tasks updated", requestID, traceID, len(updated), len(ids))
+
+	return results, nil
+}
+
+// checkTransition applies the status change to task, which tx has locked. The
+// dependencies and subtasks are counted on tx, so the check holds until it
+// commits. The returned reason is set when the task can't make the transition;
+// err is set when the check itself failed.
+func (uc *TaskUseCase) checkTransition(ctx context.Context, tx pgx.Tx, task *domain.Task, status domain.TaskStatus) (reason, err error) {
+	if status == domain.TaskStatusCompleted {
+		incomplete, err := uc.repo.CountIncompleteDependenciesTx(ctx, tx, task.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check dependencies: %w", err)
+		}
+		if incomplete > 0 {
+			return domain.ErrDependenciesIncomplete, nil
+		}
+
+		if uc.cfg.RequireSubtasksCompleted {
+			openSubtasks, err := uc.repo.CountIncompleteSubtasksTx(ctx, tx, task.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check subtasks: %w", err)
+			}
+			if openSubtasks > 0 {
+				return domain.ErrSubtasksIncomplete, nil
+			}
+		}
+	}
+
+	if err := task.TransitionTo(status, uc.clock.Now()); err != nil {
+		return err, nil
+	}
+	return nil, nil
+}
+
+// statusChangedEvents returns the event matching the new status of each task
+func (uc *TaskUseCase) statusChangedEvents(tasks []*domain.Task) []domain.TaskEvent {
+	events := make([]domain.TaskEvent, 0, len(tasks))
+	for _, task := range tasks {
+		if task.Status == domain.TaskStatusCompleted {
+			event := domain.TaskCompletedEvent{
+				TaskID:      task.ID,
+				CompletedAt: task.UpdatedAt,
+			}
+			events = append(events, uc.newTaskEvent(domain.EventTypeTaskCompleted, task, event))
+			continue
+		}
+
+		event := domain.TaskUpdatedEvent{
+			TaskID:      task.ID,
+			Name:        task.Name,
+			Description: task.Description,
+			Status:      task.Status,
+			Priority:    task.Priority,
+			AssignedTo:  task.AssignedTo,
+			DueDate:     task.DueDate,
+			UpdatedAt:   task.UpdatedAt,
+		}
+		events = append(events, uc.newTaskEvent(domain.EventTypeTaskUpdated, task, event))
+	}
+	return events
+}
+
+// recordStatusChanged counts the completed tasks among the committed ones
+func (uc *TaskUseCase) recordStatusChanged(tasks []*domain.Task) {
+	for _, task := range tasks {
+		if task.Status == domain.TaskStatusCompleted {
+			uc.metrics.RecordTaskCompleted()
+		}
+	}
+}
+
+// uniqueSorted returns the positive ids in ascending order without duplicates
+func uniqueSorted(ids []int64) []int64 {
+	seen := make(map[int64]bool, len(ids))
+	out := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if id > 0 && !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
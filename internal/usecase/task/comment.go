@@ -0,0 +1,121 @@
+package task
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	pkgcontext "github.com/seldomhappy/vibe_architecture/internal/pkg/context"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// AddComment adds a comment to a task on behalf of the user in the context
+func (uc *TaskUseCase) AddComment(ctx context.Context, taskID int64, body string) (*domain.Comment, error) {
+	ctx, span := tracing.StartSpan(ctx, "usecase", "add_comment")
+	defer tracing.EndSpan(span)
+
+	requestID := pkgcontext.GetRequestID(ctx)
+	traceID := pkgcontext.GetTraceID(ctx)
+
+	span.SetAttributes(attribute.Int64("task.id", taskID))
+
+	author := pkgcontext.GetUserID(ctx)
+	if author <= 0 {
+		tracing.RecordError(ctx, domain.ErrUnauthorized)
+		return nil, domain.ErrUnauthorized
+	}
+
+	uc.logger.Info("[%s][trace:%s] Adding comment to task %d by user %d", requestID, traceID, taskID, author)
+
+	comment := &domain.Comment{
+		TaskID: taskID,
+		Author: author,
+		Body:   body,
+	}
+
+	if err := comment.Validate(); err != nil {
+		uc.logger.Warn("[%s][trace:%s] Comment validation failed: %v", requestID, traceID, err)
+		tracing.AddEvent(ctx, "validation_failed", attribute.String("error", err.Error()))
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+
+	err := uc.commit(ctx, func(ctx context.Context, tx pgx.Tx) ([]domain.TaskEvent, error) {
+		if err := uc.repo.CreateCommentTx(ctx, tx, comment); err != nil {
+			uc.logger.Error("[%s][trace:%s] Failed to add comment: %v", requestID, traceID, err)
+			return nil, err
+		}
+
+		event := domain.TaskCommentedEvent{
+			TaskID:    comment.TaskID,
+			CommentID: comment.ID,
+			Author:    comment.Author,
+			Body:      comment.Body,
+			CreatedAt: comment.CreatedAt,
+		}
+		return []domain.TaskEvent{{
+			Type:       domain.EventTypeTaskCommented,
+			TaskID:     comment.TaskID,
+			Payload:    event,
+			OccurredAt: comment.CreatedAt,
+		}}, nil
+	})
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+// ListComments returns the comments of a task, oldest first
+func (uc *TaskUseCase) ListComments(ctx context.Context, taskID int64) ([]*domain.Comment, error) {
+	ctx, span := tracing.StartSpan(ctx, "usecase", "list_comments")
+	defer tracing.EndSpan(span)
+
+	requestID := pkgcontext.GetRequestID(ctx)
+	traceID := pkgcontext.GetTraceID(ctx)
+
+	span.SetAttributes(attribute.Int64("task.id", taskID))
+
+	if err := uc.requireTask(ctx, taskID); err != nil {
+		uc.logger.Error("[%s][trace:%s] Failed to get task: %v", requestID, traceID, err)
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+
+	comments, err := uc.repo.GetComments(ctx, taskID)
+	if err != nil {
+		uc.logger.Error("[%s][trace:%s] Failed to list comments: %v", requestID, traceID, err)
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+// DeleteComment deletes a comment from a task
+func (uc *TaskUseCase) DeleteComment(ctx context.Context, taskID, commentID int64) error {
+	ctx, span := tracing.StartSpan(ctx, "usecase", "delete_comment")
+	defer tracing.EndSpan(span)
+
+	requestID := pkgcontext.GetRequestID(ctx)
+	traceID := pkgcontext.GetTraceID(ctx)
+
+	span.SetAttributes(
+		attribute.Int64("task.id", taskID),
+		attribute.Int64("comment.id", commentID),
+	)
+
+	uc.logger.Info("[%s][trace:%s] Deleting comment %d of task %d", requestID, traceID, commentID, taskID)
+
+	if err := uc.repo.DeleteComment(ctx, taskID, commentID); err != nil {
+		uc.logger.Error("[%s][trace:%s] Failed to delete comment: %v", requestID, traceID, err)
+		tracing.RecordError(ctx, err)
+		return err
+	}
+
+	return nil
+}
@@ -2,45 +2,136 @@ package task
 
 import (
 	"context"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/seldomhappy/vibe_architecture/internal/domain"
 	"github.com/seldomhappy/vibe_architecture/internal/repository"
 )
 
-// Repository defines the task repository interface
+// Repository defines the task repository interface. Create, Update and UpdateTx
+// set created_at and updated_at themselves, from the database clock, and
+// write them back into the task.
 type Repository interface {
 	Create(ctx context.Context, task *domain.Task) error
+	CreateTx(ctx context.Context, tx pgx.Tx, task *domain.Task) error
 	GetByID(ctx context.Context, id int64) (*domain.Task, error)
+	Exists(ctx context.Context, id int64) (bool, error)
+	GetByIDs(ctx context.Context, ids []int64) (map[int64]*domain.Task, error)
+	GetByIDForUpdate(ctx context.Context, tx pgx.Tx, id int64) (*domain.Task, error)
+	GetIDByUUID(ctx context.Context, id uuid.UUID) (int64, error)
 	GetAll(ctx context.Context, filter repository.TaskFilter) ([]*domain.Task, error)
+	IterateAll(ctx context.Context, filter repository.TaskFilter, fn func(*domain.Task) error) error
+	GetListVersion(ctx context.Context, filter repository.TaskFilter) (repository.ListVersion, error)
 	Update(ctx context.Context, task *domain.Task) error
+	UpdateTx(ctx context.Context, tx pgx.Tx, task *domain.Task) error
+	// UpdateTxIfUnchanged is UpdateTx as long as the stored updated_at still
+	// equals updatedAt; otherwise it returns domain.ErrTaskModified
+	UpdateTxIfUnchanged(ctx context.Context, tx pgx.Tx, task *domain.Task, updatedAt time.Time) error
+	// Assign assigns an assignable task in one step; nil means there was none
+	Assign(ctx context.Context, id, userID int64) (*domain.Task, error)
+	AssignTx(ctx context.Context, tx pgx.Tx, id, userID int64) (*domain.Task, error)
+	// CompleteTx completes a completable task in one step; nil means there was none
+	CompleteTx(ctx context.Context, tx pgx.Tx, id int64, requireSubtasks bool) (*domain.Task, error)
 	Delete(ctx context.Context, id int64) error
+	DeleteTx(ctx context.Context, tx pgx.Tx, id int64) error
+	CountByStatus(ctx context.Context) (map[domain.TaskStatus]int64, error)
+	CountByPriority(ctx context.Context) (map[domain.Priority]int64, error)
+	CountOverdue(ctx context.Context, now time.Time) (int64, error)
+	GetRecurringWithoutNext(ctx context.Context, limit int) ([]*domain.Task, error)
+	GetDueWithin(ctx context.Context, d time.Duration) ([]*domain.Task, error)
+	MarkDueNotified(ctx context.Context, id int64, at time.Time) (bool, error)
+	MarkDueNotifiedTx(ctx context.Context, tx pgx.Tx, id int64, at time.Time) (bool, error)
+	AddDependency(ctx context.Context, taskID, dependsOnID int64) error
+	RemoveDependency(ctx context.Context, taskID, dependsOnID int64) error
+	GetDependencies(ctx context.Context, taskID int64) ([]int64, error)
+	CountIncompleteDependenciesTx(ctx context.Context, tx pgx.Tx, taskID int64) (int, error)
+	CountSubtasks(ctx context.Context, parentID int64) (int, error)
+	CountIncompleteSubtasksTx(ctx context.Context, tx pgx.Tx, parentID int64) (int, error)
+	GetTreeForUpdate(ctx context.Context, tx pgx.Tx, id int64) ([]*domain.Task, error)
+	DeleteTree(ctx context.Context, id int64) ([]*domain.Task, error)
+	DeleteTreeTx(ctx context.Context, tx pgx.Tx, id int64) ([]*domain.Task, error)
+	CreateComment(ctx context.Context, comment *domain.Comment) error
+	CreateCommentTx(ctx context.Context, tx pgx.Tx, comment *domain.Comment) error
+	GetComments(ctx context.Context, taskID int64) ([]*domain.Comment, error)
+	DeleteComment(ctx context.Context, taskID, commentID int64) error
+}
+
+// TxManager runs a function inside a database transaction
+type TxManager interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context, tx pgx.Tx) error) error
+}
+
+// Outbox stores events in the transaction of the change they describe
+type Outbox interface {
+	AddTx(ctx context.Context, tx pgx.Tx, event domain.TaskEvent) error
+}
+
+// EventBus hands task events to every sink: Kafka, live subscribers and webhooks
+type EventBus interface {
+	Publish(ctx context.Context, event domain.TaskEvent)
+}
+
+// EventSink takes one event at a time and reports whether it has it
+type EventSink interface {
+	HandleEvent(ctx context.Context, event domain.TaskEvent) error
 }
 
 // UseCase defines the task use case interface
 type UseCase interface {
 	CreateTask(ctx context.Context, input CreateTaskInput) (*domain.Task, error)
+	ValidateTask(ctx context.Context, input CreateTaskInput) error
 	GetTask(ctx context.Context, id int64) (*domain.Task, error)
+	CheckTaskExists(ctx context.Context, id int64) error
+	ResolveTaskUUID(ctx context.Context, id uuid.UUID) (int64, error)
 	ListTasks(ctx context.Context, filter ListTasksFilter) ([]*domain.Task, error)
+	StreamTasks(ctx context.Context, filter ListTasksFilter, fn func(*domain.Task) error) error
+	GetListVersion(ctx context.Context, filter ListTasksFilter) (repository.ListVersion, error)
 	UpdateTask(ctx context.Context, id int64, input UpdateTaskInput) (*domain.Task, error)
-	DeleteTask(ctx context.Context, id int64) error
+	DeleteTask(ctx context.Context, id int64, cascade bool) error
 	AssignTask(ctx context.Context, taskID, userID int64) error
 	CompleteTask(ctx context.Context, id int64) error
+	CompleteTaskTree(ctx context.Context, id int64) ([]*domain.Task, error)
+	BulkUpdateStatus(ctx context.Context, ids []int64, status domain.TaskStatus) ([]BulkStatusResult, error)
+	GetStats(ctx context.Context) (*domain.TaskStats, error)
+	ReconcileMetrics(ctx context.Context) (map[domain.TaskStatus]int64, error)
+	GenerateRecurringTasks(ctx context.Context) (int, error)
+	NotifyDueSoon(ctx context.Context) (int, error)
+	AddDependency(ctx context.Context, taskID, dependsOnID int64) error
+	RemoveDependency(ctx context.Context, taskID, dependsOnID int64) error
+	CountSubtasks(ctx context.Context, id int64) (int, error)
+	AddComment(ctx context.Context, taskID int64, body string) (*domain.Comment, error)
+	ListComments(ctx context.Context, taskID int64) ([]*domain.Comment, error)
+	DeleteComment(ctx context.Context, taskID, commentID int64) error
 }
 
 // CreateTaskInput represents input for creating a task
 type CreateTaskInput struct {
-	Name        string          `json:"name"`
-	Description string          `json:"description"`
-	Priority    domain.Priority `json:"priority"`
-	CreatedBy   int64           `json:"created_by"`
+	Name           string          `json:"name"`
+	Description    string          `json:"description"`
+	Priority       domain.Priority `json:"priority"`
+	DueDate        *time.Time      `json:"due_date,omitempty"`
+	RecurrenceRule *string         `json:"recurrence_rule,omitempty"`
+	ParentID       *int64          `json:"parent_id,omitempty"`
+	CreatedBy      int64           `json:"created_by"`
 }
 
 // UpdateTaskInput represents input for updating a task
 type UpdateTaskInput struct {
-	Name        *string          `json:"name,omitempty"`
-	Description *string          `json:"description,omitempty"`
+	Name *string `json:"name,omitempty"`
+	// Description sets the description; null clears it
+	Description Optional[string]   `json:"description"`
 	Status      *domain.TaskStatus `json:"status,omitempty"`
 	Priority    *domain.Priority   `json:"priority,omitempty"`
+	// AssignedTo sets the assignee; null unassigns the task
+	AssignedTo Optional[int64] `json:"assigned_to"`
+	DueDate    *time.Time      `json:"due_date,omitempty"`
+	// RecurrenceRule sets the rule; an empty string removes it
+	RecurrenceRule *string `json:"recurrence_rule,omitempty"`
+	// IfUpdatedAt, when set, only updates the task while its updated_at still
+	// equals it; otherwise UpdateTask fails with domain.ErrTaskModified
+	IfUpdatedAt *time.Time `json:"-"`
 }
 
 // ListTasksFilter represents filters for listing tasks
@@ -48,6 +139,49 @@ type ListTasksFilter struct {
 	Status     *domain.TaskStatus
 	Priority   *domain.Priority
 	AssignedTo *int64
-	Limit      int
-	Offset     int
+	ParentID   *int64
+	CreatedBy  *int64
+	// CreatedAfter and CreatedBefore bound the creation time, inclusive
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	// DueBefore only matches tasks due before it
+	DueBefore *time.Time
+	// Include lists the terminal statuses to list anyway when
+	// Config.ListExcludeTerminal hides them
+	Include []domain.TaskStatus
+	// Limit is the page size; 0 means Config.ListDefaultLimit, and it is capped
+	// at Config.ListMaxLimit
+	Limit  int
+	Offset int
+}
+
+// repositoryFilter converts the filter for the repository, Limit aside
+func (f ListTasksFilter) repositoryFilter() repository.TaskFilter {
+	return repository.TaskFilter{
+		Status:        f.Status,
+		Priority:      f.Priority,
+		AssignedTo:    f.AssignedTo,
+		ParentID:      f.ParentID,
+		CreatedBy:     f.CreatedBy,
+		CreatedAfter:  f.CreatedAfter,
+		CreatedBefore: f.CreatedBefore,
+		DueBefore:     f.DueBefore,
+		Offset:        f.Offset,
+	}
+}
+
+// Per-task outcomes of a bulk status update
+const (
+	BulkResultUpdated           = "updated"
+	BulkResultUnchanged         = "unchanged"
+	BulkResultInvalidTransition = "invalid_transition"
+	BulkResultNotFound          = "not_found"
+)
+
+// BulkStatusResult reports what a bulk status update did to one task
+type BulkStatusResult struct {
+	ID     int64  `json:"id"`
+	Result string `json:"result"`
+	// Reason explains an invalid transition
+	Reason string `json:"reason,omitempty"`
 }
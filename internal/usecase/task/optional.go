@@ -0,0 +1,77 @@
+package task
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Optional is a JSON field that tells "omitted" apart from "null". A plain
+// pointer can't: both leave it nil. An omitted field is left unset, null sets it
+// to no value, and anything else sets it to that value.
+type Optional[T any] struct {
+	set   bool
+	value *T
+}
+
+// Some returns an Optional set to v
+func Some[T any](v T) Optional[T] {
+	return Optional[T]{set: true, value: &v}
+}
+
+// Null returns an Optional explicitly set to no value
+func Null[T any]() Optional[T] {
+	return Optional[T]{set: true}
+}
+
+// IsSet reports whether the field was present, null or not
+func (o Optional[T]) IsSet() bool {
+	return o.set
+}
+
+// IsNull reports whether the field was explicitly set to null
+func (o Optional[T]) IsNull() bool {
+	return o.set && o.value == nil
+}
+
+// Get returns the value and whether there is one; it is false for both omitted and null
+func (o Optional[T]) Get() (T, bool) {
+	if o.value == nil {
+		var zero T
+		return zero, false
+	}
+	return *o.value, true
+}
+
+// Ptr returns a copy of the value, or nil when there is none
+func (o Optional[T]) Ptr() *T {
+	if o.value == nil {
+		return nil
+	}
+	v := *o.value
+	return &v
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It is only called for fields
+// present in the input, which is what marks the Optional as set.
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.set = true
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		o.value = nil
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	o.value = &v
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler. An unset Optional is written as null;
+// there is no way to omit a struct field from Marshal.
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if o.value == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(*o.value)
+}
@@ -0,0 +1,95 @@
+package task
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	pkgcontext "github.com/seldomhappy/vibe_architecture/internal/pkg/context"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/tracing"
+)
+
+// What a use case does when one of its events can't be published
+const (
+	// PublishBestEffort logs the failure and reports success; downstream
+	// consumers may never hear of the change
+	PublishBestEffort = "best_effort"
+	// PublishOutbox stores events for Kafka in the outbox, in the transaction
+	// of their change, and the outbox publishes them once Kafka is reachable.
+	// The other sinks are published to as under PublishBestEffort.
+	PublishOutbox = "outbox"
+	// PublishStrict delivers the events to the durable sink, Kafka, before the
+	// change commits. If that fails, the change is rolled back and the call
+	// fails with domain.ErrEventNotPublished. The other sinks are published to
+	// once the change has committed, as under PublishBestEffort.
+	PublishStrict = "strict"
+)
+
+// commit runs fn in a transaction and publishes the events it returns to the
+// bus once it has committed, so live subscribers and webhooks only see
+// committed changes. Under PublishStrict they are first delivered to the
+// durable sink inside the transaction, so a failed delivery rolls the change
+// back; Kafka may still have an event whose commit then failed. Under
+// PublishOutbox they are stored in the outbox inside the transaction instead.
+func (uc *TaskUseCase) commit(ctx context.Context, fn func(ctx context.Context, tx pgx.Tx) ([]domain.TaskEvent, error)) error {
+	var events []domain.TaskEvent
+	err := uc.tx.WithTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		var err error
+		if events, err = fn(ctx, tx); err != nil {
+			return err
+		}
+		for i := range events {
+			uc.prepareEvent(ctx, &events[i])
+			if uc.cfg.PublishPolicy != PublishOutbox {
+				continue
+			}
+			if err := uc.cfg.Outbox.AddTx(ctx, tx, events[i]); err != nil {
+				uc.logger.Error("[%s][trace:%s] Failed to store %s event for task %d in the outbox: %v",
+					pkgcontext.GetRequestID(ctx), pkgcontext.GetTraceID(ctx), events[i].Type, events[i].TaskID, err)
+				return err
+			}
+		}
+		if uc.cfg.PublishPolicy != PublishStrict {
+			return nil
+		}
+		for _, event := range events {
+			if err := uc.deliver(ctx, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		uc.events.Publish(ctx, event)
+	}
+	return nil
+}
+
+// prepareEvent gives an event its ID, so every sink and every redelivery sees
+// the same one. Events not built from a task belong to the tenant of ctx.
+func (uc *TaskUseCase) prepareEvent(ctx context.Context, event *domain.TaskEvent) {
+	event.ID = uuid.NewString()
+	if event.TenantID == "" {
+		event.TenantID = pkgcontext.GetTenantID(ctx)
+	}
+}
+
+// deliver hands an event to the durable sink and waits for it
+func (uc *TaskUseCase) deliver(ctx context.Context, event domain.TaskEvent) error {
+	if uc.cfg.Durable == nil {
+		return nil
+	}
+	if err := uc.cfg.Durable.HandleEvent(ctx, event); err != nil {
+		uc.logger.Error("[%s][trace:%s] Failed to publish %s event for task %d: %v",
+			pkgcontext.GetRequestID(ctx), pkgcontext.GetTraceID(ctx), event.Type, event.TaskID, err)
+		tracing.RecordError(ctx, err)
+		return fmt.Errorf("%w: %s for task %d", domain.ErrEventNotPublished, event.Type, event.TaskID)
+	}
+	return nil
+}
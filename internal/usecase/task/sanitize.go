@@ -0,0 +1,35 @@
+package task
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Ways of sanitizing task names and descriptions before they are stored
+const (
+	// SanitizeNone stores text as sent, apart from trimming surrounding whitespace
+	SanitizeNone = "none"
+	// SanitizeEscape HTML-escapes text, so it is safe to insert into a page as is
+	SanitizeEscape = "escape"
+	// SanitizeStrip removes HTML tags from text
+	SanitizeStrip = "strip"
+)
+
+// htmlTag matches an HTML tag or comment
+var htmlTag = regexp.MustCompile(`<!--[\s\S]*?-->|</?[a-zA-Z][^>]*>`)
+
+// sanitizer cleans up user-supplied task text
+type sanitizer func(string) string
+
+// newSanitizer returns the sanitizer for mode; anything unknown means SanitizeNone
+func newSanitizer(mode string) sanitizer {
+	switch mode {
+	case SanitizeEscape:
+		return func(s string) string { return html.EscapeString(strings.TrimSpace(s)) }
+	case SanitizeStrip:
+		return func(s string) string { return strings.TrimSpace(htmlTag.ReplaceAllString(s, "")) }
+	default:
+		return strings.TrimSpace
+	}
+}
@@ -0,0 +1,122 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	pkgcontext "github.com/seldomhappy/vibe_architecture/internal/pkg/context"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// CompleteTaskTree completes a task together with all of its incomplete
+// subtasks (recursively) in one transaction, subtasks before their parents, and
+// returns the tasks it completed. If any task in the tree can't be completed,
+// nothing is and a *domain.TaskTreeBlockedError lists every blocker.
+func (uc *TaskUseCase) CompleteTaskTree(ctx context.Context, id int64) ([]*domain.Task, error) {
+	start := time.Now()
+	ctx, span := tracing.StartSpan(ctx, "usecase", "complete_task_tree")
+	defer tracing.EndSpan(span)
+
+	requestID := pkgcontext.GetRequestID(ctx)
+	traceID := pkgcontext.GetTraceID(ctx)
+
+	span.SetAttributes(attribute.Int64("task.id", id))
+
+	uc.logger.Info("[%s][trace:%s] Completing task tree: ID=%d", requestID, traceID, id)
+
+	var completed []*domain.Task
+	err := uc.commit(ctx, func(ctx context.Context, tx pgx.Tx) ([]domain.TaskEvent, error) {
+		tree, err := uc.repo.GetTreeForUpdate(ctx, tx, id)
+		if err != nil {
+			uc.logger.Error("[%s][trace:%s] Failed to get task tree: %v", requestID, traceID, err)
+			return nil, err
+		}
+
+		inTree := make(map[int64]bool, len(tree))
+		for _, task := range tree {
+			inTree[task.ID] = true
+		}
+
+		// Walk the tree backwards so subtasks are completed before their parents
+		var blockers []domain.TaskBlocker
+		completed = nil
+		for i := len(tree) - 1; i >= 0; i-- {
+			task := tree[i]
+			if task.IsCompleted() {
+				continue
+			}
+
+			blocked, err := uc.hasIncompleteDependenciesOutside(ctx, task.ID, inTree)
+			if err != nil {
+				return nil, err
+			}
+			if blocked {
+				blockers = append(blockers, domain.TaskBlocker{TaskID: task.ID, Reason: domain.ErrDependenciesIncomplete.Error()})
+				continue
+			}
+
+			if err := task.Complete(uc.clock.Now()); err != nil {
+				blockers = append(blockers, domain.TaskBlocker{TaskID: task.ID, Reason: err.Error()})
+				continue
+			}
+			completed = append(completed, task)
+		}
+		if len(blockers) > 0 {
+			uc.logger.Warn("[%s][trace:%s] Task tree %d has %d blocking tasks", requestID, traceID, id, len(blockers))
+			return nil, &domain.TaskTreeBlockedError{Blockers: blockers}
+		}
+
+		for _, task := range completed {
+			if err := uc.repo.UpdateTx(ctx, tx, task); err != nil {
+				uc.logger.Error("[%s][trace:%s] Failed to save task %d: %v", requestID, traceID, task.ID, err)
+				return nil, fmt.Errorf("failed to save task %d: %w", task.ID, err)
+			}
+		}
+		return uc.statusChangedEvents(completed), nil
+	})
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+	uc.recordStatusChanged(completed)
+
+	span.SetAttributes(attribute.Int("tasks.completed", len(completed)))
+	uc.metrics.RecordTaskProcessingDuration(time.Since(start))
+	uc.logger.Info("[%s][trace:%s] Task tree completed successfully: ID=%d (%d tasks)", requestID, traceID, id, len(completed))
+
+	return completed, nil
+}
+
+// hasIncompleteDependenciesOutside reports whether taskID depends on a task that
+// isn't completed and isn't part of the tree being completed along with it
+func (uc *TaskUseCase) hasIncompleteDependenciesOutside(ctx context.Context, taskID int64, inTree map[int64]bool) (bool, error) {
+	deps, err := uc.repo.GetDependencies(ctx, taskID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check dependencies: %w", err)
+	}
+
+	outside := make([]int64, 0, len(deps))
+	for _, dep := range deps {
+		if !inTree[dep] {
+			outside = append(outside, dep)
+		}
+	}
+	if len(outside) == 0 {
+		return false, nil
+	}
+
+	tasks, err := uc.repo.GetByIDs(ctx, outside)
+	if err != nil {
+		return false, fmt.Errorf("failed to check dependencies: %w", err)
+	}
+	for _, dep := range tasks {
+		if !dep.IsCompleted() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -2,11 +2,15 @@ package task
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/seldomhappy/vibe_architecture/internal/domain"
-	"github.com/seldomhappy/vibe_architecture/internal/infrastructure/kafka"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/clock"
 	pkgcontext "github.com/seldomhappy/vibe_architecture/internal/pkg/context"
 	"github.com/seldomhappy/vibe_architecture/internal/pkg/metrics"
 	"github.com/seldomhappy/vibe_architecture/internal/pkg/tracing"
@@ -15,21 +19,115 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 )
 
+// Config holds task use case configuration
+type Config struct {
+	// StatsCacheTTL is how long computed task stats are served from memory
+	StatsCacheTTL time.Duration
+	// RequireSubtasksCompleted blocks completing a task while it has open subtasks
+	RequireSubtasksCompleted bool
+	// BulkMaxIDs caps how many tasks one bulk status update may touch
+	BulkMaxIDs int
+	// DefaultPriority is given to new tasks created without a priority
+	DefaultPriority domain.Priority
+	// InitialStatus is the status new tasks start in
+	InitialStatus domain.TaskStatus
+	// Sanitize is how task names and descriptions are cleaned up before they are
+	// stored: SanitizeNone, SanitizeEscape or SanitizeStrip. All of them trim
+	// surrounding whitespace.
+	Sanitize string
+	// ListDefaultLimit is the page size of ListTasks when the filter has no limit
+	ListDefaultLimit int
+	// ListMaxLimit caps the page size of ListTasks, whoever the caller is
+	ListMaxLimit int
+	// IncludeTaskInEvents publishes a snapshot of the whole task with every
+	// created, updated, completed and deleted event
+	IncludeTaskInEvents bool
+	// PublishPolicy is PublishBestEffort, PublishOutbox or PublishStrict
+	PublishPolicy string
+	// Outbox stores the events for Kafka under PublishOutbox
+	Outbox Outbox
+	// Durable is the sink, Kafka, that PublishStrict delivers events to before
+	// their change commits. It must not be subscribed to the bus as well.
+	Durable EventSink
+	// DueSoonWindow is how long before its due date a task gets its due soon event
+	DueSoonWindow time.Duration
+	// ListExcludeTerminal leaves completed and cancelled tasks out of lists that
+	// don't filter by status, unless the filter includes them
+	ListExcludeTerminal bool
+	// Limits bounds task fields such as the description length
+	Limits domain.Limits
+	// Clock is the time the use case goes by; nil is the system clock
+	Clock clock.Clock
+}
+
 // TaskUseCase implements the UseCase interface
 type TaskUseCase struct {
+	cfg      Config
 	repo     Repository
-	producer *kafka.Producer
+	tx       TxManager
+	events   EventBus
 	logger   logger.ILogger
 	metrics  *metrics.Metrics
+	sanitize sanitizer
+	clock    clock.Clock
+
+	statsMu    sync.Mutex
+	statsCache map[string]cachedStats
+}
+
+// cachedStats are the stats of one tenant until they expire
+type cachedStats struct {
+	stats   *domain.TaskStats
+	expires time.Time
 }
 
 // New creates a new task use case
-func New(repo Repository, producer *kafka.Producer, log logger.ILogger, m *metrics.Metrics) UseCase {
+func New(cfg Config, repo Repository, txManager TxManager, events EventBus, log logger.ILogger, m *metrics.Metrics) UseCase {
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.Real{}
+	}
 	return &TaskUseCase{
+		cfg:      cfg,
 		repo:     repo,
-		producer: producer,
+		tx:       txManager,
+		events:   events,
 		logger:   log,
 		metrics:  m,
+		sanitize: newSanitizer(cfg.Sanitize),
+		clock:    clk,
+
+		statsCache: make(map[string]cachedStats),
+	}
+}
+
+// isConstraintError reports whether the repository rejected a write because of a
+// database constraint. These are returned as is so the handler can map them.
+func isConstraintError(err error) bool {
+	return errors.Is(err, domain.ErrConflict) || errors.Is(err, domain.ErrReferenceNotFound) || errors.Is(err, domain.ErrInvalidInput)
+}
+
+// taskSnapshot returns the copy of task published with its events, or nil when
+// events don't include the task
+func (uc *TaskUseCase) taskSnapshot(task *domain.Task) *domain.Task {
+	if !uc.cfg.IncludeTaskInEvents {
+		return nil
+	}
+	snapshot := *task
+	return &snapshot
+}
+
+// newTaskEvent wraps an event payload for the event bus
+func (uc *TaskUseCase) newTaskEvent(eventType domain.EventType, task *domain.Task, payload interface{}) domain.TaskEvent {
+	return domain.TaskEvent{
+		Type:       eventType,
+		TaskID:     task.ID,
+		TenantID:   task.TenantID,
+		Status:     task.Status,
+		AssignedTo: task.AssignedTo,
+		Payload:    payload,
+		Task:       uc.taskSnapshot(task),
+		OccurredAt: uc.clock.Now(),
 	}
 }
 
@@ -37,65 +135,141 @@ func New(repo Repository, producer *kafka.Producer, log logger.ILogger, m *metri
 func (uc *TaskUseCase) CreateTask(ctx context.Context, input CreateTaskInput) (*domain.Task, error) {
 	start := time.Now()
 	ctx, span := tracing.StartSpan(ctx, "usecase", "create_task")
-	defer span.End()
+	defer tracing.EndSpan(span)
 
 	requestID := pkgcontext.GetRequestID(ctx)
 	traceID := pkgcontext.GetTraceID(ctx)
 
+	task := uc.newTask(ctx, input)
+
 	span.SetAttributes(
 		attribute.String("task.name", input.Name),
-		attribute.String("task.priority", string(input.Priority)),
+		attribute.String("task.priority", string(task.Priority)),
 	)
 
 	uc.logger.Info("[%s][trace:%s] Creating task: %s", requestID, traceID, input.Name)
 
-	task := &domain.Task{
-		Name:        input.Name,
-		Description: input.Description,
-		Status:      domain.TaskStatusPending,
-		Priority:    input.Priority,
-		CreatedBy:   input.CreatedBy,
+	if input.ParentID != nil {
+		if err := uc.requireTask(ctx, *input.ParentID); err != nil {
+			uc.logger.Error("[%s][trace:%s] Parent task not found: %v", requestID, traceID, err)
+			tracing.RecordError(ctx, err)
+			return nil, err
+		}
+		task.ParentID = input.ParentID
 	}
 
-	if err := task.Validate(); err != nil {
+	if err := task.Validate(uc.cfg.Limits); err != nil {
 		uc.logger.Error("[%s][trace:%s] Task validation failed: %v", requestID, traceID, err)
+		tracing.AddEvent(ctx, "validation_failed", attribute.String("error", err.Error()))
 		tracing.RecordError(ctx, err)
 		uc.metrics.RecordTaskFailed()
 		return nil, err
 	}
 
-	if err := uc.repo.Create(ctx, task); err != nil {
-		uc.logger.Error("[%s][trace:%s] Failed to create task: %v", requestID, traceID, err)
+	err := uc.commit(ctx, func(ctx context.Context, tx pgx.Tx) ([]domain.TaskEvent, error) {
+		if err := uc.repo.CreateTx(ctx, tx, task); err != nil {
+			uc.logger.Error("[%s][trace:%s] Failed to create task: %v", requestID, traceID, err)
+			if isConstraintError(err) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("failed to create task: %w", err)
+		}
+
+		// Publish task created event
+		event := domain.TaskCreatedEvent{
+			TaskID:      task.ID,
+			Name:        task.Name,
+			Description: task.Description,
+			Priority:    task.Priority,
+			DueDate:     task.DueDate,
+			CreatedBy:   task.CreatedBy,
+			CreatedAt:   task.CreatedAt,
+		}
+		return []domain.TaskEvent{uc.newTaskEvent(domain.EventTypeTaskCreated, task, event)}, nil
+	})
+	if err != nil {
 		tracing.RecordError(ctx, err)
 		uc.metrics.RecordTaskFailed()
-		return nil, fmt.Errorf("failed to create task: %w", err)
-	}
-
-	// Publish task created event
-	event := domain.TaskCreatedEvent{
-		TaskID:      task.ID,
-		Name:        task.Name,
-		Description: task.Description,
-		Priority:    task.Priority,
-		CreatedBy:   task.CreatedBy,
-		CreatedAt:   task.CreatedAt,
-	}
-
-	if err := uc.producer.PublishTaskCreated(ctx, event); err != nil {
-		uc.logger.Warn("[%s][trace:%s] Failed to publish task created event: %v", requestID, traceID, err)
+		return nil, err
 	}
 
 	uc.metrics.RecordTaskCreated()
 	uc.metrics.RecordTaskProcessingDuration(time.Since(start))
+
 	uc.logger.Info("[%s][trace:%s] Task created successfully: ID=%d", requestID, traceID, task.ID)
 
 	return task, nil
 }
 
+// ValidateTask validates a task the way CreateTask does, without creating it
+// or publishing anything
+func (uc *TaskUseCase) ValidateTask(ctx context.Context, input CreateTaskInput) error {
+	ctx, span := tracing.StartSpan(ctx, "usecase", "validate_task")
+	defer tracing.EndSpan(span)
+
+	if input.ParentID != nil {
+		if err := uc.requireTask(ctx, *input.ParentID); err != nil {
+			tracing.RecordError(ctx, err)
+			return err
+		}
+	}
+
+	if err := uc.newTask(ctx, input).Validate(uc.cfg.Limits); err != nil {
+		uc.logger.Debug("[%s][trace:%s] Task validation failed: %v",
+			pkgcontext.GetRequestID(ctx), pkgcontext.GetTraceID(ctx), err)
+		tracing.AddEvent(ctx, "validation_failed", attribute.String("error", err.Error()))
+		tracing.RecordError(ctx, err)
+		return err
+	}
+	return nil
+}
+
+// requireTask returns domain.ErrTaskNotFound unless the task exists. It doesn't
+// load the task.
+func (uc *TaskUseCase) requireTask(ctx context.Context, id int64) error {
+	exists, err := uc.repo.Exists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return domain.ErrTaskNotFound
+	}
+	return nil
+}
+
+// newTask builds the task CreateTask stores, with the configured defaults
+// applied. It belongs to the tenant of ctx.
+func (uc *TaskUseCase) newTask(ctx context.Context, input CreateTaskInput) *domain.Task {
+	priority := input.Priority
+	if priority == "" {
+		priority = uc.cfg.DefaultPriority
+	}
+	status := uc.cfg.InitialStatus
+	if status == "" {
+		status = domain.TaskStatusPending
+	}
+
+	// Only the text the client sent is sanitized; stored text already was, and
+	// escaping it again would double-escape it
+	task := &domain.Task{
+		TenantID:    pkgcontext.GetTenantID(ctx),
+		Name:        uc.sanitize(input.Name),
+		Description: uc.sanitize(input.Description),
+		Status:      status,
+		Priority:    priority,
+		DueDate:     input.DueDate,
+		CreatedBy:   input.CreatedBy,
+	}
+	if input.RecurrenceRule != nil && *input.RecurrenceRule != "" {
+		task.RecurrenceRule = input.RecurrenceRule
+	}
+	return task
+}
+
 // GetTask retrieves a task by ID
 func (uc *TaskUseCase) GetTask(ctx context.Context, id int64) (*domain.Task, error) {
 	ctx, span := tracing.StartSpan(ctx, "usecase", "get_task")
-	defer span.End()
+	defer tracing.EndSpan(span)
 
 	requestID := pkgcontext.GetRequestID(ctx)
 	traceID := pkgcontext.GetTraceID(ctx)
@@ -111,28 +285,43 @@ func (uc *TaskUseCase) GetTask(ctx context.Context, id int64) (*domain.Task, err
 		return nil, err
 	}
 
+	dependsOn, err := uc.repo.GetDependencies(ctx, id)
+	if err != nil {
+		uc.logger.Error("[%s][trace:%s] Failed to get task dependencies: %v", requestID, traceID, err)
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	task.DependsOn = dependsOn
+
 	return task, nil
 }
 
+// ResolveTaskUUID returns the ID of the task with the given UUID
+func (uc *TaskUseCase) ResolveTaskUUID(ctx context.Context, id uuid.UUID) (int64, error) {
+	ctx, span := tracing.StartSpan(ctx, "usecase", "resolve_task_uuid")
+	defer tracing.EndSpan(span)
+
+	span.SetAttributes(attribute.String("task.uuid", id.String()))
+
+	taskID, err := uc.repo.GetIDByUUID(ctx, id)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return 0, err
+	}
+	return taskID, nil
+}
+
 // ListTasks retrieves tasks with filters
 func (uc *TaskUseCase) ListTasks(ctx context.Context, filter ListTasksFilter) ([]*domain.Task, error) {
 	ctx, span := tracing.StartSpan(ctx, "usecase", "list_tasks")
-	defer span.End()
+	defer tracing.EndSpan(span)
 
 	requestID := pkgcontext.GetRequestID(ctx)
 	traceID := pkgcontext.GetTraceID(ctx)
 
 	uc.logger.Debug("[%s][trace:%s] Listing tasks with filter", requestID, traceID)
 
-	repoFilter := repository.TaskFilter{
-		Status:     filter.Status,
-		Priority:   filter.Priority,
-		AssignedTo: filter.AssignedTo,
-		Limit:      filter.Limit,
-		Offset:     filter.Offset,
-	}
-
-	tasks, err := uc.repo.GetAll(ctx, repoFilter)
+	tasks, err := uc.repo.GetAll(ctx, uc.listFilter(ctx, filter))
 	if err != nil {
 		uc.logger.Error("[%s][trace:%s] Failed to list tasks: %v", requestID, traceID, err)
 		tracing.RecordError(ctx, err)
@@ -143,66 +332,204 @@ func (uc *TaskUseCase) ListTasks(ctx context.Context, filter ListTasksFilter) ([
 	return tasks, nil
 }
 
-// UpdateTask updates an existing task
-func (uc *TaskUseCase) UpdateTask(ctx context.Context, id int64, input UpdateTaskInput) (*domain.Task, error) {
-	ctx, span := tracing.StartSpan(ctx, "usecase", "update_task")
-	defer span.End()
+// StreamTasks calls fn with each task ListTasks would return, as it is read,
+// so the page is never held in memory as a whole. It stops at the first error
+// from fn and returns it.
+func (uc *TaskUseCase) StreamTasks(ctx context.Context, filter ListTasksFilter, fn func(*domain.Task) error) error {
+	ctx, span := tracing.StartSpan(ctx, "usecase", "stream_tasks")
+	defer tracing.EndSpan(span)
 
 	requestID := pkgcontext.GetRequestID(ctx)
 	traceID := pkgcontext.GetTraceID(ctx)
 
-	span.SetAttributes(attribute.Int64("task.id", id))
-
-	uc.logger.Info("[%s][trace:%s] Updating task: ID=%d", requestID, traceID, id)
+	uc.logger.Debug("[%s][trace:%s] Streaming tasks with filter", requestID, traceID)
 
-	task, err := uc.repo.GetByID(ctx, id)
+	count := 0
+	err := uc.repo.IterateAll(ctx, uc.listFilter(ctx, filter), func(task *domain.Task) error {
+		count++
+		return fn(task)
+	})
 	if err != nil {
-		uc.logger.Error("[%s][trace:%s] Task not found: %v", requestID, traceID, err)
+		uc.logger.Error("[%s][trace:%s] Failed to stream tasks: %v", requestID, traceID, err)
 		tracing.RecordError(ctx, err)
-		return nil, err
+		return fmt.Errorf("failed to list tasks: %w", err)
 	}
 
-	if input.Name != nil {
-		task.Name = *input.Name
+	span.SetAttributes(attribute.Int("tasks.count", count))
+	return nil
+}
+
+// listFilter converts a list filter for the repository, with its limit
+// defaulted and capped
+func (uc *TaskUseCase) listFilter(ctx context.Context, filter ListTasksFilter) repository.TaskFilter {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = uc.cfg.ListDefaultLimit
 	}
-	if input.Description != nil {
-		task.Description = *input.Description
+	if limit > uc.cfg.ListMaxLimit {
+		limit = uc.cfg.ListMaxLimit
 	}
-	if input.Status != nil {
-		task.Status = *input.Status
+
+	repoFilter := uc.repositoryFilter(ctx, filter)
+	repoFilter.Limit = limit
+	return repoFilter
+}
+
+// repositoryFilter converts a list filter for the repository, Limit aside,
+// limited to the tenant of ctx and leaving out terminal statuses when lists
+// hide them
+func (uc *TaskUseCase) repositoryFilter(ctx context.Context, filter ListTasksFilter) repository.TaskFilter {
+	repoFilter := filter.repositoryFilter()
+	repoFilter.TenantID = repository.TenantOf(ctx)
+	if !uc.cfg.ListExcludeTerminal || filter.Status != nil {
+		return repoFilter
 	}
-	if input.Priority != nil {
-		task.Priority = *input.Priority
+	for _, status := range []domain.TaskStatus{domain.TaskStatusCompleted, domain.TaskStatusCancelled} {
+		if !containsStatus(filter.Include, status) {
+			repoFilter.ExcludeStatuses = append(repoFilter.ExcludeStatuses, status)
+		}
 	}
-	task.UpdatedAt = time.Now()
+	return repoFilter
+}
 
-	if err := task.Validate(); err != nil {
-		uc.logger.Error("[%s][trace:%s] Task validation failed: %v", requestID, traceID, err)
-		tracing.RecordError(ctx, err)
-		uc.metrics.RecordTaskFailed()
-		return nil, err
+func containsStatus(statuses []domain.TaskStatus, status domain.TaskStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
 	}
+	return false
+}
+
+// GetListVersion returns the version of the tasks ListTasks would list with
+// filter, across all pages. It changes whenever any of them does.
+func (uc *TaskUseCase) GetListVersion(ctx context.Context, filter ListTasksFilter) (repository.ListVersion, error) {
+	ctx, span := tracing.StartSpan(ctx, "usecase", "get_task_list_version")
+	defer tracing.EndSpan(span)
 
-	if err := uc.repo.Update(ctx, task); err != nil {
-		uc.logger.Error("[%s][trace:%s] Failed to update task: %v", requestID, traceID, err)
+	requestID := pkgcontext.GetRequestID(ctx)
+	traceID := pkgcontext.GetTraceID(ctx)
+
+	version, err := uc.repo.GetListVersion(ctx, uc.repositoryFilter(ctx, filter))
+	if err != nil {
+		uc.logger.Error("[%s][trace:%s] Failed to get task list version: %v", requestID, traceID, err)
 		tracing.RecordError(ctx, err)
-		uc.metrics.RecordTaskFailed()
-		return nil, fmt.Errorf("failed to update task: %w", err)
+		return repository.ListVersion{}, fmt.Errorf("failed to get task list version: %w", err)
 	}
 
-	// Publish task updated event
-	event := domain.TaskUpdatedEvent{
-		TaskID:      task.ID,
-		Name:        task.Name,
-		Description: task.Description,
-		Status:      task.Status,
-		Priority:    task.Priority,
-		AssignedTo:  task.AssignedTo,
-		UpdatedAt:   task.UpdatedAt,
-	}
+	return version, nil
+}
+
+// UpdateTask updates an existing task. A status change is held to the same
+// rules as completing a task or a bulk status update: completed and cancelled
+// tasks are final, and a task can't be completed before its dependencies (and,
+// with RequireSubtasksCompleted, its subtasks). Completing a task this way also
+// publishes TaskCompleted.
+func (uc *TaskUseCase) UpdateTask(ctx context.Context, id int64, input UpdateTaskInput) (*domain.Task, error) {
+	ctx, span := tracing.StartSpan(ctx, "usecase", "update_task")
+	defer tracing.EndSpan(span)
 
-	if err := uc.producer.PublishTaskUpdated(ctx, event); err != nil {
-		uc.logger.Warn("[%s][trace:%s] Failed to publish task updated event: %v", requestID, traceID, err)
+	requestID := pkgcontext.GetRequestID(ctx)
+	traceID := pkgcontext.GetTraceID(ctx)
+
+	span.SetAttributes(attribute.Int64("task.id", id))
+
+	uc.logger.Info("[%s][trace:%s] Updating task: ID=%d", requestID, traceID, id)
+
+	// Lock the row so the status checks still hold when the update is written
+	var task *domain.Task
+	statusChanged := false
+	err := uc.commit(ctx, func(ctx context.Context, tx pgx.Tx) ([]domain.TaskEvent, error) {
+		var err error
+		task, err = uc.repo.GetByIDForUpdate(ctx, tx, id)
+		if err != nil {
+			uc.logger.Error("[%s][trace:%s] Task not found: %v", requestID, traceID, err)
+			return nil, err
+		}
+
+		if input.Name != nil {
+			task.Name = uc.sanitize(*input.Name)
+		}
+		if input.Description.IsSet() {
+			description, _ := input.Description.Get()
+			task.Description = uc.sanitize(description)
+		}
+		if input.Status != nil && *input.Status != task.Status {
+			reason, err := uc.checkTransition(ctx, tx, task, *input.Status)
+			if err != nil {
+				uc.logger.Error("[%s][trace:%s] Failed to check status transition: %v", requestID, traceID, err)
+				return nil, err
+			}
+			if reason != nil {
+				uc.logger.Warn("[%s][trace:%s] Task %d can't move to %s: %v", requestID, traceID, id, *input.Status, reason)
+				return nil, reason
+			}
+			statusChanged = true
+		}
+		if input.Priority != nil {
+			task.Priority = *input.Priority
+		}
+		if input.AssignedTo.IsSet() {
+			task.AssignedTo = input.AssignedTo.Ptr()
+		}
+		if input.DueDate != nil {
+			task.DueDate = input.DueDate
+		}
+		if input.RecurrenceRule != nil {
+			if *input.RecurrenceRule == "" {
+				task.RecurrenceRule = nil
+			} else {
+				task.RecurrenceRule = input.RecurrenceRule
+			}
+		}
+
+		if err := task.Validate(uc.cfg.Limits); err != nil {
+			uc.logger.Error("[%s][trace:%s] Task validation failed: %v", requestID, traceID, err)
+			tracing.AddEvent(ctx, "validation_failed", attribute.String("error", err.Error()))
+			return nil, err
+		}
+
+		if input.IfUpdatedAt != nil {
+			err = uc.repo.UpdateTxIfUnchanged(ctx, tx, task, *input.IfUpdatedAt)
+		} else {
+			err = uc.repo.UpdateTx(ctx, tx, task)
+		}
+		if err != nil {
+			uc.logger.Error("[%s][trace:%s] Failed to update task: %v", requestID, traceID, err)
+			if isConstraintError(err) || errors.Is(err, domain.ErrTaskModified) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("failed to update task: %w", err)
+		}
+
+		// Publish task updated event
+		event := domain.TaskUpdatedEvent{
+			TaskID:      task.ID,
+			Name:        task.Name,
+			Description: task.Description,
+			Status:      task.Status,
+			Priority:    task.Priority,
+			AssignedTo:  task.AssignedTo,
+			DueDate:     task.DueDate,
+			UpdatedAt:   task.UpdatedAt,
+		}
+		events := []domain.TaskEvent{uc.newTaskEvent(domain.EventTypeTaskUpdated, task, event)}
+		if statusChanged && task.IsCompleted() {
+			completed := domain.TaskCompletedEvent{
+				TaskID:      task.ID,
+				CompletedAt: task.UpdatedAt,
+			}
+			events = append(events, uc.newTaskEvent(domain.EventTypeTaskCompleted, task, completed))
+		}
+		return events, nil
+	})
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		uc.metrics.RecordTaskFailed()
+		return nil, err
+	}
+	if statusChanged && task.IsCompleted() {
+		uc.metrics.RecordTaskCompleted()
 	}
 
 	uc.logger.Info("[%s][trace:%s] Task updated successfully: ID=%d", requestID, traceID, task.ID)
@@ -210,43 +537,119 @@ func (uc *TaskUseCase) UpdateTask(ctx context.Context, id int64, input UpdateTas
 	return task, nil
 }
 
-// DeleteTask deletes a task
-func (uc *TaskUseCase) DeleteTask(ctx context.Context, id int64) error {
+// DeleteTask deletes a task. Tasks with subtasks are only deleted when cascade is
+// set, in which case the whole subtree is removed and an event is published per task.
+func (uc *TaskUseCase) DeleteTask(ctx context.Context, id int64, cascade bool) error {
 	ctx, span := tracing.StartSpan(ctx, "usecase", "delete_task")
-	defer span.End()
+	defer tracing.EndSpan(span)
 
 	requestID := pkgcontext.GetRequestID(ctx)
 	traceID := pkgcontext.GetTraceID(ctx)
 
-	span.SetAttributes(attribute.Int64("task.id", id))
+	span.SetAttributes(
+		attribute.Int64("task.id", id),
+		attribute.Bool("task.cascade", cascade),
+	)
 
 	uc.logger.Info("[%s][trace:%s] Deleting task: ID=%d", requestID, traceID, id)
 
-	if err := uc.repo.Delete(ctx, id); err != nil {
-		uc.logger.Error("[%s][trace:%s] Failed to delete task: %v", requestID, traceID, err)
+	var deleted []*domain.Task
+	err := uc.commit(ctx, func(ctx context.Context, tx pgx.Tx) ([]domain.TaskEvent, error) {
+		if cascade {
+			tasks, err := uc.repo.DeleteTreeTx(ctx, tx, id)
+			if err != nil {
+				uc.logger.Error("[%s][trace:%s] Failed to delete task: %v", requestID, traceID, err)
+				return nil, err
+			}
+			deleted = tasks
+		} else {
+			subtasks, err := uc.repo.CountSubtasks(ctx, id)
+			if err != nil {
+				uc.logger.Error("[%s][trace:%s] Failed to count subtasks: %v", requestID, traceID, err)
+				return nil, fmt.Errorf("failed to delete task: %w", err)
+			}
+			if subtasks > 0 {
+				return nil, domain.ErrTaskHasSubtasks
+			}
+
+			// Only the ID is needed unless the event carries the task as it was
+			task := &domain.Task{ID: id}
+			if uc.cfg.IncludeTaskInEvents {
+				if task, err = uc.repo.GetByIDForUpdate(ctx, tx, id); err != nil {
+					uc.logger.Error("[%s][trace:%s] Failed to get task: %v", requestID, traceID, err)
+					return nil, err
+				}
+			}
+
+			if err := uc.repo.DeleteTx(ctx, tx, id); err != nil {
+				uc.logger.Error("[%s][trace:%s] Failed to delete task: %v", requestID, traceID, err)
+				return nil, err
+			}
+			deleted = []*domain.Task{task}
+		}
+
+		// Publish task deleted events
+		events := make([]domain.TaskEvent, 0, len(deleted))
+		for _, task := range deleted {
+			event := domain.TaskDeletedEvent{
+				TaskID:    task.ID,
+				DeletedAt: uc.clock.Now(),
+			}
+
+			events = append(events, domain.TaskEvent{
+				Type:       domain.EventTypeTaskDeleted,
+				TaskID:     task.ID,
+				Payload:    event,
+				Task:       uc.taskSnapshot(task),
+				OccurredAt: event.DeletedAt,
+			})
+		}
+		return events, nil
+	})
+	if err != nil {
 		tracing.RecordError(ctx, err)
 		return err
 	}
 
-	// Publish task deleted event
-	event := domain.TaskDeletedEvent{
-		TaskID:    id,
-		DeletedAt: time.Now(),
-	}
+	uc.logger.Info("[%s][trace:%s] Task deleted successfully: ID=%d (%d total)", requestID, traceID, id, len(deleted))
 
-	if err := uc.producer.PublishTaskDeleted(ctx, event); err != nil {
-		uc.logger.Warn("[%s][trace:%s] Failed to publish task deleted event: %v", requestID, traceID, err)
-	}
+	return nil
+}
+
+// CheckTaskExists returns domain.ErrTaskNotFound unless the task exists,
+// without loading it
+func (uc *TaskUseCase) CheckTaskExists(ctx context.Context, id int64) error {
+	ctx, span := tracing.StartSpan(ctx, "usecase", "check_task_exists")
+	defer tracing.EndSpan(span)
 
-	uc.logger.Info("[%s][trace:%s] Task deleted successfully: ID=%d", requestID, traceID, id)
+	span.SetAttributes(attribute.Int64("task.id", id))
 
+	if err := uc.requireTask(ctx, id); err != nil {
+		tracing.RecordError(ctx, err)
+		return err
+	}
 	return nil
 }
 
+// CountSubtasks returns the number of direct subtasks of a task
+func (uc *TaskUseCase) CountSubtasks(ctx context.Context, id int64) (int, error) {
+	ctx, span := tracing.StartSpan(ctx, "usecase", "count_subtasks")
+	defer tracing.EndSpan(span)
+
+	span.SetAttributes(attribute.Int64("task.id", id))
+
+	count, err := uc.repo.CountSubtasks(ctx, id)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return 0, fmt.Errorf("failed to count subtasks: %w", err)
+	}
+	return count, nil
+}
+
 // AssignTask assigns a task to a user
 func (uc *TaskUseCase) AssignTask(ctx context.Context, taskID, userID int64) error {
 	ctx, span := tracing.StartSpan(ctx, "usecase", "assign_task")
-	defer span.End()
+	defer tracing.EndSpan(span)
 
 	requestID := pkgcontext.GetRequestID(ctx)
 	traceID := pkgcontext.GetTraceID(ctx)
@@ -258,90 +661,448 @@ func (uc *TaskUseCase) AssignTask(ctx context.Context, taskID, userID int64) err
 
 	uc.logger.Info("[%s][trace:%s] Assigning task %d to user %d", requestID, traceID, taskID, userID)
 
-	task, err := uc.repo.GetByID(ctx, taskID)
+	if userID <= 0 {
+		tracing.RecordError(ctx, domain.ErrUserNotFound)
+		return domain.ErrUserNotFound
+	}
+
+	err := uc.commit(ctx, func(ctx context.Context, tx pgx.Tx) ([]domain.TaskEvent, error) {
+		// One conditional UPDATE, so concurrent assign/complete calls can't
+		// interleave between reading the task and writing it
+		task, err := uc.repo.AssignTx(ctx, tx, taskID, userID)
+		if err != nil {
+			uc.logger.Error("[%s][trace:%s] Failed to assign task: %v", requestID, traceID, err)
+			return nil, fmt.Errorf("failed to assign task: %w", err)
+		}
+		if task == nil {
+			// Nothing was assigned; the task is only read to tell why
+			err := uc.assignRejection(ctx, taskID, userID)
+			uc.logger.Error("[%s][trace:%s] Failed to assign task: %v", requestID, traceID, err)
+			return nil, err
+		}
+
+		// Publish task updated event
+		event := domain.TaskUpdatedEvent{
+			TaskID:      task.ID,
+			Name:        task.Name,
+			Description: task.Description,
+			Status:      task.Status,
+			Priority:    task.Priority,
+			AssignedTo:  task.AssignedTo,
+			DueDate:     task.DueDate,
+			UpdatedAt:   task.UpdatedAt,
+		}
+		return []domain.TaskEvent{uc.newTaskEvent(domain.EventTypeTaskUpdated, task, event)}, nil
+	})
 	if err != nil {
-		uc.logger.Error("[%s][trace:%s] Task not found: %v", requestID, traceID, err)
 		tracing.RecordError(ctx, err)
 		return err
 	}
 
-	if err := task.Assign(userID); err != nil {
-		uc.logger.Error("[%s][trace:%s] Failed to assign task: %v", requestID, traceID, err)
-		tracing.RecordError(ctx, err)
+	uc.logger.Info("[%s][trace:%s] Task assigned successfully", requestID, traceID)
+
+	return nil
+}
+
+// assignRejection tells why the task couldn't be assigned: it doesn't exist or
+// its status doesn't allow it
+func (uc *TaskUseCase) assignRejection(ctx context.Context, taskID, userID int64) error {
+	task, err := uc.repo.GetByID(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	if err := task.Assign(userID, uc.clock.Now()); err != nil {
 		return err
 	}
+	// It became assignable after the UPDATE; the caller may try again
+	return fmt.Errorf("%w: %s", domain.ErrTaskNotAssignable, task.Status)
+}
+
+// CompleteTask marks a task as completed. Completing a completed task again
+// succeeds without changing it or publishing another event.
+func (uc *TaskUseCase) CompleteTask(ctx context.Context, id int64) error {
+	start := time.Now()
+	ctx, span := tracing.StartSpan(ctx, "usecase", "complete_task")
+	defer tracing.EndSpan(span)
+
+	requestID := pkgcontext.GetRequestID(ctx)
+	traceID := pkgcontext.GetTraceID(ctx)
+
+	span.SetAttributes(attribute.Int64("task.id", id))
+
+	uc.logger.Info("[%s][trace:%s] Completing task: ID=%d", requestID, traceID, id)
 
-	if err := uc.repo.Update(ctx, task); err != nil {
-		uc.logger.Error("[%s][trace:%s] Failed to save task: %v", requestID, traceID, err)
+	alreadyCompleted := false
+	err := uc.commit(ctx, func(ctx context.Context, tx pgx.Tx) ([]domain.TaskEvent, error) {
+		// One conditional UPDATE, so concurrent completes can't both succeed
+		task, err := uc.repo.CompleteTx(ctx, tx, id, uc.cfg.RequireSubtasksCompleted)
+		if err != nil {
+			uc.logger.Error("[%s][trace:%s] Failed to complete task: %v", requestID, traceID, err)
+			return nil, fmt.Errorf("failed to complete task: %w", err)
+		}
+		if task == nil {
+			// Nothing was completed; the task is only read to tell why
+			err := uc.completeRejection(ctx, tx, id)
+			// Completing twice is a retry, not a conflict: nothing changes
+			if errors.Is(err, domain.ErrTaskAlreadyCompleted) {
+				alreadyCompleted = true
+				return nil, nil
+			}
+			uc.logger.Warn("[%s][trace:%s] Task %d not completed: %v", requestID, traceID, id, err)
+			return nil, err
+		}
+
+		// Publish task completed event
+		event := domain.TaskCompletedEvent{
+			TaskID:      task.ID,
+			CompletedAt: uc.clock.Now(),
+		}
+		return []domain.TaskEvent{uc.newTaskEvent(domain.EventTypeTaskCompleted, task, event)}, nil
+	})
+	if err != nil {
 		tracing.RecordError(ctx, err)
-		return fmt.Errorf("failed to save task: %w", err)
+		return err
 	}
+	if alreadyCompleted {
+		uc.logger.Info("[%s][trace:%s] Task already completed: ID=%d", requestID, traceID, id)
+		return nil
+	}
+
+	uc.metrics.RecordTaskCompleted()
+	uc.metrics.RecordTaskProcessingDuration(time.Since(start))
+
+	uc.logger.Info("[%s][trace:%s] Task completed successfully: ID=%d", requestID, traceID, id)
 
-	// Publish task updated event
-	event := domain.TaskUpdatedEvent{
-		TaskID:      task.ID,
-		Name:        task.Name,
-		Description: task.Description,
-		Status:      task.Status,
-		Priority:    task.Priority,
-		AssignedTo:  task.AssignedTo,
-		UpdatedAt:   task.UpdatedAt,
+	return nil
+}
+
+// completeRejection tells why the task couldn't be completed: it doesn't
+// exist, is already completed or cancelled, or is blocked by dependencies or
+// subtasks
+func (uc *TaskUseCase) completeRejection(ctx context.Context, tx pgx.Tx, id int64) error {
+	task, err := uc.repo.GetByIDForUpdate(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if task.IsCompleted() {
+		return domain.ErrTaskAlreadyCompleted
 	}
 
-	if err := uc.producer.PublishTaskUpdated(ctx, event); err != nil {
-		uc.logger.Warn("[%s][trace:%s] Failed to publish task updated event: %v", requestID, traceID, err)
+	incomplete, err := uc.repo.CountIncompleteDependenciesTx(ctx, tx, id)
+	if err != nil {
+		return fmt.Errorf("failed to check dependencies: %w", err)
+	}
+	if incomplete > 0 {
+		return domain.ErrDependenciesIncomplete
 	}
 
-	uc.logger.Info("[%s][trace:%s] Task assigned successfully", requestID, traceID)
+	if uc.cfg.RequireSubtasksCompleted {
+		openSubtasks, err := uc.repo.CountIncompleteSubtasksTx(ctx, tx, id)
+		if err != nil {
+			return fmt.Errorf("failed to check subtasks: %w", err)
+		}
+		if openSubtasks > 0 {
+			return domain.ErrSubtasksIncomplete
+		}
+	}
 
-	return nil
+	if err := task.Complete(uc.clock.Now()); err != nil {
+		return err
+	}
+	// It became completable after the UPDATE; the caller may try again
+	return domain.ErrTaskModified
 }
 
-// CompleteTask marks a task as completed
-func (uc *TaskUseCase) CompleteTask(ctx context.Context, id int64) error {
-	start := time.Now()
-	ctx, span := tracing.StartSpan(ctx, "usecase", "complete_task")
-	defer span.End()
+// AddDependency makes taskID depend on dependsOnID
+func (uc *TaskUseCase) AddDependency(ctx context.Context, taskID, dependsOnID int64) error {
+	ctx, span := tracing.StartSpan(ctx, "usecase", "add_dependency")
+	defer tracing.EndSpan(span)
 
 	requestID := pkgcontext.GetRequestID(ctx)
 	traceID := pkgcontext.GetTraceID(ctx)
 
-	span.SetAttributes(attribute.Int64("task.id", id))
+	span.SetAttributes(
+		attribute.Int64("task.id", taskID),
+		attribute.Int64("task.depends_on_id", dependsOnID),
+	)
 
-	uc.logger.Info("[%s][trace:%s] Completing task: ID=%d", requestID, traceID, id)
+	uc.logger.Info("[%s][trace:%s] Adding dependency: task %d depends on %d", requestID, traceID, taskID, dependsOnID)
 
-	task, err := uc.repo.GetByID(ctx, id)
+	tasks, err := uc.repo.GetByIDs(ctx, []int64{taskID, dependsOnID})
 	if err != nil {
-		uc.logger.Error("[%s][trace:%s] Task not found: %v", requestID, traceID, err)
+		uc.logger.Error("[%s][trace:%s] Failed to get tasks: %v", requestID, traceID, err)
+		tracing.RecordError(ctx, err)
+		return fmt.Errorf("failed to get tasks: %w", err)
+	}
+	for _, id := range []int64{taskID, dependsOnID} {
+		if tasks[id] == nil {
+			uc.logger.Error("[%s][trace:%s] Task not found: %d", requestID, traceID, id)
+			tracing.RecordError(ctx, domain.ErrTaskNotFound)
+			return domain.ErrTaskNotFound
+		}
+	}
+
+	if err := uc.repo.AddDependency(ctx, taskID, dependsOnID); err != nil {
+		uc.logger.Error("[%s][trace:%s] Failed to add dependency: %v", requestID, traceID, err)
 		tracing.RecordError(ctx, err)
 		return err
 	}
 
-	if err := task.Complete(); err != nil {
-		uc.logger.Error("[%s][trace:%s] Failed to complete task: %v", requestID, traceID, err)
+	return nil
+}
+
+// RemoveDependency removes the dependency of taskID on dependsOnID
+func (uc *TaskUseCase) RemoveDependency(ctx context.Context, taskID, dependsOnID int64) error {
+	ctx, span := tracing.StartSpan(ctx, "usecase", "remove_dependency")
+	defer tracing.EndSpan(span)
+
+	requestID := pkgcontext.GetRequestID(ctx)
+	traceID := pkgcontext.GetTraceID(ctx)
+
+	span.SetAttributes(
+		attribute.Int64("task.id", taskID),
+		attribute.Int64("task.depends_on_id", dependsOnID),
+	)
+
+	uc.logger.Info("[%s][trace:%s] Removing dependency: task %d on %d", requestID, traceID, taskID, dependsOnID)
+
+	if err := uc.repo.RemoveDependency(ctx, taskID, dependsOnID); err != nil {
+		uc.logger.Error("[%s][trace:%s] Failed to remove dependency: %v", requestID, traceID, err)
 		tracing.RecordError(ctx, err)
 		return err
 	}
 
-	if err := uc.repo.Update(ctx, task); err != nil {
-		uc.logger.Error("[%s][trace:%s] Failed to save task: %v", requestID, traceID, err)
+	return nil
+}
+
+// recurrenceBatchSize caps how many occurrences are generated per scheduler run
+const recurrenceBatchSize = 100
+
+// GenerateRecurringTasks creates the next occurrence for every completed recurring
+// task that doesn't have one yet, of every tenant. It is driven by the
+// recurrence scheduler and returns the number of generated tasks.
+func (uc *TaskUseCase) GenerateRecurringTasks(ctx context.Context) (int, error) {
+	ctx, span := tracing.StartSpan(ctx, "usecase", "generate_recurring_tasks")
+	defer tracing.EndSpan(span)
+
+	ctx = pkgcontext.WithAllTenants(ctx)
+
+	traceID := pkgcontext.GetTraceID(ctx)
+
+	completed, err := uc.repo.GetRecurringWithoutNext(ctx, recurrenceBatchSize)
+	if err != nil {
+		uc.logger.Error("[trace:%s] Failed to load recurring tasks: %v", traceID, err)
+		tracing.RecordError(ctx, err)
+		return 0, fmt.Errorf("failed to load recurring tasks: %w", err)
+	}
+
+	generated := 0
+	for _, parent := range completed {
+		next, err := parent.NextOccurrence(uc.clock.Now())
+		if err != nil {
+			uc.logger.Warn("[trace:%s] Skipping task %d with invalid recurrence rule: %v", traceID, parent.ID, err)
+			continue
+		}
+
+		err = uc.commit(ctx, func(ctx context.Context, tx pgx.Tx) ([]domain.TaskEvent, error) {
+			// A unique index on parent_task_id makes concurrent schedulers safe:
+			// the loser of the race fails here and the occurrence is not duplicated.
+			if err := uc.repo.CreateTx(ctx, tx, next); err != nil {
+				return nil, err
+			}
+
+			event := domain.TaskCreatedEvent{
+				TaskID:      next.ID,
+				Name:        next.Name,
+				Description: next.Description,
+				Priority:    next.Priority,
+				DueDate:     next.DueDate,
+				CreatedBy:   next.CreatedBy,
+				CreatedAt:   next.CreatedAt,
+			}
+			return []domain.TaskEvent{uc.newTaskEvent(domain.EventTypeTaskCreated, next, event)}, nil
+		})
+		if err != nil {
+			// The next run tries again
+			uc.logger.Error("[trace:%s] Failed to create next occurrence of task %d: %v", traceID, parent.ID, err)
+			tracing.RecordError(ctx, err)
+			continue
+		}
+
+		uc.metrics.RecordTaskCreated()
+		uc.logger.Info("[trace:%s] Generated next occurrence of task %d: ID=%d", traceID, parent.ID, next.ID)
+		generated++
+	}
+
+	span.SetAttributes(attribute.Int("tasks.generated", generated))
+	return generated, nil
+}
+
+// NotifyDueSoon publishes a TaskDueSoonEvent for every open task, of every
+// tenant, that came within DueSoonWindow of its due date, once per due date. It
+// is driven by the due soon scheduler and returns the number of events published.
+func (uc *TaskUseCase) NotifyDueSoon(ctx context.Context) (int, error) {
+	ctx, span := tracing.StartSpan(ctx, "usecase", "notify_due_soon")
+	defer tracing.EndSpan(span)
+
+	ctx = pkgcontext.WithAllTenants(ctx)
+
+	traceID := pkgcontext.GetTraceID(ctx)
+
+	tasks, err := uc.repo.GetDueWithin(ctx, uc.cfg.DueSoonWindow)
+	if err != nil {
+		uc.logger.Error("[trace:%s] Failed to load tasks due soon: %v", traceID, err)
 		tracing.RecordError(ctx, err)
-		return fmt.Errorf("failed to save task: %w", err)
+		return 0, fmt.Errorf("failed to load tasks due soon: %w", err)
 	}
 
-	// Publish task completed event
-	event := domain.TaskCompletedEvent{
-		TaskID:      task.ID,
-		CompletedAt: time.Now(),
+	notified := 0
+	for _, task := range tasks {
+		now := uc.clock.Now()
+		marked := false
+		err := uc.commit(ctx, func(ctx context.Context, tx pgx.Tx) ([]domain.TaskEvent, error) {
+			// Marking first keeps concurrent schedulers from both publishing.
+			// Under PublishStrict a failed publish unmarks the task again and
+			// the next run retries; otherwise the reminder is lost rather
+			// than repeated.
+			var err error
+			if marked, err = uc.repo.MarkDueNotifiedTx(ctx, tx, task.ID, now); err != nil || !marked {
+				return nil, err
+			}
+
+			event := domain.TaskDueSoonEvent{
+				TaskID:     task.ID,
+				Name:       task.Name,
+				AssignedTo: task.AssignedTo,
+				DueDate:    *task.DueDate,
+				NotifiedAt: now,
+			}
+			return []domain.TaskEvent{uc.newTaskEvent(domain.EventTypeTaskDueSoon, task, event)}, nil
+		})
+		if err != nil {
+			uc.logger.Error("[trace:%s] Failed to notify task %d due soon: %v", traceID, task.ID, err)
+			tracing.RecordError(ctx, err)
+			continue
+		}
+		if !marked {
+			continue
+		}
+
+		uc.logger.Info("[trace:%s] Task %d is due at %s", traceID, task.ID, task.DueDate.Format(time.RFC3339))
+		notified++
 	}
 
-	if err := uc.producer.PublishTaskCompleted(ctx, event); err != nil {
-		uc.logger.Warn("[%s][trace:%s] Failed to publish task completed event: %v", requestID, traceID, err)
+	span.SetAttributes(attribute.Int("tasks.notified", notified))
+	return notified, nil
+}
+
+// GetStats returns aggregated task counts of the tenant of ctx, cached for the
+// configured TTL
+func (uc *TaskUseCase) GetStats(ctx context.Context) (*domain.TaskStats, error) {
+	ctx, span := tracing.StartSpan(ctx, "usecase", "get_stats")
+	defer tracing.EndSpan(span)
+
+	requestID := pkgcontext.GetRequestID(ctx)
+	traceID := pkgcontext.GetTraceID(ctx)
+
+	tenantID := pkgcontext.GetTenantID(ctx)
+
+	uc.statsMu.Lock()
+	defer uc.statsMu.Unlock()
+
+	if cached, ok := uc.statsCache[tenantID]; ok && uc.clock.Now().Before(cached.expires) {
+		span.SetAttributes(attribute.Bool("stats.cached", true))
+		return cached.stats, nil
 	}
 
-	uc.metrics.RecordTaskCompleted()
-	uc.metrics.RecordTaskProcessingDuration(time.Since(start))
-	uc.logger.Info("[%s][trace:%s] Task completed successfully: ID=%d", requestID, traceID, id)
+	byStatus, err := uc.countByStatus(ctx)
+	if err != nil {
+		uc.logger.Error("[%s][trace:%s] Failed to count tasks by status: %v", requestID, traceID, err)
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
 
-	return nil
+	byPriority, err := uc.repo.CountByPriority(ctx)
+	if err != nil {
+		uc.logger.Error("[%s][trace:%s] Failed to count tasks by priority: %v", requestID, traceID, err)
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to get stats: %w", err)
+	}
+
+	now := uc.clock.Now()
+	overdue, err := uc.repo.CountOverdue(ctx, now)
+	if err != nil {
+		uc.logger.Error("[%s][trace:%s] Failed to count overdue tasks: %v", requestID, traceID, err)
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to get stats: %w", err)
+	}
+
+	for _, p := range domain.Priorities() {
+		if _, ok := byPriority[p]; !ok {
+			byPriority[p] = 0
+		}
+	}
+
+	stats := &domain.TaskStats{
+		ByStatus:    byStatus,
+		ByPriority:  byPriority,
+		Overdue:     overdue,
+		GeneratedAt: now,
+	}
+	// Expired entries are dropped as the cache is refreshed, so tenants that
+	// stop asking don't stay in it
+	for id, cached := range uc.statsCache {
+		if !now.Before(cached.expires) {
+			delete(uc.statsCache, id)
+		}
+	}
+	uc.statsCache[tenantID] = cachedStats{stats: stats, expires: now.Add(uc.cfg.StatsCacheTTL)}
+
+	span.SetAttributes(attribute.Bool("stats.cached", false))
+	return stats, nil
+}
+
+// ReconcileMetrics recomputes the tasks_by_status gauge from the database.
+// It runs periodically and on demand from the admin API, and counts the tasks
+// of every tenant either way.
+func (uc *TaskUseCase) ReconcileMetrics(ctx context.Context) (map[domain.TaskStatus]int64, error) {
+	ctx, span := tracing.StartSpan(ctx, "usecase", "reconcile_metrics")
+	defer tracing.EndSpan(span)
+
+	ctx = pkgcontext.WithAllTenants(ctx)
+
+	requestID := pkgcontext.GetRequestID(ctx)
+	traceID := pkgcontext.GetTraceID(ctx)
+
+	counts, err := uc.countByStatus(ctx)
+	if err != nil {
+		uc.logger.Error("[%s][trace:%s] Failed to reconcile task metrics: %v", requestID, traceID, err)
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+
+	uc.logger.Debug("[%s][trace:%s] Task metrics reconciled: %v", requestID, traceID, counts)
+	return counts, nil
+}
+
+// countByStatus runs the status-count query and, when it counts every tenant,
+// refreshes the tasks_by_status gauge. Statuses without tasks are reported as
+// zero so the gauge never goes stale.
+func (uc *TaskUseCase) countByStatus(ctx context.Context) (map[domain.TaskStatus]int64, error) {
+	counts, err := uc.repo.CountByStatus(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tasks by status: %w", err)
+	}
+
+	global := pkgcontext.IsAllTenants(ctx)
+	for _, s := range domain.TaskStatuses() {
+		if _, ok := counts[s]; !ok {
+			counts[s] = 0
+		}
+		if global {
+			uc.metrics.SetTasksByStatus(string(s), float64(counts[s]))
+		}
+	}
+
+	return counts, nil
 }
@@ -0,0 +1,174 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	pkgcontext "github.com/seldomhappy/vibe_architecture/internal/pkg/context"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/tracing"
+	"github.com/seldomhappy/vibe_architecture/internal/repository"
+	"github.com/seldomhappy/vibe_architecture/internal/repository/memory"
+	"github.com/seldomhappy/vibe_architecture/logger"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type nopEventBus struct{}
+
+func (nopEventBus) Publish(ctx context.Context, event domain.TaskEvent) {}
+
+// recordingEventBus keeps the events published to it
+type recordingEventBus struct {
+	events []domain.TaskEvent
+}
+
+func (b *recordingEventBus) Publish(ctx context.Context, event domain.TaskEvent) {
+	b.events = append(b.events, event)
+}
+
+// sinkFunc adapts a function to EventSink
+type sinkFunc func(ctx context.Context, event domain.TaskEvent) error
+
+func (f sinkFunc) HandleEvent(ctx context.Context, event domain.TaskEvent) error {
+	return f(ctx, event)
+}
+
+func TestStrictPublishesToBusAfterCommit(t *testing.T) {
+	log := logger.New("test", logger.WithOutput(io.Discard))
+	ctx := pkgcontext.WithTenantID(context.Background(), "test")
+
+	tests := []struct {
+		name       string
+		durableErr error
+		wantErr    error
+		wantTasks  int
+		wantEvents int
+	}{
+		{name: "kafka delivers", wantTasks: 1, wantEvents: 1},
+		{name: "kafka fails", durableErr: errors.New("kafka is down"), wantErr: domain.ErrEventNotPublished},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memory.NewStore()
+			repo := memory.NewTaskRepository(store, log)
+			bus := &recordingEventBus{}
+			var durable []domain.TaskEvent
+			uc := New(Config{
+				DefaultPriority: domain.PriorityMedium,
+				PublishPolicy:   PublishStrict,
+				Durable: sinkFunc(func(ctx context.Context, event domain.TaskEvent) error {
+					// Nothing reaches the bus while the transaction is open
+					if len(bus.events) != 0 {
+						t.Errorf("bus got %d events before the commit", len(bus.events))
+					}
+					durable = append(durable, event)
+					return tt.durableErr
+				}),
+			}, repo, memory.NewTxManager(store, log), bus, log, nil)
+
+			_, err := uc.CreateTask(ctx, CreateTaskInput{Name: "strict", CreatedBy: 1})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateTask error = %v, want %v", err, tt.wantErr)
+			}
+			if len(durable) != 1 {
+				t.Errorf("durable sink got %d events, want 1", len(durable))
+			}
+			if len(bus.events) != tt.wantEvents {
+				t.Errorf("bus got %d events, want %d", len(bus.events), tt.wantEvents)
+			}
+			tasks, err := repo.GetAll(ctx, repository.TaskFilter{TenantID: "test"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tasks) != tt.wantTasks {
+				t.Errorf("%d tasks stored, want %d", len(tasks), tt.wantTasks)
+			}
+		})
+	}
+}
+
+// BenchmarkGetTask measures the GetTask path with no metrics, with tracing off
+// (it is only switched on by tracing.New) and with every span sampled and
+// exported to memory, so the difference is the cost of tracing
+func BenchmarkGetTask(b *testing.B) {
+	b.Run("tracing_off", func(b *testing.B) {
+		benchmarkGetTask(b, nil)
+	})
+
+	b.Run("tracing_on", func(b *testing.B) {
+		exporter := tracetest.NewInMemoryExporter()
+		tracer, err := tracing.NewWithExporter("bench", exporter, tracing.SamplingConfig{Rate: 1})
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer tracer.Shutdown(context.Background())
+
+		// Exported spans are dropped as the benchmark goes so they don't pile up
+		benchmarkGetTask(b, exporter.Reset)
+	})
+}
+
+// benchmarkGetTask runs GetTask b.N times, calling reset, if set, every 1000 calls
+func benchmarkGetTask(b *testing.B, reset func()) {
+	log := logger.New("bench", logger.WithOutput(io.Discard), logger.WithLevel(logger.LevelInfo))
+	store := memory.NewStore()
+	uc := New(Config{DefaultPriority: domain.PriorityMedium}, memory.NewTaskRepository(store, log),
+		memory.NewTxManager(store, log), nopEventBus{}, log, nil)
+
+	ctx := pkgcontext.WithTenantID(context.Background(), "bench")
+	ctx = pkgcontext.WithUserID(ctx, 1)
+	task, err := uc.CreateTask(ctx, CreateTaskInput{Name: "bench", CreatedBy: 1})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := uc.GetTask(ctx, task.ID); err != nil {
+			b.Fatal(err)
+		}
+		if reset != nil && i%1000 == 999 {
+			reset()
+		}
+	}
+}
+
+func TestTenantScopeFailsClosed(t *testing.T) {
+	log := logger.New("test", logger.WithOutput(io.Discard))
+	store := memory.NewStore()
+	repo := memory.NewTaskRepository(store, log)
+	uc := New(Config{DefaultPriority: domain.PriorityMedium}, repo, memory.NewTxManager(store, log), nopEventBus{}, log, nil)
+
+	created, err := uc.CreateTask(pkgcontext.WithTenantID(context.Background(), "acme"), CreateTaskInput{Name: "scoped", CreatedBy: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A context without a tenant sees nothing rather than everything
+	ctx := context.Background()
+	if _, err := uc.GetTask(ctx, created.ID); !errors.Is(err, domain.ErrTaskNotFound) {
+		t.Errorf("GetTask without a tenant: error = %v, want %v", err, domain.ErrTaskNotFound)
+	}
+	tasks, err := uc.ListTasks(ctx, ListTasksFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 0 {
+		t.Errorf("ListTasks without a tenant returned %d tasks, want 0", len(tasks))
+	}
+
+	// Background jobs ask for every tenant explicitly
+	if _, err := repo.GetByID(pkgcontext.WithAllTenants(ctx), created.ID); err != nil {
+		t.Errorf("GetByID for all tenants: %v", err)
+	}
+	tasks, err = repo.GetAll(pkgcontext.WithAllTenants(ctx), repository.TaskFilter{TenantID: repository.AllTenants})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 {
+		t.Errorf("GetAll for all tenants returned %d tasks, want 1", len(tasks))
+	}
+}
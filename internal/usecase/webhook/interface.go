@@ -0,0 +1,36 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+)
+
+// Repository defines the webhook repository interface. Every method but
+// CreateDeadLetter is scoped to the tenant of ctx, as tasks are; a webhook of
+// another tenant is reported as domain.ErrWebhookNotFound.
+type Repository interface {
+	Create(ctx context.Context, webhook *domain.Webhook) error
+	GetByID(ctx context.Context, id int64) (*domain.Webhook, error)
+	GetAll(ctx context.Context) ([]*domain.Webhook, error)
+	Delete(ctx context.Context, id int64) error
+	CreateDeadLetter(ctx context.Context, letter *domain.WebhookDeadLetter) error
+	GetDeadLetters(ctx context.Context, webhookID int64, limit int) ([]*domain.WebhookDeadLetter, error)
+}
+
+// UseCase defines the webhook use case interface
+type UseCase interface {
+	CreateWebhook(ctx context.Context, input CreateWebhookInput) (*domain.Webhook, error)
+	GetWebhook(ctx context.Context, id int64) (*domain.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]*domain.Webhook, error)
+	DeleteWebhook(ctx context.Context, id int64) error
+	ListDeadLetters(ctx context.Context, webhookID int64) ([]*domain.WebhookDeadLetter, error)
+}
+
+// CreateWebhookInput represents input for creating a webhook
+type CreateWebhookInput struct {
+	URL        string
+	EventTypes []domain.EventType
+	// Secret signs deliveries; empty generates a random one
+	Secret string
+}
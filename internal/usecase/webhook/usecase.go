@@ -0,0 +1,158 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/seldomhappy/vibe_architecture/internal/domain"
+	pkgcontext "github.com/seldomhappy/vibe_architecture/internal/pkg/context"
+	"github.com/seldomhappy/vibe_architecture/internal/pkg/tracing"
+	"github.com/seldomhappy/vibe_architecture/logger"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// deadLetterListLimit caps how many dead letters ListDeadLetters returns
+const deadLetterListLimit = 100
+
+// WebhookUseCase implements the UseCase interface
+type WebhookUseCase struct {
+	repo   Repository
+	logger logger.ILogger
+}
+
+// New creates a new webhook use case
+func New(repo Repository, log logger.ILogger) UseCase {
+	return &WebhookUseCase{
+		repo:   repo,
+		logger: log,
+	}
+}
+
+// CreateWebhook creates a new webhook subscription
+func (uc *WebhookUseCase) CreateWebhook(ctx context.Context, input CreateWebhookInput) (*domain.Webhook, error) {
+	ctx, span := tracing.StartSpan(ctx, "usecase", "create_webhook")
+	defer tracing.EndSpan(span)
+
+	requestID := pkgcontext.GetRequestID(ctx)
+	traceID := pkgcontext.GetTraceID(ctx)
+
+	uc.logger.Info("[%s][trace:%s] Creating webhook for %s", requestID, traceID, input.URL)
+
+	webhook := &domain.Webhook{
+		TenantID:   pkgcontext.GetTenantID(ctx),
+		URL:        input.URL,
+		EventTypes: input.EventTypes,
+		Secret:     input.Secret,
+	}
+	if webhook.EventTypes == nil {
+		webhook.EventTypes = []domain.EventType{}
+	}
+
+	if err := webhook.Validate(); err != nil {
+		uc.logger.Warn("[%s][trace:%s] Webhook validation failed: %v", requestID, traceID, err)
+		tracing.AddEvent(ctx, "validation_failed", attribute.String("error", err.Error()))
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+
+	if webhook.Secret == "" {
+		secret, err := newSecret()
+		if err != nil {
+			tracing.RecordError(ctx, err)
+			return nil, err
+		}
+		webhook.Secret = secret
+	}
+
+	if err := uc.repo.Create(ctx, webhook); err != nil {
+		uc.logger.Error("[%s][trace:%s] Failed to create webhook: %v", requestID, traceID, err)
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	span.SetAttributes(attribute.Int64("webhook.id", webhook.ID))
+	uc.logger.Info("[%s][trace:%s] Webhook created successfully: ID=%d", requestID, traceID, webhook.ID)
+	return webhook, nil
+}
+
+// newSecret returns 32 random bytes, hex encoded
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// GetWebhook retrieves a webhook by ID
+func (uc *WebhookUseCase) GetWebhook(ctx context.Context, id int64) (*domain.Webhook, error) {
+	ctx, span := tracing.StartSpan(ctx, "usecase", "get_webhook")
+	defer tracing.EndSpan(span)
+
+	span.SetAttributes(attribute.Int64("webhook.id", id))
+
+	webhook, err := uc.repo.GetByID(ctx, id)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// ListWebhooks returns every webhook of the tenant
+func (uc *WebhookUseCase) ListWebhooks(ctx context.Context) ([]*domain.Webhook, error) {
+	ctx, span := tracing.StartSpan(ctx, "usecase", "list_webhooks")
+	defer tracing.EndSpan(span)
+
+	webhooks, err := uc.repo.GetAll(ctx)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+
+	span.SetAttributes(attribute.Int("webhooks.count", len(webhooks)))
+	return webhooks, nil
+}
+
+// DeleteWebhook deletes a webhook; events already queued for it are still delivered
+func (uc *WebhookUseCase) DeleteWebhook(ctx context.Context, id int64) error {
+	ctx, span := tracing.StartSpan(ctx, "usecase", "delete_webhook")
+	defer tracing.EndSpan(span)
+
+	requestID := pkgcontext.GetRequestID(ctx)
+	traceID := pkgcontext.GetTraceID(ctx)
+
+	span.SetAttributes(attribute.Int64("webhook.id", id))
+
+	uc.logger.Info("[%s][trace:%s] Deleting webhook %d", requestID, traceID, id)
+
+	if err := uc.repo.Delete(ctx, id); err != nil {
+		uc.logger.Error("[%s][trace:%s] Failed to delete webhook: %v", requestID, traceID, err)
+		tracing.RecordError(ctx, err)
+		return err
+	}
+
+	return nil
+}
+
+// ListDeadLetters returns the most recent events that could not be delivered to a webhook
+func (uc *WebhookUseCase) ListDeadLetters(ctx context.Context, webhookID int64) ([]*domain.WebhookDeadLetter, error) {
+	ctx, span := tracing.StartSpan(ctx, "usecase", "list_webhook_dead_letters")
+	defer tracing.EndSpan(span)
+
+	span.SetAttributes(attribute.Int64("webhook.id", webhookID))
+
+	if _, err := uc.repo.GetByID(ctx, webhookID); err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+
+	letters, err := uc.repo.GetDeadLetters(ctx, webhookID, deadLetterListLimit)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, fmt.Errorf("failed to list webhook dead letters: %w", err)
+	}
+	return letters, nil
+}
@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"sync"
+	"time"
+)
+
+// dedupKey groups log calls by level and format string, so the same message
+// about different requests still counts as a repeat
+type dedupKey struct {
+	level  Level
+	format string
+}
+
+// dedupState tracks a message while its window is open
+type dedupState struct {
+	suppressed int
+	lastArgs   []interface{}
+}
+
+// dedupLogger collapses repeated warnings and errors
+type dedupLogger struct {
+	ILogger
+	window time.Duration
+
+	mu   sync.Mutex
+	open map[dedupKey]*dedupState
+}
+
+// NewDeduplicating wraps next so that a warning or error repeated within window
+// is logged once, followed by a single "repeated N times" line when the window
+// closes. Debug, info and fatal messages pass through unchanged.
+func NewDeduplicating(next ILogger, window time.Duration) ILogger {
+	return &dedupLogger{
+		ILogger: next,
+		window:  window,
+		open:    make(map[dedupKey]*dedupState),
+	}
+}
+
+// Warn logs a warning unless the same one was logged within the window
+func (d *dedupLogger) Warn(format string, args ...interface{}) {
+	d.log(LevelWarn, d.ILogger.Warn, format, args)
+}
+
+// Error logs an error unless the same one was logged within the window
+func (d *dedupLogger) Error(format string, args ...interface{}) {
+	d.log(LevelError, d.ILogger.Error, format, args)
+}
+
+func (d *dedupLogger) log(level Level, emit func(string, ...interface{}), format string, args []interface{}) {
+	key := dedupKey{level: level, format: format}
+
+	d.mu.Lock()
+	if state, ok := d.open[key]; ok {
+		state.suppressed++
+		state.lastArgs = args
+		d.mu.Unlock()
+		return
+	}
+	d.open[key] = &dedupState{}
+	d.mu.Unlock()
+
+	emit(format, args...)
+	time.AfterFunc(d.window, func() { d.close(key, emit) })
+}
+
+// close ends the window for key, reporting how often the message was suppressed
+func (d *dedupLogger) close(key dedupKey, emit func(string, ...interface{})) {
+	d.mu.Lock()
+	state := d.open[key]
+	delete(d.open, key)
+	d.mu.Unlock()
+
+	if state == nil || state.suppressed == 0 {
+		return
+	}
+	args := append(state.lastArgs, state.suppressed, d.window)
+	emit(key.format+" (repeated %d times in %v)", args...)
+}
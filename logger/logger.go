@@ -2,8 +2,12 @@ package logger
 
 import (
 	"fmt"
-	"log"
+	"io"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // ILogger defines the logging interface
@@ -13,49 +17,141 @@ type ILogger interface {
 	Warn(format string, args ...interface{})
 	Error(format string, args ...interface{})
 	Fatal(format string, args ...interface{})
+	// SetLevel changes the minimum level that is logged; safe for concurrent use
+	SetLevel(level Level)
+}
+
+// Level is a logging severity
+type Level int32
+
+// Logging levels in increasing severity
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelFatal
+)
+
+// String returns the level name as printed in log lines
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	case LevelFatal:
+		return "FATAL"
+	default:
+		return fmt.Sprintf("LEVEL(%d)", int32(l))
+	}
+}
+
+// ParseLevel parses a level name such as "debug" or "warn"
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	case "fatal":
+		return LevelFatal, nil
+	default:
+		return LevelInfo, fmt.Errorf("unknown log level: %q", s)
+	}
+}
+
+// Option configures a Logger
+type Option func(*Logger)
+
+// WithLevel sets the minimum level that is logged
+func WithLevel(level Level) Option {
+	return func(l *Logger) {
+		l.level.Store(int32(level))
+	}
 }
 
 // Logger implements ILogger
 type Logger struct {
 	appName string
-	logger  *log.Logger
+	format  Format
+	color   bool
+	level   atomic.Int32
+
+	mu  sync.Mutex // serializes writes so lines never interleave
+	out io.Writer
 }
 
-// New creates a new logger instance
-func New(appName string) ILogger {
-	return &Logger{
+// New creates a new logger instance writing to stdout in the text format
+// unless configured otherwise. Everything is logged unless a level is set.
+func New(appName string, opts ...Option) ILogger {
+	l := &Logger{
 		appName: appName,
-		logger:  log.New(os.Stdout, "", log.LstdFlags),
+		format:  FormatText,
+		out:     os.Stdout,
+	}
+	for _, opt := range opts {
+		opt(l)
 	}
+	l.color = l.format == FormatConsole && isTerminal(l.out)
+	return l
+}
+
+// SetLevel changes the minimum level that is logged
+func (l *Logger) SetLevel(level Level) {
+	l.level.Store(int32(level))
 }
 
 // Debug logs a debug message
 func (l *Logger) Debug(format string, args ...interface{}) {
-	l.log("DEBUG", format, args...)
+	l.log(LevelDebug, format, args...)
 }
 
 // Info logs an info message
 func (l *Logger) Info(format string, args ...interface{}) {
-	l.log("INFO", format, args...)
+	l.log(LevelInfo, format, args...)
 }
 
 // Warn logs a warning message
 func (l *Logger) Warn(format string, args ...interface{}) {
-	l.log("WARN", format, args...)
+	l.log(LevelWarn, format, args...)
 }
 
 // Error logs an error message
 func (l *Logger) Error(format string, args ...interface{}) {
-	l.log("ERROR", format, args...)
+	l.log(LevelError, format, args...)
 }
 
 // Fatal logs a fatal message and exits
 func (l *Logger) Fatal(format string, args ...interface{}) {
-	l.log("FATAL", format, args...)
+	l.log(LevelFatal, format, args...)
+	// os.Exit skips deferred closes, so flush file output here
+	if s, ok := l.out.(interface{ Sync() error }); ok {
+		_ = s.Sync()
+	}
 	os.Exit(1)
 }
 
-func (l *Logger) log(level, format string, args ...interface{}) {
-	message := fmt.Sprintf(format, args...)
-	l.logger.Printf("[%s] [%s] %s", l.appName, level, message)
+func (l *Logger) log(level Level, format string, args ...interface{}) {
+	if level < Level(l.level.Load()) {
+		return
+	}
+	line := formatEntry(l.format, l.color, entry{
+		time:    time.Now(),
+		level:   level,
+		app:     l.appName,
+		message: fmt.Sprintf(format, args...),
+	})
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = l.out.Write(line)
 }
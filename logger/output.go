@@ -0,0 +1,198 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Output destinations other than a file path
+const (
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+)
+
+// RotateConfig controls rotation of a log file
+type RotateConfig struct {
+	// MaxSizeMB rotates the file once it would grow past this size; 0 disables
+	MaxSizeMB int
+	// MaxAge rotates the file once it has been written to for this long; 0 disables
+	MaxAge time.Duration
+	// MaxBackups is how many rotated files are kept; 0 keeps all of them
+	MaxBackups int
+}
+
+// WithOutput sets where log lines are written
+func WithOutput(w io.Writer) Option {
+	return func(l *Logger) {
+		l.out = w
+	}
+}
+
+// OpenOutput opens a log destination: stdout, stderr or a file path.
+// Files are appended to and rotated according to rotate. Close flushes the
+// file to disk; it is a no-op for the standard streams.
+func OpenOutput(output string, rotate RotateConfig) (io.WriteCloser, error) {
+	switch output {
+	case "", OutputStdout:
+		return stdStream{os.Stdout}, nil
+	case OutputStderr:
+		return stdStream{os.Stderr}, nil
+	}
+
+	f := &rotatingFile{path: output, cfg: rotate}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// stdStream is a standard stream that is left open on Close
+type stdStream struct {
+	*os.File
+}
+
+// Close implements io.Closer without closing the stream
+func (s stdStream) Close() error {
+	return nil
+}
+
+// rotatingFile is an append-only log file that moves itself aside once it is
+// too big or too old. Rotated files get a timestamp suffix.
+type rotatingFile struct {
+	path string
+	cfg  RotateConfig
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+// open opens the log file, continuing an existing one
+func (f *rotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.opened = time.Now()
+	return nil
+}
+
+// Write implements io.Writer, rotating first if p would not fit
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.shouldRotate(int64(len(p))) {
+		if err := f.rotate(); err != nil {
+			// Keep logging to the current file rather than losing lines
+			fmt.Fprintf(os.Stderr, "log rotation failed: %v\n", err)
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Sync flushes the file to disk
+func (f *rotatingFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Sync()
+}
+
+// Close flushes and closes the file
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Sync()
+	if closeErr := f.file.Close(); err == nil {
+		err = closeErr
+	}
+	f.file = nil
+	return err
+}
+
+func (f *rotatingFile) shouldRotate(next int64) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.cfg.MaxSizeMB > 0 && f.size+next > int64(f.cfg.MaxSizeMB)*1024*1024 {
+		return true
+	}
+	return f.cfg.MaxAge > 0 && time.Since(f.opened) >= f.cfg.MaxAge
+}
+
+// rotate moves the current file aside, opens a fresh one and prunes old backups
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	f.file = nil
+
+	backup := f.path + "." + time.Now().UTC().Format("20060102T150405.000")
+	renameErr := os.Rename(f.path, backup)
+
+	// Reopen even if the rename failed, so logging carries on
+	if err := f.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		return fmt.Errorf("failed to rename log file: %w", renameErr)
+	}
+
+	return f.prune()
+}
+
+// prune removes the oldest backups beyond MaxBackups
+func (f *rotatingFile) prune() error {
+	if f.cfg.MaxBackups <= 0 {
+		return nil
+	}
+
+	backups, err := filepath.Glob(f.path + ".*")
+	if err != nil {
+		return fmt.Errorf("failed to list log backups: %w", err)
+	}
+	if len(backups) <= f.cfg.MaxBackups {
+		return nil
+	}
+
+	// The timestamp suffix sorts chronologically
+	sort.Strings(backups)
+	for _, old := range backups[:len(backups)-f.cfg.MaxBackups] {
+		if err := os.Remove(old); err != nil {
+			return fmt.Errorf("failed to remove log backup: %w", err)
+		}
+	}
+	return nil
+}